@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/DavidG9999/my_grpc_app/internal/lib/password"
+)
+
+// breachfilter builds a bloom filter of breached passwords from an HIBP
+// file, to be set as password.breached_passwords_path instead of the much
+// larger file itself.
+func main() {
+	var inputPath, outputPath string
+	var fpRate float64
+
+	flag.StringVar(&inputPath, "input", "", "path to HIBP file of SHA-1 hashes")
+	flag.StringVar(&outputPath, "output", "", "path to write bloom filter to")
+	flag.Float64Var(&fpRate, "fp-rate", 0.001, "false positive rate of bloom filter")
+	flag.Parse()
+
+	if inputPath == "" {
+		panic("input is required")
+	}
+	if outputPath == "" {
+		panic("output is required")
+	}
+	if fpRate <= 0 || fpRate >= 1 {
+		panic("fp-rate must be between 0 and 1")
+	}
+
+	n, err := build(inputPath, outputPath, fpRate)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Printf("bloom filter of %d hashes written to %s\n", n, outputPath)
+}
+
+// build writes a bloom filter of the hashes of the HIBP file at the input
+// path to the output path and returns the number of hashes.
+func build(inputPath string, outputPath string, fpRate float64) (uint64, error) {
+	n, err := countLines(inputPath)
+	if err != nil {
+		return 0, err
+	}
+
+	filter := password.NewBloomFilter(n, fpRate)
+
+	in, err := os.Open(inputPath)
+	if err != nil {
+		return 0, err
+	}
+	defer in.Close()
+
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		digest, err := password.ParseHIBPLine(scanner.Text())
+		if err != nil {
+			return 0, err
+		}
+		filter.Add(digest)
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+
+	out, err := os.Create(outputPath)
+	if err != nil {
+		return 0, err
+	}
+	w := bufio.NewWriter(out)
+	if _, err := filter.WriteTo(w); err != nil {
+		out.Close()
+		return 0, err
+	}
+	if err := w.Flush(); err != nil {
+		out.Close()
+		return 0, err
+	}
+	return n, out.Close()
+}
+
+func countLines(path string) (uint64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	var n uint64
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) > 0 {
+			n++
+		}
+	}
+	return n, scanner.Err()
+}
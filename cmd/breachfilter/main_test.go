@@ -0,0 +1,56 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/DavidG9999/my_grpc_app/internal/lib/password"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Build(t *testing.T) {
+	dir := t.TempDir()
+	inputPath := filepath.Join(dir, "breached.txt")
+	outputPath := filepath.Join(dir, "breached.bloom")
+
+	passwords := make([]string, 500)
+	var input strings.Builder
+	for i := range passwords {
+		passwords[i] = fmt.Sprintf("breached-%d", i)
+		sum := sha1.Sum([]byte(passwords[i]))
+		// Blank lines are skipped.
+		fmt.Fprintf(&input, "%s:%d\n\n", strings.ToUpper(hex.EncodeToString(sum[:])), i+1)
+	}
+	require.NoError(t, os.WriteFile(inputPath, []byte(input.String()), 0o600))
+
+	n, err := build(inputPath, outputPath, 0.001)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(len(passwords)), n)
+
+	list, err := password.OpenBreachedList(outputPath)
+	require.NoError(t, err)
+	require.IsType(t, &password.BloomFilter{}, list)
+	for _, p := range passwords {
+		contains, err := list.Contains(p)
+		require.NoError(t, err)
+		assert.True(t, contains, p)
+	}
+}
+
+func Test_Build_MalformedInput(t *testing.T) {
+	dir := t.TempDir()
+	inputPath := filepath.Join(dir, "breached.txt")
+	require.NoError(t, os.WriteFile(inputPath, []byte("not-a-hash:1\n"), 0o600))
+
+	_, err := build(inputPath, filepath.Join(dir, "breached.bloom"), 0.001)
+	assert.ErrorContains(t, err, "malformed line")
+
+	_, err = build(filepath.Join(dir, "missing.txt"), filepath.Join(dir, "breached.bloom"), 0.001)
+	assert.Error(t, err)
+}
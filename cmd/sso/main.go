@@ -23,9 +23,10 @@ func main() {
 
 	log.Info("starting application")
 
-	application := app.NewApp(log, cfg.GRPC.Port, cfg.StoragePath, cfg.TokenTTL)
+	application := app.NewApp(log, cfg)
 
 	go application.GRPCSrv.MustRun()
+	go application.HTTPSrv.MustRun()
 
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGTERM, syscall.SIGINT)
@@ -33,6 +34,7 @@ func main() {
 
 	log.Info("stopping application", slog.String("signal", stopSignal.String()))
 
+	application.HTTPSrv.Stop()
 	application.GRPCSrv.Stop()
 
 	log.Info("application stopped")
@@ -2,21 +2,27 @@ package app
 
 import (
 	"log/slog"
-	"time"
 
 	grpcapp "github.com/DavidG9999/my_grpc_app/internal/app/grpc"
+	httpapp "github.com/DavidG9999/my_grpc_app/internal/app/http"
+	"github.com/DavidG9999/my_grpc_app/internal/config"
+	"github.com/DavidG9999/my_grpc_app/internal/lib/jwt"
+	"github.com/DavidG9999/my_grpc_app/internal/lib/mail"
+	"github.com/DavidG9999/my_grpc_app/internal/lib/password"
 	"github.com/DavidG9999/my_grpc_app/internal/services/auth"
+	"github.com/DavidG9999/my_grpc_app/internal/services/oauth"
 	"github.com/DavidG9999/my_grpc_app/internal/storage"
 	"github.com/DavidG9999/my_grpc_app/internal/storage/sqlite"
 )
 
 type App struct {
 	GRPCSrv *grpcapp.App
+	HTTPSrv *httpapp.App
 }
 
-func NewApp(log *slog.Logger, grpcPort int, storagePath string, tokenTTL time.Duration) *App {
+func NewApp(log *slog.Logger, cfg *config.Config) *App {
 
-	db, err := sqlite.NewSQLiteDB(storagePath)
+	db, err := sqlite.NewSQLiteDB(cfg.StoragePath)
 	if err != nil {
 		
 		panic(err)
@@ -24,11 +30,95 @@ func NewApp(log *slog.Logger, grpcPort int, storagePath string, tokenTTL time.Du
 
 	storage := storage.NewStorage(db)
 
-	authSrv := auth.NewAuth(log, storage, tokenTTL)
+	hasher, err := password.NewHasherFor(cfg.Password.Hash.Algorithm, password.HashParams{
+		BcryptCost:        cfg.Password.Hash.BcryptCost,
+		Argon2Memory:      cfg.Password.Hash.Argon2Memory,
+		Argon2Time:        cfg.Password.Hash.Argon2Time,
+		Argon2Parallelism: cfg.Password.Hash.Argon2Parallelism,
+		Limits: password.Limits{
+			Argon2Memory:      cfg.Password.Hash.Limits.Argon2Memory,
+			Argon2Time:        cfg.Password.Hash.Limits.Argon2Time,
+			Argon2Parallelism: cfg.Password.Hash.Limits.Argon2Parallelism,
+			BcryptCost:        cfg.Password.Hash.Limits.BcryptCost,
+			PBKDF2Iterations:  cfg.Password.Hash.Limits.PBKDF2Iterations,
+			ScryptCost:        cfg.Password.Hash.Limits.ScryptCost,
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	var breached password.BreachedList
+	if cfg.Password.BreachedPasswordsPath != "" {
+		breached, err = password.OpenBreachedList(cfg.Password.BreachedPasswordsPath)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	signingKey, err := oauthSigningKey(log, cfg.OAuth.SigningKeyPath)
+	if err != nil {
+		panic(err)
+	}
+
+	authSrv := auth.NewAuth(log, storage, cfg.TokenSecret, cfg.TokenTTL, cfg.ImpersonationTTL, cfg.ChangeTTL, cfg.VerificationTTL, cfg.BootstrapToken, passwordPolicies(cfg.Password), hasher, breached, mail.Outbox{Dir: cfg.Mail.OutboxDir})
+
+	oauthSrv := oauth.NewOAuth(
+		log,
+		storage,
+		cfg.TokenSecret,
+		signingKey,
+		cfg.OAuth.Issuer,
+		cfg.TokenTTL,
+		cfg.OAuth.CodeTTL,
+		cfg.OAuth.RefreshTokenTTL,
+		cfg.OAuth.DeviceCodeTTL,
+		cfg.OAuth.DevicePollInterval,
+	)
+
+	grpcApp := grpcapp.NewApp(log, cfg.GRPC.Port, authSrv, oauthSrv)
 
-	grpcApp := grpcapp.NewApp(log, grpcPort, authSrv)
+	httpApp := httpapp.NewApp(log, cfg.HTTP, authSrv, oauthSrv)
 
 	return &App{
 		GRPCSrv: grpcApp,
+		HTTPSrv: httpApp,
+	}
+}
+
+// oauthSigningKey loads the key ID tokens are signed with or, if there is
+// none, generates one that lasts until the next start.
+func oauthSigningKey(log *slog.Logger, path string) (jwt.SigningKey, error) {
+	if path != "" {
+		return jwt.LoadSigningKey(path)
+	}
+	log.Warn("no oauth signing key configured, ID tokens will not verify after a restart")
+
+	return jwt.GenerateSigningKey()
+}
+
+func passwordPolicies(cfg config.PasswordConfig) password.Policies {
+	policies := password.Policies{
+		Default: passwordPolicy(cfg.Policy),
+		Apps:    make(map[int]password.Policy, len(cfg.Apps)),
+	}
+	for appID, policy := range cfg.Apps {
+		policies.Apps[appID] = passwordPolicy(policy)
+	}
+	return policies
+}
+
+func passwordPolicy(cfg config.PasswordPolicyConfig) password.Policy {
+	return password.Policy{
+		MinLength:           cfg.MinLength,
+		MaxLength:           cfg.MaxLength,
+		RequireUpper:        cfg.RequireUpper,
+		RequireLower:        cfg.RequireLower,
+		RequireDigit:        cfg.RequireDigit,
+		RequireSymbol:       cfg.RequireSymbol,
+		MaxRepeated:         cfg.MaxRepeated,
+		ForbiddenSubstrings: cfg.ForbiddenSubstrings,
+		ForbidPersonalInfo:  cfg.ForbidPersonalInfo,
+		MinStrengthScore:    cfg.MinStrengthScore,
 	}
 }
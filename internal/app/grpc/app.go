@@ -7,6 +7,7 @@ import (
 
 	authgrpc "github.com/DavidG9999/my_grpc_app/internal/grpc/auth"
 	"github.com/DavidG9999/my_grpc_app/internal/services/auth"
+	"github.com/DavidG9999/my_grpc_app/internal/services/oauth"
 	"google.golang.org/grpc"
 )
 
@@ -16,10 +17,10 @@ type App struct {
 	port       int
 }
 
-func NewApp(log *slog.Logger, port int, authService *auth.Auth) *App {
-	gRPCServer := grpc.NewServer()
+func NewApp(log *slog.Logger, port int, authService *auth.Auth, oauthService *oauth.OAuth) *App {
+	gRPCServer := grpc.NewServer(grpc.UnaryInterceptor(authgrpc.Interceptor(authService)))
 
-	authgrpc.Register(gRPCServer, *authService)
+	authgrpc.Register(gRPCServer, *authService, oauthService)
 
 	return &App{
 		log:        log,
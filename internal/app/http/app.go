@@ -0,0 +1,128 @@
+package httpapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/DavidG9999/my_grpc_app/internal/config"
+	authgrpc "github.com/DavidG9999/my_grpc_app/internal/grpc/auth"
+	"github.com/DavidG9999/my_grpc_app/internal/grpc/web"
+	oauthhttp "github.com/DavidG9999/my_grpc_app/internal/http/oauth"
+	"github.com/DavidG9999/my_grpc_app/internal/services/auth"
+	"github.com/DavidG9999/my_grpc_app/internal/services/oauth"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+const shutdownTimeout = 10 * time.Second
+
+type App struct {
+	log        *slog.Logger
+	httpServer *http.Server
+	port       int
+}
+
+func NewApp(log *slog.Logger, cfg config.HTTPConfig, authService *auth.Auth, oauthService *oauth.OAuth) *App {
+	webServer := web.NewServer(log, authgrpc.Interceptor(authService))
+
+	authgrpc.Register(webServer, *authService, oauthService)
+
+	mux := http.NewServeMux()
+	mux.Handle("/", webServer)
+
+	oauthhttp.Register(mux, log, oauthService, authService)
+
+	httpServer := &http.Server{
+		Handler:      h2c.NewHandler(cors(cfg.AllowedOrigins, mux), &http2.Server{}),
+		ReadTimeout:  cfg.Timeout,
+		WriteTimeout: cfg.Timeout,
+	}
+
+	return &App{
+		log:        log,
+		httpServer: httpServer,
+		port:       cfg.Port,
+	}
+}
+
+func (a *App) MustRun() {
+	if err := a.Run(); err != nil {
+		panic(err)
+	}
+}
+
+func (a *App) Run() error {
+	const op = "httpapp.Run"
+
+	log := a.log.With(slog.String("op", op), slog.Int("port", a.port))
+
+	l, err := net.Listen("tcp", fmt.Sprintf(":%d", a.port))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("http server is running", slog.String("addr", l.Addr().String()))
+
+	if err := a.httpServer.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (a *App) Stop() {
+	const op = "httpapp.Stop"
+
+	log := a.log.With(slog.String("op", op))
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := a.httpServer.Shutdown(ctx); err != nil {
+		log.Error("failed to shutdown http server", slog.String("error", err.Error()))
+	}
+
+	log.Info("stopping http server")
+}
+
+// cors allows browser clients from the configured origins to call the gRPC-Web
+// and Connect endpoints.
+func cors(allowedOrigins []string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" || !(slices.Contains(allowedOrigins, origin) || slices.Contains(allowedOrigins, "*")) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		h := w.Header()
+		h.Set("Access-Control-Allow-Origin", origin)
+		h.Add("Vary", "Origin")
+		h.Set("Access-Control-Expose-Headers", "Grpc-Status, Grpc-Message, Grpc-Status-Details-Bin")
+
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			h.Set("Access-Control-Allow-Methods", strings.Join([]string{http.MethodGet, http.MethodPost}, ", "))
+			h.Set("Access-Control-Allow-Headers", strings.Join([]string{
+				"Authorization",
+				"Content-Type",
+				"Connect-Protocol-Version",
+				"Connect-Timeout-Ms",
+				"Grpc-Timeout",
+				"X-Grpc-Web",
+				"X-User-Agent",
+			}, ", "))
+			h.Set("Access-Control-Max-Age", "7200")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -10,10 +10,19 @@ import (
 )
 
 type Config struct {
-	Env         string        `yaml:"env" env-default:"local"`
-	StoragePath string        `yaml:"storage_path" env-default:"local"`
-	TokenTTL    time.Duration `yaml:"token_ttl" env-required:"true"`
-	GRPC        GRPCConfig    `yaml:"grpc"`
+	Env              string         `yaml:"env" env-default:"local"`
+	StoragePath      string         `yaml:"storage_path" env-default:"local"`
+	TokenSecret      string         `yaml:"token_secret" env:"TOKEN_SECRET" env-required:"true"`
+	TokenTTL         time.Duration  `yaml:"token_ttl" env-required:"true"`
+	ImpersonationTTL time.Duration  `yaml:"impersonation_ttl" env-default:"15m"`
+	ChangeTTL        time.Duration  `yaml:"change_ttl" env-default:"72h"`
+	VerificationTTL  time.Duration  `yaml:"email_verification_ttl" env-default:"24h"`
+	BootstrapToken   string         `yaml:"bootstrap_token" env:"BOOTSTRAP_TOKEN"`
+	GRPC             GRPCConfig     `yaml:"grpc"`
+	HTTP             HTTPConfig     `yaml:"http"`
+	OAuth            OAuthConfig    `yaml:"oauth"`
+	Password         PasswordConfig `yaml:"password"`
+	Mail             MailConfig     `yaml:"mail"`
 }
 
 type GRPCConfig struct {
@@ -21,6 +30,79 @@ type GRPCConfig struct {
 	Timeout time.Duration `yaml:"timeout"`
 }
 
+type HTTPConfig struct {
+	Port           int           `yaml:"port"`
+	Timeout        time.Duration `yaml:"timeout"`
+	AllowedOrigins []string      `yaml:"allowed_origins"`
+}
+
+type MailConfig struct {
+	// OutboxDir gets the mail to users as files, there is no delivery yet.
+	OutboxDir string `yaml:"outbox_dir" env-default:"./storage/mail"`
+}
+
+type OAuthConfig struct {
+	Issuer          string        `yaml:"issuer" env-required:"true"`
+	CodeTTL         time.Duration `yaml:"code_ttl" env-default:"1m"`
+	RefreshTokenTTL time.Duration `yaml:"refresh_token_ttl" env-default:"720h"`
+
+	DeviceCodeTTL      time.Duration `yaml:"device_code_ttl" env-default:"10m"`
+	DevicePollInterval time.Duration `yaml:"device_poll_interval" env-default:"5s"`
+
+	// SigningKeyPath is a PEM file with the RSA key ID tokens are signed
+	// with. A new key is generated on every start if it is empty.
+	SigningKeyPath string `yaml:"signing_key_path" env:"OAUTH_SIGNING_KEY_PATH"`
+}
+
+type PasswordConfig struct {
+	Policy PasswordPolicyConfig `yaml:"policy"`
+	// Apps replace the policy for users signing up to the given app.
+	Apps map[int]PasswordPolicyConfig `yaml:"apps"`
+	Hash PasswordHashConfig           `yaml:"hash"`
+	// BreachedPasswordsPath is an HIBP file or a bloom filter built from one
+	// with cmd/breachfilter. Passwords are not checked if it is empty.
+	BreachedPasswordsPath string `yaml:"breached_passwords_path"`
+}
+
+type PasswordHashConfig struct {
+	// Algorithm hashes new passwords, hashes of the others are upgraded to it
+	// on sign in.
+	Algorithm  string `yaml:"algorithm" env-default:"argon2id"`
+	BcryptCost int    `yaml:"bcrypt_cost" env-default:"10"`
+	// Argon2Memory is in KiB.
+	Argon2Memory      uint32 `yaml:"argon2_memory" env-default:"65536"`
+	Argon2Time        uint32 `yaml:"argon2_time" env-default:"3"`
+	Argon2Parallelism uint8  `yaml:"argon2_parallelism" env-default:"4"`
+	// Hashes with parameters above the limits are neither imported nor
+	// verified, they could exhaust the memory or CPU.
+	Limits PasswordHashLimitsConfig `yaml:"limits"`
+}
+
+type PasswordHashLimitsConfig struct {
+	// Argon2Memory is in KiB.
+	Argon2Memory      uint32 `yaml:"argon2_memory" env-default:"262144"`
+	Argon2Time        uint32 `yaml:"argon2_time" env-default:"10"`
+	Argon2Parallelism uint8  `yaml:"argon2_parallelism" env-default:"16"`
+	BcryptCost        int    `yaml:"bcrypt_cost" env-default:"14"`
+	PBKDF2Iterations  int    `yaml:"pbkdf2_iterations" env-default:"1000000"`
+	// ScryptCost bounds N·r·p, scrypt needs 128·N·r bytes of memory.
+	ScryptCost int `yaml:"scrypt_cost" env-default:"1048576"`
+}
+
+type PasswordPolicyConfig struct {
+	MinLength           int      `yaml:"min_length" env-default:"8"`
+	MaxLength           int      `yaml:"max_length" env-default:"72"`
+	RequireUpper        bool     `yaml:"require_upper"`
+	RequireLower        bool     `yaml:"require_lower"`
+	RequireDigit        bool     `yaml:"require_digit"`
+	RequireSymbol       bool     `yaml:"require_symbol"`
+	MaxRepeated         int      `yaml:"max_repeated"`
+	ForbiddenSubstrings []string `yaml:"forbidden_substrings"`
+	ForbidPersonalInfo  bool     `yaml:"forbid_personal_info" env-default:"true"`
+	// MinStrengthScore is from 0, accepting any password, to 4.
+	MinStrengthScore int `yaml:"min_strength_score"`
+}
+
 func MustLoad() *Config {
 	path := fetchConfigPath()
 	if path == "" {
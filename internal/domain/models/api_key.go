@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// APIKey is a long-lived credential of a user for one app. Only the hash of
+// the key is kept; the key itself is shown once when it is created.
+type APIKey struct {
+	ID         int64
+	UserID     int64
+	AppID      int
+	Name       string
+	KeyHash    []byte
+	Scope      string
+	CreatedAt  time.Time
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+}
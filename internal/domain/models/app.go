@@ -2,6 +2,14 @@ package models
 
 type App struct{
 	ID int
+	OrgID int64
 	Name string
 	Secret string
-}
\ No newline at end of file
+	// InviteOnly apps can only be signed in to by their members.
+	InviteOnly bool
+	// GroupsClaim apps get the names of the user's groups in tokens.
+	GroupsClaim bool
+	// Public apps can not keep a secret and redeem codes and refresh tokens
+	// with PKCE alone, all others have to authenticate with their secret.
+	Public bool
+}
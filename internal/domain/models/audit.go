@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+type AuditAction string
+
+const (
+	AuditImpersonate AuditAction = "impersonate"
+	// AuditAdminSignUp is an admin created at sign-up.
+	AuditAdminSignUp AuditAction = "admin_sign_up"
+	// AuditAdminSignUpDenied is a sign-up that asked for an admin without
+	// being allowed to and created a regular user instead.
+	AuditAdminSignUpDenied AuditAction = "admin_sign_up_denied"
+	AuditDemoteAdmin       AuditAction = "demote_admin"
+	AuditRevokeOrgAdmin    AuditAction = "revoke_org_admin"
+	AuditProposeChange     AuditAction = "propose_change"
+	AuditApproveChange     AuditAction = "approve_change"
+	AuditRejectChange      AuditAction = "reject_change"
+	AuditActivateUser      AuditAction = "activate_user"
+	AuditDisableUser       AuditAction = "disable_user"
+	AuditSuspendUser       AuditAction = "suspend_user"
+	AuditDeleteUser        AuditAction = "delete_user"
+)
+
+// AuditEvent records an admin, or somebody trying to be one, acting on a
+// user. Fields that do not apply to
+// the action are zero.
+type AuditEvent struct {
+	ID           int64
+	ActorID      int64
+	Action       AuditAction
+	TargetUserID int64
+	AppID        int
+	SessionID    int64
+	ChangeID     int64
+	Reason       string
+	IP           string
+	CreatedAt    time.Time
+}
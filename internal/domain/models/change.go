@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// ChangeKind is a privileged change that needs the approval of a second admin.
+type ChangeKind string
+
+const (
+	ChangePromoteAdmin    ChangeKind = "promote_admin"
+	ChangePromoteOrgAdmin ChangeKind = "promote_org_admin"
+	ChangeRotateAppSecret ChangeKind = "rotate_app_secret"
+	ChangeDeleteApp       ChangeKind = "delete_app"
+)
+
+type ChangeStatus string
+
+const (
+	ChangePending  ChangeStatus = "pending"
+	ChangeApproved ChangeStatus = "approved"
+	ChangeRejected ChangeStatus = "rejected"
+	ChangeExpired  ChangeStatus = "expired"
+)
+
+// PendingChange is a proposed privileged change. It is carried out only when
+// an admin other than the proposer approves it before it expires.
+type PendingChange struct {
+	ID           int64
+	Kind         ChangeKind
+	ProposerID   int64
+	TargetUserID int64
+	AppID        int
+	Reason       string
+	Status       ChangeStatus
+	DecidedBy    *int64
+	DecidedAt    *time.Time
+	CreatedAt    time.Time
+	ExpiresAt    time.Time
+}
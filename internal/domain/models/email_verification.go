@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// EmailVerification proves that the user received mail at the email. Only
+// the hash of the token is kept; the token itself is only mailed.
+type EmailVerification struct {
+	ID        int64
+	UserID    int64
+	Email     string
+	TokenHash []byte
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// Group is a set of users and of other groups of the same organization,
+// whose members belong to it too.
+type Group struct {
+	ID        int64
+	OrgID     int64
+	Name      string
+	CreatedAt time.Time
+}
@@ -0,0 +1,57 @@
+package models
+
+import "time"
+
+type AuthorizationCode struct {
+	ID                  int64
+	CodeHash            []byte
+	AppID               int
+	UserID              int64
+	RedirectURI         string
+	Scope               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+	Nonce               string
+	AuthTime            time.Time
+	CreatedAt           time.Time
+	ExpiresAt           time.Time
+	UsedAt              *time.Time
+}
+
+type RefreshToken struct {
+	ID                  int64
+	TokenHash           []byte
+	AppID               int
+	UserID              int64
+	AuthorizationCodeID *int64
+	SessionID           *int64
+	Scope               string
+	AuthTime            time.Time
+	CreatedAt           time.Time
+	ExpiresAt           time.Time
+	RevokedAt           *time.Time
+}
+
+type DeviceAuthorizationStatus string
+
+const (
+	DeviceAuthorizationPending  DeviceAuthorizationStatus = "pending"
+	DeviceAuthorizationApproved DeviceAuthorizationStatus = "approved"
+	DeviceAuthorizationDenied   DeviceAuthorizationStatus = "denied"
+	DeviceAuthorizationConsumed DeviceAuthorizationStatus = "consumed"
+)
+
+type DeviceAuthorization struct {
+	ID             int64
+	DeviceCodeHash []byte
+	UserCode       string
+	AppID          int
+	Scope          string
+	Status         DeviceAuthorizationStatus
+	UserID         *int64
+	PollInterval   time.Duration
+	LastPolledAt   *time.Time
+	AuthTime       *time.Time
+	CreatedAt      time.Time
+	ExpiresAt      time.Time
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// DefaultOrgID is the organization of users signing up without an app and
+// of everything created before there were organizations.
+const DefaultOrgID int64 = 1
+
+// Organization is a tenant owning users and apps. Emails are unique within
+// an organization only.
+type Organization struct {
+	ID        int64
+	Name      string
+	CreatedAt time.Time
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+type Session struct {
+	ID         int64
+	UserID     int64
+	AppID      int
+	Device     string
+	IP         string
+	UserAgent  string
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	RevokedAt  *time.Time
+	// ImpersonatorID is the admin who opened the session as the user, nil
+	// for sessions the user opened.
+	ImpersonatorID *int64
+}
+
+// ClientInfo describes the client a session is opened from.
+type ClientInfo struct {
+	Device    string
+	IP        string
+	UserAgent string
+}
@@ -1,8 +1,85 @@
 package models
 
-type User struct{
-	ID int64
-	Name string
-	Email string
-	PasswordHash []byte
-}
\ No newline at end of file
+import "time"
+
+type UserStatus string
+
+const (
+	UserStatusActive UserStatus = "active"
+	// UserStatusDisabled users cannot sign in until an admin activates them.
+	UserStatusDisabled UserStatus = "disabled"
+	// UserStatusSuspended users cannot sign in until SuspendedUntil.
+	UserStatusSuspended UserStatus = "suspended"
+	// UserStatusDeleted users are soft-deleted and kept for the records.
+	UserStatusDeleted UserStatus = "deleted"
+)
+
+type User struct {
+	ID            int64
+	OrgID         int64
+	Name          string
+	Email         string
+	PasswordHash  []byte
+	EmailVerified bool
+	IsAdmin       bool
+	// IsOrgAdmin users administer the users of their organization only.
+	IsOrgAdmin bool
+	Status     UserStatus
+	// StatusReason tells why an admin set the status.
+	StatusReason   string
+	SuspendedUntil *time.Time
+	CreatedAt      time.Time
+}
+
+// Active reports whether the user may sign in and use their tokens.
+func (u User) Active(now time.Time) bool {
+	switch u.Status {
+	case UserStatusActive:
+		return true
+	case UserStatusSuspended:
+		return u.SuspendedUntil != nil && !now.Before(*u.SuspendedUntil)
+	}
+	return false
+}
+
+type UserSortField string
+
+const (
+	UserSortCreatedAt UserSortField = "created_at"
+	UserSortEmail     UserSortField = "email"
+)
+
+// UserFilter selects users to list. Zero values match every user.
+type UserFilter struct {
+	OrgID         int64
+	EmailPrefix   string
+	IsAdmin       *bool
+	Status        UserStatus
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+}
+
+// UserCursor is the last user of the previous page.
+type UserCursor struct {
+	ID        int64
+	Email     string
+	CreatedAt time.Time
+}
+
+type UserListQuery struct {
+	Filter     UserFilter
+	SortBy     UserSortField
+	Descending bool
+	After      *UserCursor
+	Limit      int
+}
+
+// UserMatch is a user found by a search. The highlights are the user's name
+// and email, HTML-escaped, with the matched parts marked. Better matches have
+// higher scores.
+type UserMatch struct {
+	User           User
+	NameHighlight  string
+	EmailHighlight string
+	Score          float64
+}
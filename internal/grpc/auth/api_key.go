@@ -0,0 +1,135 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	ssov1 "github.com/DavidG9999/api/gen/go/sso"
+	"github.com/DavidG9999/my_grpc_app/internal/domain/models"
+	"github.com/DavidG9999/my_grpc_app/internal/services/auth"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *serverAPI) CreateAPIKey(ctx context.Context, req *ssov1.CreateAPIKeyRequest) (*ssov1.CreateAPIKeyResponse, error) {
+	if req.GetName() == "" {
+		return nil, status.Error(codes.InvalidArgument, "name is required")
+	}
+	if req.GetExpiresIn() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "expires_in must not be negative")
+	}
+	caller, err := s.caller(ctx)
+	if err != nil {
+		return nil, err
+	}
+	appID := int(req.GetAppId())
+	if appID == emptyValue {
+		appID = caller.AppID
+	}
+
+	secret, key, err := s.auth.CreateAPIKey(ctx, caller, appID, req.GetName(), req.GetScopes(),
+		time.Duration(req.GetExpiresIn())*time.Second)
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrPermissionDenied):
+			return nil, status.Error(codes.PermissionDenied, "not allowed to create api keys")
+		case errors.Is(err, auth.ErrInvalidAppID):
+			return nil, status.Error(codes.InvalidArgument, "app not found")
+		case errors.Is(err, auth.ErrNotAppMember):
+			return nil, status.Error(codes.PermissionDenied, "user is not a member of the app")
+		case errors.Is(err, auth.ErrInvalidScope):
+			return nil, status.Error(codes.PermissionDenied, "scope is not allowed for app")
+		}
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+	return &ssov1.CreateAPIKeyResponse{
+		Key:    secret,
+		ApiKey: apiKeyToProto(key),
+	}, nil
+}
+
+func (s *serverAPI) ListAPIKeys(ctx context.Context, req *ssov1.ListAPIKeysRequest) (*ssov1.ListAPIKeysResponse, error) {
+	caller, err := s.caller(ctx)
+	if err != nil {
+		return nil, err
+	}
+	userID := req.GetUserId()
+	if userID == emptyValue {
+		userID = caller.UserID
+	}
+
+	keys, err := s.auth.ListAPIKeys(ctx, caller.UserID, userID)
+	if err != nil {
+		if errors.Is(err, auth.ErrPermissionDenied) {
+			return nil, status.Error(codes.PermissionDenied, "not allowed to list api keys of user")
+		}
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	resp := &ssov1.ListAPIKeysResponse{ApiKeys: make([]*ssov1.APIKey, 0, len(keys))}
+	for _, key := range keys {
+		resp.ApiKeys = append(resp.ApiKeys, apiKeyToProto(key))
+	}
+	return resp, nil
+}
+
+func (s *serverAPI) RevokeAPIKey(ctx context.Context, req *ssov1.RevokeAPIKeyRequest) (*ssov1.RevokeAPIKeyResponse, error) {
+	if req.GetApiKeyId() == emptyValue {
+		return nil, status.Error(codes.InvalidArgument, "api_key_id is required")
+	}
+	caller, err := s.caller(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.auth.RevokeAPIKey(ctx, caller.UserID, req.GetApiKeyId()); err != nil {
+		if errors.Is(err, auth.ErrAPIKeyNotFound) {
+			return nil, status.Error(codes.NotFound, "api key not found")
+		}
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+	return &ssov1.RevokeAPIKeyResponse{}, nil
+}
+
+func (s *serverAPI) ExchangeAPIKey(ctx context.Context, req *ssov1.ExchangeAPIKeyRequest) (*ssov1.ExchangeAPIKeyResponse, error) {
+	if req.GetApiKey() == "" {
+		return nil, status.Error(codes.InvalidArgument, "api_key is required")
+	}
+
+	token, err := s.auth.ExchangeAPIKey(ctx, req.GetApiKey(), req.GetScopes())
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrInvalidAPIKey):
+			return nil, status.Error(codes.Unauthenticated, "invalid api key")
+		case errors.Is(err, auth.ErrNotAppMember):
+			return nil, status.Error(codes.PermissionDenied, "user is not a member of the app")
+		case errors.Is(err, auth.ErrInvalidScope):
+			return nil, status.Error(codes.PermissionDenied, "scope is not allowed for api key")
+		}
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+	return &ssov1.ExchangeAPIKeyResponse{
+		Token:     token.Token,
+		ExpiresIn: int64(token.ExpiresIn.Seconds()),
+		Scopes:    token.Scopes,
+	}, nil
+}
+
+func apiKeyToProto(key models.APIKey) *ssov1.APIKey {
+	out := &ssov1.APIKey{
+		Id:        key.ID,
+		AppId:     int32(key.AppID),
+		Name:      key.Name,
+		Scopes:    strings.Fields(key.Scope),
+		CreatedAt: key.CreatedAt.Unix(),
+	}
+	if key.ExpiresAt != nil {
+		out.ExpiresAt = key.ExpiresAt.Unix()
+	}
+	if key.LastUsedAt != nil {
+		out.LastUsedAt = key.LastUsedAt.Unix()
+	}
+	return out
+}
@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"context"
+	"errors"
+
+	ssov1 "github.com/DavidG9999/api/gen/go/sso"
+	"github.com/DavidG9999/my_grpc_app/internal/services/auth"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *serverAPI) AddUserToApp(ctx context.Context, req *ssov1.AddUserToAppRequest) (*ssov1.AddUserToAppResponse, error) {
+	if err := validateAppMember(req.GetAppId(), req.GetUserId()); err != nil {
+		return nil, err
+	}
+	caller, err := s.caller(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.auth.AddUserToApp(ctx, caller.UserID, int(req.GetAppId()), req.GetUserId())
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrPermissionDenied):
+			return nil, status.Error(codes.PermissionDenied, "not allowed to manage app members")
+		case errors.Is(err, auth.ErrInvalidAppID):
+			return nil, status.Error(codes.NotFound, "app not found")
+		case errors.Is(err, auth.ErrUserNotFound):
+			return nil, status.Error(codes.NotFound, "user not found")
+		}
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+	return &ssov1.AddUserToAppResponse{}, nil
+}
+
+func (s *serverAPI) RemoveUserFromApp(ctx context.Context, req *ssov1.RemoveUserFromAppRequest) (*ssov1.RemoveUserFromAppResponse, error) {
+	if err := validateAppMember(req.GetAppId(), req.GetUserId()); err != nil {
+		return nil, err
+	}
+	caller, err := s.caller(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.auth.RemoveUserFromApp(ctx, caller.UserID, int(req.GetAppId()), req.GetUserId())
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrPermissionDenied):
+			return nil, status.Error(codes.PermissionDenied, "not allowed to manage app members")
+		case errors.Is(err, auth.ErrInvalidAppID):
+			return nil, status.Error(codes.NotFound, "app not found")
+		case errors.Is(err, auth.ErrNotAppMember):
+			return nil, status.Error(codes.NotFound, "user is not a member of the app")
+		}
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+	return &ssov1.RemoveUserFromAppResponse{}, nil
+}
+
+func validateAppMember(appID int32, userID int64) error {
+	if appID == emptyValue {
+		return status.Error(codes.InvalidArgument, "app_id is required")
+	}
+	if userID == emptyValue {
+		return status.Error(codes.InvalidArgument, "user_id is required")
+	}
+	return nil
+}
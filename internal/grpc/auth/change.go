@@ -0,0 +1,131 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	ssov1 "github.com/DavidG9999/api/gen/go/sso"
+	"github.com/DavidG9999/my_grpc_app/internal/domain/models"
+	"github.com/DavidG9999/my_grpc_app/internal/services/auth"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *serverAPI) ProposeChange(ctx context.Context, req *ssov1.ProposeChangeRequest) (*ssov1.ProposeChangeResponse, error) {
+	if err := validateProposeChange(req); err != nil {
+		return nil, err
+	}
+	caller, err := s.caller(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	change, err := s.auth.ProposeChange(ctx, caller, models.ChangeKind(req.GetKind()), req.GetUserId(), int(req.GetAppId()),
+		req.GetReason(), clientInfo(ctx))
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrPermissionDenied):
+			return nil, status.Error(codes.PermissionDenied, "only global admins can propose changes")
+		case errors.Is(err, auth.ErrUserNotFound):
+			return nil, status.Error(codes.NotFound, "user not found")
+		case errors.Is(err, auth.ErrUserIsAdmin):
+			return nil, status.Error(codes.FailedPrecondition, "user is a global admin")
+		case errors.Is(err, auth.ErrInvalidAppID):
+			return nil, status.Error(codes.NotFound, "app not found")
+		}
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+	return &ssov1.ProposeChangeResponse{
+		Change: changeToProto(change),
+	}, nil
+}
+
+func (s *serverAPI) ApproveChange(ctx context.Context, req *ssov1.ApproveChangeRequest) (*ssov1.ApproveChangeResponse, error) {
+	if req.GetChangeId() == emptyValue {
+		return nil, status.Error(codes.InvalidArgument, "change_id is required")
+	}
+	caller, err := s.caller(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.auth.ApproveChange(ctx, caller, req.GetChangeId(), clientInfo(ctx))
+	if err != nil {
+		return nil, changeError(err)
+	}
+	return &ssov1.ApproveChangeResponse{
+		Change:    changeToProto(result.Change),
+		AppSecret: result.AppSecret,
+	}, nil
+}
+
+func (s *serverAPI) RejectChange(ctx context.Context, req *ssov1.RejectChangeRequest) (*ssov1.RejectChangeResponse, error) {
+	if req.GetChangeId() == emptyValue {
+		return nil, status.Error(codes.InvalidArgument, "change_id is required")
+	}
+	caller, err := s.caller(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.auth.RejectChange(ctx, caller, req.GetChangeId(), req.GetReason(), clientInfo(ctx)); err != nil {
+		return nil, changeError(err)
+	}
+	return &ssov1.RejectChangeResponse{}, nil
+}
+
+func validateProposeChange(req *ssov1.ProposeChangeRequest) error {
+	switch models.ChangeKind(req.GetKind()) {
+	case models.ChangePromoteAdmin, models.ChangePromoteOrgAdmin:
+		if req.GetUserId() == emptyValue {
+			return status.Error(codes.InvalidArgument, "user_id is required")
+		}
+	case models.ChangeRotateAppSecret, models.ChangeDeleteApp:
+		if req.GetAppId() == emptyValue {
+			return status.Error(codes.InvalidArgument, "app_id is required")
+		}
+	default:
+		return status.Error(codes.InvalidArgument, "unknown change kind")
+	}
+	if strings.TrimSpace(req.GetReason()) == "" {
+		return status.Error(codes.InvalidArgument, "reason is required")
+	}
+	return nil
+}
+
+func changeError(err error) error {
+	switch {
+	case errors.Is(err, auth.ErrPermissionDenied):
+		return status.Error(codes.PermissionDenied, "only global admins can decide changes")
+	case errors.Is(err, auth.ErrSelfApproval):
+		return status.Error(codes.PermissionDenied, "change must be approved by another admin")
+	case errors.Is(err, auth.ErrChangeNotFound):
+		return status.Error(codes.NotFound, "change not found")
+	case errors.Is(err, auth.ErrChangeDecided):
+		return status.Error(codes.FailedPrecondition, "change already decided")
+	case errors.Is(err, auth.ErrChangeExpired):
+		return status.Error(codes.FailedPrecondition, "change expired")
+	case errors.Is(err, auth.ErrUserNotFound):
+		return status.Error(codes.FailedPrecondition, "user no longer exists")
+	case errors.Is(err, auth.ErrUserIsAdmin):
+		return status.Error(codes.FailedPrecondition, "user is a global admin")
+	case errors.Is(err, auth.ErrInvalidAppID):
+		return status.Error(codes.FailedPrecondition, "app no longer exists")
+	}
+	return status.Error(codes.Internal, "internal error")
+}
+
+func changeToProto(change models.PendingChange) *ssov1.PendingChange {
+	return &ssov1.PendingChange{
+		Id:         change.ID,
+		Kind:       string(change.Kind),
+		ProposerId: change.ProposerID,
+		UserId:     change.TargetUserID,
+		AppId:      int32(change.AppID),
+		Reason:     change.Reason,
+		Status:     string(change.Status),
+		CreatedAt:  change.CreatedAt.Unix(),
+		ExpiresAt:  change.ExpiresAt.Unix(),
+	}
+}
@@ -0,0 +1,145 @@
+package auth
+
+import (
+	"context"
+	"errors"
+
+	ssov1 "github.com/DavidG9999/api/gen/go/sso"
+	"github.com/DavidG9999/my_grpc_app/internal/domain/models"
+	"github.com/DavidG9999/my_grpc_app/internal/services/auth"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *serverAPI) CreateGroup(ctx context.Context, req *ssov1.CreateGroupRequest) (*ssov1.CreateGroupResponse, error) {
+	if req.GetName() == "" {
+		return nil, status.Error(codes.InvalidArgument, "name is required")
+	}
+	caller, err := s.caller(ctx)
+	if err != nil {
+		return nil, err
+	}
+	orgID := req.GetOrgId()
+	if orgID == emptyValue {
+		orgID = caller.OrgID
+	}
+
+	groupID, err := s.auth.CreateGroup(ctx, caller.UserID, orgID, req.GetName())
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrPermissionDenied):
+			return nil, status.Error(codes.PermissionDenied, "not allowed to create groups")
+		case errors.Is(err, auth.ErrOrganizationNotFound):
+			return nil, status.Error(codes.NotFound, "organization not found")
+		case errors.Is(err, auth.ErrGroupExists):
+			return nil, status.Error(codes.AlreadyExists, "group already exists")
+		}
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+	return &ssov1.CreateGroupResponse{
+		GroupId: groupID,
+	}, nil
+}
+
+func (s *serverAPI) AddGroupMember(ctx context.Context, req *ssov1.AddGroupMemberRequest) (*ssov1.AddGroupMemberResponse, error) {
+	if err := validateAddGroupMember(req); err != nil {
+		return nil, err
+	}
+	caller, err := s.caller(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.auth.AddGroupMember(ctx, caller.UserID, req.GetGroupId(), req.GetUserId(), req.GetMemberGroupId())
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrPermissionDenied):
+			return nil, status.Error(codes.PermissionDenied, "not allowed to manage groups")
+		case errors.Is(err, auth.ErrGroupNotFound):
+			return nil, status.Error(codes.NotFound, "group not found")
+		case errors.Is(err, auth.ErrUserNotFound):
+			return nil, status.Error(codes.NotFound, "user not found")
+		case errors.Is(err, auth.ErrGroupCycle):
+			return nil, status.Error(codes.FailedPrecondition, "group would contain itself")
+		}
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+	return &ssov1.AddGroupMemberResponse{}, nil
+}
+
+func validateAddGroupMember(req *ssov1.AddGroupMemberRequest) error {
+	if req.GetGroupId() == emptyValue {
+		return status.Error(codes.InvalidArgument, "group_id is required")
+	}
+	if (req.GetUserId() == emptyValue) == (req.GetMemberGroupId() == emptyValue) {
+		return status.Error(codes.InvalidArgument, "exactly one of user_id and member_group_id is required")
+	}
+	return nil
+}
+
+func (s *serverAPI) ListGroupMembers(ctx context.Context, req *ssov1.ListGroupMembersRequest) (*ssov1.ListGroupMembersResponse, error) {
+	if req.GetGroupId() == emptyValue {
+		return nil, status.Error(codes.InvalidArgument, "group_id is required")
+	}
+	caller, err := s.caller(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	members, err := s.auth.ListGroupMembers(ctx, caller.UserID, req.GetGroupId(), req.GetEffective())
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrPermissionDenied):
+			return nil, status.Error(codes.PermissionDenied, "not allowed to list group members")
+		case errors.Is(err, auth.ErrGroupNotFound):
+			return nil, status.Error(codes.NotFound, "group not found")
+		}
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	users := make([]*ssov1.User, 0, len(members.Users))
+	for _, user := range members.Users {
+		users = append(users, userToProto(user))
+	}
+	return &ssov1.ListGroupMembersResponse{
+		Users:  users,
+		Groups: groupsToProto(members.Groups),
+	}, nil
+}
+
+func (s *serverAPI) ListUserGroups(ctx context.Context, req *ssov1.ListUserGroupsRequest) (*ssov1.ListUserGroupsResponse, error) {
+	caller, err := s.caller(ctx)
+	if err != nil {
+		return nil, err
+	}
+	userID := req.GetUserId()
+	if userID == emptyValue {
+		userID = caller.UserID
+	}
+
+	groups, err := s.auth.ListUserGroups(ctx, caller.UserID, userID, req.GetEffective())
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrPermissionDenied):
+			return nil, status.Error(codes.PermissionDenied, "not allowed to list user groups")
+		case errors.Is(err, auth.ErrUserNotFound):
+			return nil, status.Error(codes.NotFound, "user not found")
+		}
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+	return &ssov1.ListUserGroupsResponse{
+		Groups: groupsToProto(groups),
+	}, nil
+}
+
+func groupsToProto(groups []models.Group) []*ssov1.Group {
+	out := make([]*ssov1.Group, 0, len(groups))
+	for _, group := range groups {
+		out = append(out, &ssov1.Group{
+			Id:    group.ID,
+			OrgId: group.OrgID,
+			Name:  group.Name,
+		})
+	}
+	return out
+}
@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	ssov1 "github.com/DavidG9999/api/gen/go/sso"
+	"github.com/DavidG9999/my_grpc_app/internal/services/auth"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *serverAPI) Impersonate(ctx context.Context, req *ssov1.ImpersonateRequest) (*ssov1.ImpersonateResponse, error) {
+	if req.GetUserId() == emptyValue {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+	if strings.TrimSpace(req.GetReason()) == "" {
+		return nil, status.Error(codes.InvalidArgument, "reason is required")
+	}
+	caller, err := s.caller(ctx)
+	if err != nil {
+		return nil, err
+	}
+	appID := int(req.GetAppId())
+	if appID == emptyValue {
+		appID = caller.AppID
+	}
+
+	token, err := s.auth.Impersonate(ctx, caller, req.GetUserId(), appID, req.GetReason(), clientInfo(ctx))
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrPermissionDenied):
+			return nil, status.Error(codes.PermissionDenied, "not allowed to impersonate user")
+		case errors.Is(err, auth.ErrUserNotFound):
+			return nil, status.Error(codes.NotFound, "user not found")
+		case errors.Is(err, auth.ErrInvalidAppID):
+			return nil, status.Error(codes.InvalidArgument, "app not found")
+		case errors.Is(err, auth.ErrNotAppMember):
+			return nil, status.Error(codes.FailedPrecondition, "user is not a member of the app")
+		}
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+	return &ssov1.ImpersonateResponse{
+		Token:     token.Token,
+		ExpiresIn: int64(token.ExpiresIn.Seconds()),
+	}, nil
+}
@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"context"
+	"errors"
+
+	ssov1 "github.com/DavidG9999/api/gen/go/sso"
+	"github.com/DavidG9999/my_grpc_app/internal/services/auth"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const maxImportBatch = 1000
+
+func (s *serverAPI) ImportUsers(ctx context.Context, req *ssov1.ImportUsersRequest) (*ssov1.ImportUsersResponse, error) {
+	if err := validateImportUsers(req); err != nil {
+		return nil, err
+	}
+	caller, err := s.caller(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	users := make([]auth.ImportedUser, 0, len(req.GetUsers()))
+	for _, user := range req.GetUsers() {
+		users = append(users, auth.ImportedUser{
+			Name:         user.GetName(),
+			Email:        user.GetEmail(),
+			PasswordHash: []byte(user.GetPasswordHash()),
+			IsAdmin:      user.GetIsAdmin(),
+		})
+	}
+
+	orgID := req.GetOrgId()
+	if orgID == emptyValue {
+		orgID = caller.OrgID
+	}
+
+	result, err := s.auth.ImportUsers(ctx, caller.UserID, orgID, users)
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrPermissionDenied):
+			return nil, status.Error(codes.PermissionDenied, "only admins can import users")
+		case errors.Is(err, auth.ErrOrganizationNotFound):
+			return nil, status.Error(codes.NotFound, "organization not found")
+		}
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	resp := &ssov1.ImportUsersResponse{Imported: result.Imported}
+	for _, failure := range result.Failures {
+		reason := "internal error"
+		switch {
+		case errors.Is(failure.Err, auth.ErrUserExist):
+			reason = "user already exists"
+		case errors.Is(failure.Err, auth.ErrUnknownPasswordHash):
+			reason = "unknown password hash format"
+		case errors.Is(failure.Err, auth.ErrPasswordHashLimits):
+			reason = "password hash parameters are out of limits"
+		case errors.Is(failure.Err, auth.ErrPermissionDenied):
+			reason = "only global admins can import admins"
+		case errors.Is(failure.Err, auth.ErrApprovalRequired):
+			reason = "admins are promoted through approved changes"
+		}
+		resp.Failures = append(resp.Failures, &ssov1.ImportFailure{
+			Index:  int32(failure.Index),
+			Email:  failure.Email,
+			Reason: reason,
+		})
+	}
+	return resp, nil
+}
+
+func validateImportUsers(req *ssov1.ImportUsersRequest) error {
+	if len(req.GetUsers()) == 0 {
+		return status.Error(codes.InvalidArgument, "users are required")
+	}
+	if len(req.GetUsers()) > maxImportBatch {
+		return status.Errorf(codes.InvalidArgument, "at most %d users can be imported at once", maxImportBatch)
+	}
+	for _, user := range req.GetUsers() {
+		if user.GetEmail() == "" {
+			return status.Error(codes.InvalidArgument, "email is required")
+		}
+		if user.GetName() == "" {
+			return status.Error(codes.InvalidArgument, "name is required")
+		}
+		if user.GetPasswordHash() == "" {
+			return status.Error(codes.InvalidArgument, "password_hash is required")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	ssov1 "github.com/DavidG9999/api/gen/go/sso"
+	"github.com/DavidG9999/my_grpc_app/internal/lib/jwt"
+	"github.com/DavidG9999/my_grpc_app/internal/services/auth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const bearerPrefix = "Bearer "
+
+// Access is who may call an RPC.
+type Access int
+
+const (
+	// Public RPCs authenticate their callers themselves, if at all.
+	Public Access = iota + 1
+	// Optional RPCs are public, but get the caller if there is a bearer
+	// token. An invalid one is still rejected.
+	Optional
+	// Authenticated RPCs need a valid bearer token.
+	Authenticated
+	// Admin RPCs need the bearer token of a global or an organization admin,
+	// signed in themselves: delegated and API key tokens are not accepted.
+	// Handlers still check what the admin may do.
+	Admin
+)
+
+// policies lists the access to every RPC. RPCs missing here cannot be called.
+var policies = map[string]Access{
+	ssov1.Auth_SignUp_FullMethodName:                   Optional,
+	ssov1.Auth_SignIn_FullMethodName:                   Public,
+	ssov1.Auth_ClientCredentials_FullMethodName:        Public,
+	ssov1.Auth_StartDeviceAuthorization_FullMethodName: Public,
+	ssov1.Auth_PollDeviceToken_FullMethodName:          Public,
+	ssov1.Auth_ExchangeToken_FullMethodName:            Public,
+	ssov1.Auth_ValidateToken_FullMethodName:            Public,
+	ssov1.Auth_ExchangeAPIKey_FullMethodName:           Public,
+	ssov1.Auth_VerifyEmail_FullMethodName:              Public,
+
+	ssov1.Auth_IsAdmin_FullMethodName:               Authenticated,
+	ssov1.Auth_ListSessions_FullMethodName:          Authenticated,
+	ssov1.Auth_RevokeSession_FullMethodName:         Authenticated,
+	ssov1.Auth_RevokeAllSessions_FullMethodName:     Authenticated,
+	ssov1.Auth_GetUser_FullMethodName:               Authenticated,
+	ssov1.Auth_UpdateUser_FullMethodName:            Authenticated,
+	ssov1.Auth_ChangePassword_FullMethodName:        Authenticated,
+	ssov1.Auth_SendEmailVerification_FullMethodName: Authenticated,
+	ssov1.Auth_ListUserGroups_FullMethodName:        Authenticated,
+	ssov1.Auth_CreateAPIKey_FullMethodName:          Authenticated,
+	ssov1.Auth_ListAPIKeys_FullMethodName:           Authenticated,
+	ssov1.Auth_RevokeAPIKey_FullMethodName:          Authenticated,
+
+	ssov1.Auth_ImportUsers_FullMethodName:        Admin,
+	ssov1.Auth_ListUsers_FullMethodName:          Admin,
+	ssov1.Auth_SearchUsers_FullMethodName:        Admin,
+	ssov1.Auth_SetUserStatus_FullMethodName:      Admin,
+	ssov1.Auth_AddUserToApp_FullMethodName:       Admin,
+	ssov1.Auth_RemoveUserFromApp_FullMethodName:  Admin,
+	ssov1.Auth_CreateOrganization_FullMethodName: Admin,
+	ssov1.Auth_SetOrgAdmin_FullMethodName:        Admin,
+	ssov1.Auth_SetAdmin_FullMethodName:           Admin,
+	ssov1.Auth_ProposeChange_FullMethodName:      Admin,
+	ssov1.Auth_ApproveChange_FullMethodName:      Admin,
+	ssov1.Auth_RejectChange_FullMethodName:       Admin,
+	ssov1.Auth_CreateGroup_FullMethodName:        Admin,
+	ssov1.Auth_AddGroupMember_FullMethodName:     Admin,
+	ssov1.Auth_ListGroupMembers_FullMethodName:   Admin,
+	ssov1.Auth_Impersonate_FullMethodName:        Admin,
+}
+
+// Authenticator verifies the callers of RPCs.
+type Authenticator interface {
+	ValidateToken(ctx context.Context, token string) (jwt.Claims, error)
+	IsAdministrator(ctx context.Context, userID int64) (bool, error)
+}
+
+type callerKey struct{}
+
+// Interceptor enforces the access policy of RPCs. The verified claims of the
+// bearer token are passed on to the handler in the context.
+func Interceptor(authenticator Authenticator) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		access, ok := policies[info.FullMethod]
+		if !ok {
+			return nil, status.Error(codes.PermissionDenied, "method is not allowed")
+		}
+		if access == Public {
+			return handler(ctx, req)
+		}
+		if access == Optional {
+			md, _ := metadata.FromIncomingContext(ctx)
+			if len(md.Get("authorization")) == 0 {
+				return handler(ctx, req)
+			}
+		}
+
+		claims, err := authenticate(ctx, authenticator)
+		if err != nil {
+			return nil, err
+		}
+		if access == Admin {
+			if claims.Actor != nil || claims.APIKeyID != 0 {
+				return nil, status.Error(codes.PermissionDenied, "admin access needs the admin's own token")
+			}
+			isAdmin, err := authenticator.IsAdministrator(ctx, claims.UserID)
+			if err != nil {
+				return nil, status.Error(codes.Internal, "internal error")
+			}
+			if !isAdmin {
+				return nil, status.Error(codes.PermissionDenied, "admin access is required")
+			}
+		}
+		return handler(context.WithValue(ctx, callerKey{}, claims), req)
+	}
+}
+
+func authenticate(ctx context.Context, authenticator Authenticator) (jwt.Claims, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 || !strings.HasPrefix(values[0], bearerPrefix) {
+		return jwt.Claims{}, status.Error(codes.Unauthenticated, "bearer token is required")
+	}
+
+	claims, err := authenticator.ValidateToken(ctx, strings.TrimPrefix(values[0], bearerPrefix))
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			return jwt.Claims{}, status.Error(codes.Unauthenticated, "invalid token")
+		}
+		return jwt.Claims{}, status.Error(codes.Internal, "internal error")
+	}
+	return claims, nil
+}
+
+// caller returns the claims the interceptor verified for the RPC.
+func (s *serverAPI) caller(ctx context.Context) (jwt.Claims, error) {
+	claims, ok := ctx.Value(callerKey{}).(jwt.Claims)
+	if !ok {
+		return jwt.Claims{}, status.Error(codes.Unauthenticated, "bearer token is required")
+	}
+	return claims, nil
+}
@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"context"
+	"errors"
+
+	ssov1 "github.com/DavidG9999/api/gen/go/sso"
+	"github.com/DavidG9999/my_grpc_app/internal/services/auth"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *serverAPI) CreateOrganization(ctx context.Context, req *ssov1.CreateOrganizationRequest) (*ssov1.CreateOrganizationResponse, error) {
+	if req.GetName() == "" {
+		return nil, status.Error(codes.InvalidArgument, "name is required")
+	}
+	caller, err := s.caller(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	orgID, err := s.auth.CreateOrganization(ctx, caller.UserID, req.GetName())
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrPermissionDenied):
+			return nil, status.Error(codes.PermissionDenied, "only global admins can create organizations")
+		case errors.Is(err, auth.ErrOrganizationExists):
+			return nil, status.Error(codes.AlreadyExists, "organization already exists")
+		}
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+	return &ssov1.CreateOrganizationResponse{
+		OrgId: orgID,
+	}, nil
+}
+
+func (s *serverAPI) SetOrgAdmin(ctx context.Context, req *ssov1.SetOrgAdminRequest) (*ssov1.SetOrgAdminResponse, error) {
+	if req.GetUserId() == emptyValue {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+	caller, err := s.caller(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.auth.SetOrgAdmin(ctx, caller.UserID, req.GetUserId(), req.GetIsOrgAdmin(), clientInfo(ctx))
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrPermissionDenied):
+			return nil, status.Error(codes.PermissionDenied, "not allowed to set org admins")
+		case errors.Is(err, auth.ErrUserNotFound):
+			return nil, status.Error(codes.NotFound, "user not found")
+		case errors.Is(err, auth.ErrUserIsAdmin):
+			return nil, status.Error(codes.FailedPrecondition, "user is a global admin")
+		case errors.Is(err, auth.ErrApprovalRequired):
+			return nil, status.Error(codes.FailedPrecondition, "org admin grants must be proposed and approved")
+		}
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+	return &ssov1.SetOrgAdminResponse{}, nil
+}
+
+func (s *serverAPI) SetAdmin(ctx context.Context, req *ssov1.SetAdminRequest) (*ssov1.SetAdminResponse, error) {
+	if req.GetUserId() == emptyValue {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+	caller, err := s.caller(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.auth.SetAdmin(ctx, caller, req.GetUserId(), req.GetIsAdmin(), clientInfo(ctx))
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrPermissionDenied):
+			return nil, status.Error(codes.PermissionDenied, "only global admins can set admins")
+		case errors.Is(err, auth.ErrUserNotFound):
+			return nil, status.Error(codes.NotFound, "user not found")
+		case errors.Is(err, auth.ErrLastAdmin):
+			return nil, status.Error(codes.FailedPrecondition, "cannot demote the last admin")
+		case errors.Is(err, auth.ErrApprovalRequired):
+			return nil, status.Error(codes.FailedPrecondition, "promotions must be proposed and approved")
+		}
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+	return &ssov1.SetAdminResponse{}, nil
+}
@@ -3,12 +3,18 @@ package auth
 import (
 	"context"
 	"errors"
+	"strconv"
+	"strings"
 
 	ssov1 "github.com/DavidG9999/api/gen/go/sso"
+	"github.com/DavidG9999/my_grpc_app/internal/lib/password"
 	"github.com/DavidG9999/my_grpc_app/internal/services/auth"
+	"github.com/DavidG9999/my_grpc_app/internal/services/oauth"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
 )
 
 const (
@@ -17,11 +23,12 @@ const (
 
 type serverAPI struct {
 	ssov1.UnimplementedAuthServer
-	auth auth.Auth
+	auth  auth.Auth
+	oauth *oauth.OAuth
 }
 
-func Register(gPRC *grpc.Server, auth auth.Auth) {
-	ssov1.RegisterAuthServer(gPRC, &serverAPI{auth: auth})
+func Register(gPRC grpc.ServiceRegistrar, auth auth.Auth, oauth *oauth.OAuth) {
+	ssov1.RegisterAuthServer(gPRC, &serverAPI{auth: auth, oauth: oauth})
 }
 
 func (s *serverAPI) SignUp(ctx context.Context, req *ssov1.SignUpRequest) (*ssov1.SignUpResponse, error) {
@@ -29,15 +36,30 @@ func (s *serverAPI) SignUp(ctx context.Context, req *ssov1.SignUpRequest) (*ssov
 		return nil, err
 	}
 
-	userId, err := s.auth.SighUp(ctx, req.GetName(), req.GetEmail(), req.GetPassword(), req.GetIsAdmin())
+	// Sign-ups are public, but only admins may create admins.
+	var actorID int64
+	if caller, err := s.caller(ctx); err == nil {
+		actorID = caller.UserID
+	}
+
+	userId, isAdmin, err := s.auth.SighUp(ctx, actorID, req.GetName(), req.GetEmail(), req.GetPassword(), req.GetIsAdmin(),
+		int(req.GetAppId()), req.GetBootstrapToken(), clientInfo(ctx))
 	if err != nil {
 		if errors.Is(err, auth.ErrUserExist) {
 			return nil, status.Error(codes.AlreadyExists, "user already exists")
 		}
+		if errors.Is(err, auth.ErrInvalidAppID) {
+			return nil, status.Error(codes.InvalidArgument, "app not found")
+		}
+		var policyErr *password.PolicyError
+		if errors.As(err, &policyErr) {
+			return nil, passwordPolicyError(policyErr)
+		}
 		return nil, status.Error(codes.Internal, "internal error")
 	}
 	return &ssov1.SignUpResponse{
-		UserId: userId,
+		UserId:  userId,
+		IsAdmin: isAdmin,
 	}, nil
 }
 
@@ -45,7 +67,7 @@ func (s *serverAPI) SignIn(ctx context.Context, req *ssov1.SignInRequest) (*ssov
 	if err := validateSighIn(req); err != nil {
 		return nil, err
 	}
-	token, err := s.auth.SignIn(ctx, req.GetEmail(), req.GetPassword(), int(req.GetAppId()))
+	token, err := s.auth.SignIn(ctx, req.GetEmail(), req.GetPassword(), int(req.GetAppId()), clientInfo(ctx))
 	if err != nil {
 		if errors.Is(err, auth.ErrInvalidCredentials) {
 			return nil, status.Error(codes.InvalidArgument, "user not found")
@@ -53,6 +75,18 @@ func (s *serverAPI) SignIn(ctx context.Context, req *ssov1.SignInRequest) (*ssov
 		if errors.Is(err, auth.ErrInvalidAppID) {
 			return nil, status.Error(codes.InvalidArgument, "app not found")
 		}
+		if errors.Is(err, auth.ErrNotAppMember) {
+			return nil, status.Error(codes.PermissionDenied, "user is not a member of the app")
+		}
+		if errors.Is(err, auth.ErrUserDisabled) {
+			return nil, status.Error(codes.PermissionDenied, "user is disabled")
+		}
+		if errors.Is(err, auth.ErrUserSuspended) {
+			return nil, status.Error(codes.FailedPrecondition, "user is suspended")
+		}
+		if errors.Is(err, auth.ErrUserDeleted) {
+			return nil, status.Error(codes.NotFound, "user is deleted")
+		}
 		return nil, status.Error(codes.Internal, "internal error")
 	}
 	return &ssov1.SignInResponse{
@@ -64,8 +98,15 @@ func (s *serverAPI) IsAdmin(ctx context.Context, req *ssov1.IsAdminRequest) (*ss
 	if err := validateIsAdmin(req); err != nil {
 		return nil, err
 	}
-	isAdmin, err := s.auth.IsAdmin(ctx, req.GetUserId())
+	caller, err := s.caller(ctx)
 	if err != nil {
+		return nil, err
+	}
+	isAdmin, err := s.auth.IsAdmin(ctx, caller.UserID, req.GetUserId())
+	if err != nil {
+		if errors.Is(err, auth.ErrPermissionDenied) {
+			return nil, status.Error(codes.PermissionDenied, "not allowed to check user")
+		}
 		if errors.Is(err, auth.ErrUserNotFound) {
 			return nil, status.Error(codes.NotFound, "user not found")
 		}
@@ -76,6 +117,140 @@ func (s *serverAPI) IsAdmin(ctx context.Context, req *ssov1.IsAdminRequest) (*ss
 	}, nil
 }
 
+func (s *serverAPI) ClientCredentials(ctx context.Context, req *ssov1.ClientCredentialsRequest) (*ssov1.ClientCredentialsResponse, error) {
+	if err := validateClientCredentials(req); err != nil {
+		return nil, err
+	}
+	token, err := s.auth.ClientCredentials(ctx, int(req.GetAppId()), req.GetAppSecret(), req.GetScopes())
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidAppID) || errors.Is(err, auth.ErrInvalidAppSecret) {
+			return nil, status.Error(codes.Unauthenticated, "invalid app credentials")
+		}
+		if errors.Is(err, auth.ErrInvalidScope) {
+			return nil, status.Error(codes.PermissionDenied, "scope is not allowed for app")
+		}
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+	return &ssov1.ClientCredentialsResponse{
+		Token:     token.Token,
+		ExpiresIn: int64(token.ExpiresIn.Seconds()),
+		Scopes:    token.Scopes,
+	}, nil
+}
+
+func (s *serverAPI) StartDeviceAuthorization(ctx context.Context, req *ssov1.StartDeviceAuthorizationRequest) (*ssov1.StartDeviceAuthorizationResponse, error) {
+	if req.GetAppId() == emptyValue {
+		return nil, status.Error(codes.InvalidArgument, "app_id is required")
+	}
+	resp, err := s.oauth.StartDeviceAuthorization(ctx, strconv.Itoa(int(req.GetAppId())), strings.Join(req.GetScopes(), " "))
+	if err != nil {
+		if errors.Is(err, oauth.ErrInvalidClient) {
+			return nil, status.Error(codes.InvalidArgument, "app not found")
+		}
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+	return &ssov1.StartDeviceAuthorizationResponse{
+		DeviceCode:              resp.DeviceCode,
+		UserCode:                resp.UserCode,
+		VerificationUri:         resp.VerificationURI,
+		VerificationUriComplete: resp.VerificationURIComplete,
+		ExpiresIn:               int64(resp.ExpiresIn.Seconds()),
+		Interval:                int64(resp.Interval.Seconds()),
+	}, nil
+}
+
+func (s *serverAPI) PollDeviceToken(ctx context.Context, req *ssov1.PollDeviceTokenRequest) (*ssov1.PollDeviceTokenResponse, error) {
+	if err := validatePollDeviceToken(req); err != nil {
+		return nil, err
+	}
+	resp, err := s.oauth.PollDeviceToken(ctx, strconv.Itoa(int(req.GetAppId())), req.GetDeviceCode(), clientInfo(ctx))
+	if err != nil {
+		switch {
+		case errors.Is(err, oauth.ErrAuthorizationPending):
+			return nil, status.Error(codes.FailedPrecondition, "authorization_pending")
+		case errors.Is(err, oauth.ErrSlowDown):
+			return nil, status.Error(codes.ResourceExhausted, "slow_down")
+		case errors.Is(err, oauth.ErrExpiredToken):
+			return nil, status.Error(codes.DeadlineExceeded, "expired_token")
+		case errors.Is(err, oauth.ErrAccessDenied):
+			return nil, status.Error(codes.PermissionDenied, "access_denied")
+		case errors.Is(err, oauth.ErrInvalidClient):
+			return nil, status.Error(codes.InvalidArgument, "app not found")
+		case errors.Is(err, oauth.ErrInvalidGrant):
+			return nil, status.Error(codes.InvalidArgument, "invalid device code")
+		}
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+	return &ssov1.PollDeviceTokenResponse{
+		Token:        resp.AccessToken,
+		RefreshToken: resp.RefreshToken,
+		ExpiresIn:    resp.ExpiresIn,
+	}, nil
+}
+
+func (s *serverAPI) ExchangeToken(ctx context.Context, req *ssov1.ExchangeTokenRequest) (*ssov1.ExchangeTokenResponse, error) {
+	if err := validateExchangeToken(req); err != nil {
+		return nil, err
+	}
+	token, err := s.auth.ExchangeToken(ctx, req.GetSubjectToken(), int(req.GetActorAppId()), req.GetActorAppSecret(),
+		int(req.GetTargetAppId()), req.GetScopes())
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrInvalidAppSecret):
+			return nil, status.Error(codes.Unauthenticated, "invalid app credentials")
+		case errors.Is(err, auth.ErrInvalidAppID):
+			return nil, status.Error(codes.InvalidArgument, "app not found")
+		case errors.Is(err, auth.ErrInvalidToken):
+			return nil, status.Error(codes.Unauthenticated, "invalid subject token")
+		case errors.Is(err, auth.ErrExchangeNotAllowed):
+			return nil, status.Error(codes.PermissionDenied, "token exchange is not allowed for target app")
+		case errors.Is(err, auth.ErrInvalidScope):
+			return nil, status.Error(codes.PermissionDenied, "scope is not allowed for target app")
+		case errors.Is(err, auth.ErrNotAppMember):
+			return nil, status.Error(codes.PermissionDenied, "user is not a member of target app")
+		}
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+	return &ssov1.ExchangeTokenResponse{
+		Token:     token.Token,
+		ExpiresIn: int64(token.ExpiresIn.Seconds()),
+		Scopes:    token.Scopes,
+	}, nil
+}
+
+// passwordPolicyError reports all violations at once as BadRequest details.
+func passwordPolicyError(policyErr *password.PolicyError) error {
+	st := status.New(codes.InvalidArgument, "password does not satisfy policy")
+
+	badRequest := &errdetails.BadRequest{}
+	for _, v := range policyErr.Violations {
+		badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       v.Field,
+			Description: v.Description,
+		})
+	}
+	details := []protoadapt.MessageV1{badRequest}
+
+	// The estimator's feedback lets UIs tell the user how to do better.
+	if strength := policyErr.Strength; strength != nil {
+		details = append(details, &errdetails.ErrorInfo{
+			Reason: "PASSWORD_TOO_WEAK",
+			Domain: "sso",
+			Metadata: map[string]string{
+				"score":       strconv.Itoa(strength.Score),
+				"warning":     strength.Warning,
+				"suggestions": strings.Join(strength.Suggestions, "\n"),
+			},
+		})
+	}
+
+	withDetails, err := st.WithDetails(details...)
+	if err != nil {
+		return st.Err()
+	}
+	return withDetails.Err()
+}
+
 func validateSighIn(req *ssov1.SignInRequest) error {
 	if req.GetEmail() == "" {
 		return status.Error(codes.InvalidArgument, "email is required")
@@ -109,3 +284,39 @@ func validateIsAdmin(req *ssov1.IsAdminRequest) error {
 
 	return nil
 }
+
+func validateClientCredentials(req *ssov1.ClientCredentialsRequest) error {
+	if req.GetAppId() == emptyValue {
+		return status.Error(codes.InvalidArgument, "app_id is required")
+	}
+	if req.GetAppSecret() == "" {
+		return status.Error(codes.InvalidArgument, "app_secret is required")
+	}
+	return nil
+}
+
+func validatePollDeviceToken(req *ssov1.PollDeviceTokenRequest) error {
+	if req.GetAppId() == emptyValue {
+		return status.Error(codes.InvalidArgument, "app_id is required")
+	}
+	if req.GetDeviceCode() == "" {
+		return status.Error(codes.InvalidArgument, "device_code is required")
+	}
+	return nil
+}
+
+func validateExchangeToken(req *ssov1.ExchangeTokenRequest) error {
+	if req.GetSubjectToken() == "" {
+		return status.Error(codes.InvalidArgument, "subject_token is required")
+	}
+	if req.GetActorAppId() == emptyValue {
+		return status.Error(codes.InvalidArgument, "actor_app_id is required")
+	}
+	if req.GetActorAppSecret() == "" {
+		return status.Error(codes.InvalidArgument, "actor_app_secret is required")
+	}
+	if req.GetTargetAppId() == emptyValue {
+		return status.Error(codes.InvalidArgument, "target_app_id is required")
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	ssov1 "github.com/DavidG9999/api/gen/go/sso"
+	"github.com/DavidG9999/my_grpc_app/internal/domain/models"
+	"github.com/DavidG9999/my_grpc_app/internal/services/auth"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func (s *serverAPI) ValidateToken(ctx context.Context, req *ssov1.ValidateTokenRequest) (*ssov1.ValidateTokenResponse, error) {
+	if req.GetToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "token is required")
+	}
+	claims, err := s.auth.ValidateToken(ctx, req.GetToken())
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			return nil, status.Error(codes.Unauthenticated, "invalid token")
+		}
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+	return &ssov1.ValidateTokenResponse{
+		UserId:    claims.UserID,
+		AppId:     int32(claims.AppID),
+		SessionId: claims.SessionID,
+		ExpiresAt: claims.ExpiresAt.Unix(),
+	}, nil
+}
+
+func (s *serverAPI) ListSessions(ctx context.Context, req *ssov1.ListSessionsRequest) (*ssov1.ListSessionsResponse, error) {
+	caller, err := s.caller(ctx)
+	if err != nil {
+		return nil, err
+	}
+	userID := req.GetUserId()
+	if userID == emptyValue {
+		userID = caller.UserID
+	}
+
+	sessions, err := s.auth.ListSessions(ctx, caller.UserID, userID)
+	if err != nil {
+		if errors.Is(err, auth.ErrPermissionDenied) {
+			return nil, status.Error(codes.PermissionDenied, "not allowed to list sessions of user")
+		}
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	resp := &ssov1.ListSessionsResponse{Sessions: make([]*ssov1.Session, 0, len(sessions))}
+	for _, session := range sessions {
+		var impersonatorID int64
+		if session.ImpersonatorID != nil {
+			impersonatorID = *session.ImpersonatorID
+		}
+		resp.Sessions = append(resp.Sessions, &ssov1.Session{
+			Id:             session.ID,
+			AppId:          int32(session.AppID),
+			Device:         session.Device,
+			Ip:             session.IP,
+			UserAgent:      session.UserAgent,
+			CreatedAt:      session.CreatedAt.Unix(),
+			LastSeenAt:     session.LastSeenAt.Unix(),
+			Current:        session.ID == caller.SessionID,
+			ImpersonatorId: impersonatorID,
+		})
+	}
+	return resp, nil
+}
+
+func (s *serverAPI) RevokeSession(ctx context.Context, req *ssov1.RevokeSessionRequest) (*ssov1.RevokeSessionResponse, error) {
+	if req.GetSessionId() == emptyValue {
+		return nil, status.Error(codes.InvalidArgument, "session_id is required")
+	}
+	caller, err := s.caller(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.auth.RevokeSession(ctx, caller.UserID, req.GetSessionId()); err != nil {
+		if errors.Is(err, auth.ErrSessionNotFound) {
+			return nil, status.Error(codes.NotFound, "session not found")
+		}
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+	return &ssov1.RevokeSessionResponse{}, nil
+}
+
+func (s *serverAPI) RevokeAllSessions(ctx context.Context, req *ssov1.RevokeAllSessionsRequest) (*ssov1.RevokeAllSessionsResponse, error) {
+	caller, err := s.caller(ctx)
+	if err != nil {
+		return nil, err
+	}
+	userID := req.GetUserId()
+	if userID == emptyValue {
+		userID = caller.UserID
+	}
+	var keep int64
+	if req.GetKeepCurrent() && userID == caller.UserID {
+		keep = caller.SessionID
+	}
+
+	revoked, err := s.auth.RevokeAllSessions(ctx, caller.UserID, userID, keep)
+	if err != nil {
+		if errors.Is(err, auth.ErrPermissionDenied) {
+			return nil, status.Error(codes.PermissionDenied, "not allowed to revoke sessions of user")
+		}
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+	return &ssov1.RevokeAllSessionsResponse{
+		Revoked: revoked,
+	}, nil
+}
+
+// clientInfo describes the device that sent the request for its session.
+func clientInfo(ctx context.Context) models.ClientInfo {
+	var client models.ClientInfo
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get("x-device-name"); len(values) > 0 {
+		client.Device = values[0]
+	}
+	if values := md.Get("user-agent"); len(values) > 0 {
+		client.UserAgent = values[0]
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		client.IP = p.Addr.String()
+		if host, _, err := net.SplitHostPort(client.IP); err == nil {
+			client.IP = host
+		}
+	}
+	return client
+}
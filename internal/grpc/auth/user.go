@@ -0,0 +1,312 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	ssov1 "github.com/DavidG9999/api/gen/go/sso"
+	"github.com/DavidG9999/my_grpc_app/internal/domain/models"
+	"github.com/DavidG9999/my_grpc_app/internal/lib/password"
+	"github.com/DavidG9999/my_grpc_app/internal/services/auth"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *serverAPI) GetUser(ctx context.Context, req *ssov1.GetUserRequest) (*ssov1.GetUserResponse, error) {
+	caller, err := s.caller(ctx)
+	if err != nil {
+		return nil, err
+	}
+	userID := req.GetUserId()
+	if userID == emptyValue && req.GetEmail() == "" {
+		userID = caller.UserID
+	}
+
+	user, err := s.auth.GetUser(ctx, caller.UserID, userID, req.GetEmail())
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrPermissionDenied):
+			return nil, status.Error(codes.PermissionDenied, "not allowed to get user")
+		case errors.Is(err, auth.ErrUserNotFound):
+			return nil, status.Error(codes.NotFound, "user not found")
+		}
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+	return &ssov1.GetUserResponse{
+		User: userToProto(user),
+	}, nil
+}
+
+func (s *serverAPI) UpdateUser(ctx context.Context, req *ssov1.UpdateUserRequest) (*ssov1.UpdateUserResponse, error) {
+	if req.GetName() == "" && req.GetEmail() == "" {
+		return nil, status.Error(codes.InvalidArgument, "name or email is required")
+	}
+	caller, err := s.caller(ctx)
+	if err != nil {
+		return nil, err
+	}
+	userID := req.GetUserId()
+	if userID == emptyValue {
+		userID = caller.UserID
+	}
+
+	user, err := s.auth.UpdateUser(ctx, caller.UserID, userID, req.GetName(), req.GetEmail())
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrPermissionDenied):
+			return nil, status.Error(codes.PermissionDenied, "not allowed to update user")
+		case errors.Is(err, auth.ErrUserNotFound):
+			return nil, status.Error(codes.NotFound, "user not found")
+		case errors.Is(err, auth.ErrUserExist):
+			return nil, status.Error(codes.AlreadyExists, "user already exists")
+		case errors.Is(err, auth.ErrInvalidEmail):
+			return nil, status.Error(codes.InvalidArgument, "invalid email")
+		}
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+	return &ssov1.UpdateUserResponse{
+		User: userToProto(user),
+	}, nil
+}
+
+func (s *serverAPI) ChangePassword(ctx context.Context, req *ssov1.ChangePasswordRequest) (*ssov1.ChangePasswordResponse, error) {
+	if req.GetNewPassword() == "" {
+		return nil, status.Error(codes.InvalidArgument, "new_password is required")
+	}
+	caller, err := s.caller(ctx)
+	if err != nil {
+		return nil, err
+	}
+	userID := req.GetUserId()
+	if userID == emptyValue {
+		userID = caller.UserID
+	}
+	if userID == caller.UserID && req.GetCurrentPassword() == "" {
+		return nil, status.Error(codes.InvalidArgument, "current_password is required")
+	}
+
+	err = s.auth.ChangePassword(ctx, caller, userID, req.GetCurrentPassword(), req.GetNewPassword())
+	if err != nil {
+		var policyErr *password.PolicyError
+		switch {
+		case errors.As(err, &policyErr):
+			return nil, passwordPolicyError(policyErr)
+		case errors.Is(err, auth.ErrInvalidCredentials):
+			return nil, status.Error(codes.InvalidArgument, "invalid current password")
+		case errors.Is(err, auth.ErrPermissionDenied):
+			return nil, status.Error(codes.PermissionDenied, "not allowed to change password")
+		case errors.Is(err, auth.ErrUserNotFound):
+			return nil, status.Error(codes.NotFound, "user not found")
+		}
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+	return &ssov1.ChangePasswordResponse{}, nil
+}
+
+func (s *serverAPI) SendEmailVerification(ctx context.Context, req *ssov1.SendEmailVerificationRequest) (*ssov1.SendEmailVerificationResponse, error) {
+	caller, err := s.caller(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.auth.SendEmailVerification(ctx, caller); err != nil {
+		switch {
+		case errors.Is(err, auth.ErrEmailVerified):
+			return nil, status.Error(codes.FailedPrecondition, "email is already verified")
+		case errors.Is(err, auth.ErrUserNotFound):
+			return nil, status.Error(codes.NotFound, "user not found")
+		}
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+	return &ssov1.SendEmailVerificationResponse{}, nil
+}
+
+func (s *serverAPI) VerifyEmail(ctx context.Context, req *ssov1.VerifyEmailRequest) (*ssov1.VerifyEmailResponse, error) {
+	if req.GetToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "token is required")
+	}
+
+	if err := s.auth.VerifyEmail(ctx, req.GetToken()); err != nil {
+		if errors.Is(err, auth.ErrInvalidVerification) {
+			return nil, status.Error(codes.InvalidArgument, "invalid verification token")
+		}
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+	return &ssov1.VerifyEmailResponse{}, nil
+}
+
+func (s *serverAPI) ListUsers(ctx context.Context, req *ssov1.ListUsersRequest) (*ssov1.ListUsersResponse, error) {
+	if err := validateListUsers(req); err != nil {
+		return nil, err
+	}
+	caller, err := s.caller(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	params := auth.ListUsersParams{
+		Filter: models.UserFilter{
+			OrgID:       req.GetOrgId(),
+			EmailPrefix: req.GetEmailPrefix(),
+			IsAdmin:     req.IsAdmin,
+			Status:      models.UserStatus(req.GetStatus()),
+		},
+		SortBy:     models.UserSortField(req.GetSortBy()),
+		Descending: req.GetDescending(),
+		PageSize:   int(req.GetPageSize()),
+		PageToken:  req.GetPageToken(),
+	}
+	if req.GetCreatedAfter() != emptyValue {
+		params.Filter.CreatedAfter = time.Unix(req.GetCreatedAfter(), 0)
+	}
+	if req.GetCreatedBefore() != emptyValue {
+		params.Filter.CreatedBefore = time.Unix(req.GetCreatedBefore(), 0)
+	}
+
+	page, err := s.auth.ListUsers(ctx, caller.UserID, params)
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrPermissionDenied):
+			return nil, status.Error(codes.PermissionDenied, "not allowed to list users")
+		case errors.Is(err, auth.ErrInvalidPageToken):
+			return nil, status.Error(codes.InvalidArgument, "invalid page_token")
+		}
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	users := make([]*ssov1.User, 0, len(page.Users))
+	for _, user := range page.Users {
+		users = append(users, userToProto(user))
+	}
+	return &ssov1.ListUsersResponse{
+		Users:         users,
+		NextPageToken: page.NextPageToken,
+	}, nil
+}
+
+func validateListUsers(req *ssov1.ListUsersRequest) error {
+	switch models.UserSortField(req.GetSortBy()) {
+	case "", models.UserSortCreatedAt, models.UserSortEmail:
+	default:
+		return status.Error(codes.InvalidArgument, "sort_by must be created_at or email")
+	}
+	if req.GetPageSize() < 0 {
+		return status.Error(codes.InvalidArgument, "page_size must not be negative")
+	}
+	if req.GetCreatedAfter() != emptyValue && req.GetCreatedBefore() != emptyValue &&
+		req.GetCreatedAfter() >= req.GetCreatedBefore() {
+		return status.Error(codes.InvalidArgument, "created_after must be before created_before")
+	}
+	return nil
+}
+
+func (s *serverAPI) SearchUsers(ctx context.Context, req *ssov1.SearchUsersRequest) (*ssov1.SearchUsersResponse, error) {
+	if strings.TrimSpace(req.GetQuery()) == "" {
+		return nil, status.Error(codes.InvalidArgument, "query is required")
+	}
+	if req.GetLimit() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "limit must not be negative")
+	}
+	caller, err := s.caller(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	matches, err := s.auth.SearchUsers(ctx, caller.UserID, req.GetQuery(), int(req.GetLimit()))
+	if err != nil {
+		if errors.Is(err, auth.ErrPermissionDenied) {
+			return nil, status.Error(codes.PermissionDenied, "not allowed to search users")
+		}
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	results := make([]*ssov1.UserSearchResult, 0, len(matches))
+	for _, match := range matches {
+		results = append(results, &ssov1.UserSearchResult{
+			User:           userToProto(match.User),
+			NameHighlight:  match.NameHighlight,
+			EmailHighlight: match.EmailHighlight,
+			Score:          match.Score,
+		})
+	}
+	return &ssov1.SearchUsersResponse{
+		Results: results,
+	}, nil
+}
+
+func (s *serverAPI) SetUserStatus(ctx context.Context, req *ssov1.SetUserStatusRequest) (*ssov1.SetUserStatusResponse, error) {
+	if err := validateSetUserStatus(req); err != nil {
+		return nil, err
+	}
+	caller, err := s.caller(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var suspendedUntil *time.Time
+	if req.GetSuspendedUntil() != emptyValue {
+		until := time.Unix(req.GetSuspendedUntil(), 0).UTC()
+		suspendedUntil = &until
+	}
+
+	err = s.auth.SetUserStatus(ctx, caller, req.GetUserId(), models.UserStatus(req.GetStatus()), req.GetReason(),
+		suspendedUntil, clientInfo(ctx))
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrPermissionDenied):
+			return nil, status.Error(codes.PermissionDenied, "not allowed to set user status")
+		case errors.Is(err, auth.ErrUserNotFound):
+			return nil, status.Error(codes.NotFound, "user not found")
+		case errors.Is(err, auth.ErrUserIsAdmin):
+			return nil, status.Error(codes.FailedPrecondition, "admins must be demoted first")
+		}
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+	return &ssov1.SetUserStatusResponse{}, nil
+}
+
+func validateSetUserStatus(req *ssov1.SetUserStatusRequest) error {
+	if req.GetUserId() == emptyValue {
+		return status.Error(codes.InvalidArgument, "user_id is required")
+	}
+	switch models.UserStatus(req.GetStatus()) {
+	case models.UserStatusActive:
+		return nil
+	case models.UserStatusSuspended:
+		if !time.Unix(req.GetSuspendedUntil(), 0).After(time.Now()) {
+			return status.Error(codes.InvalidArgument, "suspended_until must be in the future")
+		}
+	case models.UserStatusDisabled, models.UserStatusDeleted:
+	default:
+		return status.Error(codes.InvalidArgument, "unknown status")
+	}
+	if strings.TrimSpace(req.GetReason()) == "" {
+		return status.Error(codes.InvalidArgument, "reason is required")
+	}
+	return nil
+}
+
+func userToProto(user models.User) *ssov1.User {
+	var createdAt int64
+	if !user.CreatedAt.IsZero() {
+		createdAt = user.CreatedAt.Unix()
+	}
+	out := &ssov1.User{
+		Id:            user.ID,
+		OrgId:         user.OrgID,
+		Name:          user.Name,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerified,
+		IsAdmin:       user.IsAdmin,
+		IsOrgAdmin:    user.IsOrgAdmin,
+		Status:        string(user.Status),
+		StatusReason:  user.StatusReason,
+		CreatedAt:     createdAt,
+	}
+	if user.SuspendedUntil != nil {
+		out.SuspendedUntil = user.SuspendedUntil.Unix()
+	}
+	return out
+}
@@ -0,0 +1,442 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	maxMessageSize = 4 << 20
+
+	frameHeaderLen = 5
+	frameData      = 0x00
+	frameTrailer   = 0x80
+
+	contentTypeGRPCWeb      = "application/grpc-web"
+	contentTypeGRPCWebProto = "application/grpc-web+proto"
+	contentTypeGRPCWebText  = "application/grpc-web-text"
+	contentTypeConnectProto = "application/proto"
+	contentTypeConnectJSON  = "application/json"
+)
+
+var (
+	ErrUnknownMethod = errors.New("unknown method")
+	ErrNotProto      = errors.New("message is not a protobuf message")
+)
+
+type protocol int
+
+const (
+	protocolGRPCWeb protocol = iota
+	protocolGRPCWebText
+	protocolConnectProto
+	protocolConnectJSON
+)
+
+type method struct {
+	srv  any
+	desc grpc.MethodDesc
+}
+
+// Server serves unary methods of registered gRPC services over the gRPC-Web
+// and Connect protocols. It implements grpc.ServiceRegistrar, so the same
+// Register functions used for *grpc.Server can be used with it.
+type Server struct {
+	log         *slog.Logger
+	methods     map[string]method
+	interceptor grpc.UnaryServerInterceptor
+}
+
+// NewServer creates a server running every call through the interceptor,
+// like a *grpc.Server created with grpc.UnaryInterceptor. It may be nil.
+func NewServer(log *slog.Logger, interceptor grpc.UnaryServerInterceptor) *Server {
+	return &Server{
+		log:         log,
+		methods:     make(map[string]method),
+		interceptor: interceptor,
+	}
+}
+
+func (s *Server) RegisterService(desc *grpc.ServiceDesc, impl any) {
+	for _, m := range desc.Methods {
+		s.methods["/"+desc.ServiceName+"/"+m.MethodName] = method{srv: impl, desc: m}
+	}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	const op = "web.ServeHTTP"
+
+	log := s.log.With(slog.String("op", op), slog.String("path", r.URL.Path))
+
+	kind, ok := detectProtocol(r.Header.Get("Content-Type"))
+	if !ok {
+		http.Error(w, "unsupported content type", http.StatusUnsupportedMediaType)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	m, ok := s.methods[r.URL.Path]
+	if !ok {
+		writeError(w, kind, r.Header.Get("Content-Type"), status.Errorf(codes.Unimplemented, "%s: %s", ErrUnknownMethod, r.URL.Path))
+		return
+	}
+
+	if enc := r.Header.Get("Content-Encoding"); enc != "" && enc != "identity" {
+		writeError(w, kind, r.Header.Get("Content-Type"), status.Errorf(codes.Unimplemented, "unsupported encoding %q", enc))
+		return
+	}
+
+	msg, err := readMessage(r, kind)
+	if err != nil {
+		writeError(w, kind, r.Header.Get("Content-Type"), err)
+		return
+	}
+
+	ctx, cancel := requestContext(r, kind)
+	defer cancel()
+
+	dec := func(v any) error {
+		pm, ok := v.(proto.Message)
+		if !ok {
+			return status.Error(codes.Internal, ErrNotProto.Error())
+		}
+		if kind == protocolConnectJSON {
+			if err := protojson.Unmarshal(msg, pm); err != nil {
+				return status.Errorf(codes.InvalidArgument, "failed to unmarshal request: %v", err)
+			}
+			return nil
+		}
+		if err := proto.Unmarshal(msg, pm); err != nil {
+			return status.Errorf(codes.InvalidArgument, "failed to unmarshal request: %v", err)
+		}
+		return nil
+	}
+
+	resp, err := m.desc.Handler(m.srv, ctx, dec, s.interceptor)
+	if err != nil {
+		writeError(w, kind, r.Header.Get("Content-Type"), err)
+		return
+	}
+
+	if err := writeMessage(w, kind, r.Header.Get("Content-Type"), resp); err != nil {
+		log.Error("failed to write response", slog.String("error", err.Error()))
+	}
+}
+
+func detectProtocol(contentType string) (protocol, bool) {
+	ct, _, _ := strings.Cut(contentType, ";")
+	switch strings.TrimSpace(strings.ToLower(ct)) {
+	case contentTypeGRPCWeb, contentTypeGRPCWebProto:
+		return protocolGRPCWeb, true
+	case contentTypeGRPCWebText, contentTypeGRPCWebText + "+proto":
+		return protocolGRPCWebText, true
+	case contentTypeConnectProto:
+		return protocolConnectProto, true
+	case contentTypeConnectJSON:
+		return protocolConnectJSON, true
+	}
+	return 0, false
+}
+
+func requestContext(r *http.Request, kind protocol) (context.Context, context.CancelFunc) {
+	md := metadata.MD{}
+	for key, values := range r.Header {
+		key = strings.ToLower(key)
+		if strings.HasSuffix(key, "-bin") {
+			for _, v := range values {
+				if decoded, err := base64.StdEncoding.DecodeString(v); err == nil {
+					v = string(decoded)
+				}
+				md.Append(key, v)
+			}
+			continue
+		}
+		md.Append(key, values...)
+	}
+
+	ctx := metadata.NewIncomingContext(r.Context(), md)
+
+	if addr, err := netip.ParseAddrPort(r.RemoteAddr); err == nil {
+		ctx = peer.NewContext(ctx, &peer.Peer{Addr: net.TCPAddrFromAddrPort(addr)})
+	}
+
+	if timeout, ok := requestTimeout(r, kind); ok {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+func requestTimeout(r *http.Request, kind protocol) (time.Duration, bool) {
+	if kind == protocolConnectProto || kind == protocolConnectJSON {
+		ms, err := strconv.ParseInt(r.Header.Get("Connect-Timeout-Ms"), 10, 64)
+		if err != nil || ms <= 0 {
+			return 0, false
+		}
+		return time.Duration(ms) * time.Millisecond, true
+	}
+
+	v := r.Header.Get("Grpc-Timeout")
+	if len(v) < 2 {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(v[:len(v)-1], 10, 64)
+	if err != nil || n <= 0 {
+		return 0, false
+	}
+	units := map[byte]time.Duration{
+		'H': time.Hour,
+		'M': time.Minute,
+		'S': time.Second,
+		'm': time.Millisecond,
+		'u': time.Microsecond,
+		'n': time.Nanosecond,
+	}
+	unit, ok := units[v[len(v)-1]]
+	if !ok {
+		return 0, false
+	}
+	return time.Duration(n) * unit, true
+}
+
+func readMessage(r *http.Request, kind protocol) ([]byte, error) {
+	body, err := io.ReadAll(http.MaxBytesReader(nil, r.Body, maxMessageSize))
+	if err != nil {
+		return nil, status.Errorf(codes.ResourceExhausted, "failed to read request: %v", err)
+	}
+
+	switch kind {
+	case protocolConnectProto, protocolConnectJSON:
+		return body, nil
+	case protocolGRPCWebText:
+		decoded := make([]byte, base64.StdEncoding.DecodedLen(len(body)))
+		n, err := base64.StdEncoding.Decode(decoded, body)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "failed to decode request: %v", err)
+		}
+		body = decoded[:n]
+	}
+
+	if len(body) < frameHeaderLen {
+		return nil, status.Error(codes.InvalidArgument, "malformed request frame")
+	}
+	if body[0] != frameData {
+		return nil, status.Error(codes.Unimplemented, "compressed request frames are not supported")
+	}
+	size := binary.BigEndian.Uint32(body[1:frameHeaderLen])
+	if uint32(len(body)-frameHeaderLen) < size {
+		return nil, status.Error(codes.InvalidArgument, "truncated request frame")
+	}
+	return body[frameHeaderLen : frameHeaderLen+int(size)], nil
+}
+
+func writeMessage(w http.ResponseWriter, kind protocol, contentType string, resp any) error {
+	pm, ok := resp.(proto.Message)
+	if !ok {
+		writeError(w, kind, contentType, status.Error(codes.Internal, ErrNotProto.Error()))
+		return ErrNotProto
+	}
+
+	var (
+		msg []byte
+		err error
+	)
+	if kind == protocolConnectJSON {
+		msg, err = protojson.Marshal(pm)
+	} else {
+		msg, err = proto.Marshal(pm)
+	}
+	if err != nil {
+		writeError(w, kind, contentType, status.Error(codes.Internal, "failed to marshal response"))
+		return err
+	}
+
+	switch kind {
+	case protocolConnectProto, protocolConnectJSON:
+		w.Header().Set("Content-Type", contentType)
+		w.WriteHeader(http.StatusOK)
+		_, err = w.Write(msg)
+		return err
+	}
+
+	var buf bytes.Buffer
+	writeFrame(&buf, frameData, msg)
+	writeFrame(&buf, frameTrailer, trailer(status.New(codes.OK, "")))
+	return writeGRPCWeb(w, kind, contentType, buf.Bytes())
+}
+
+func writeError(w http.ResponseWriter, kind protocol, contentType string, err error) {
+	st := status.Convert(err)
+
+	switch kind {
+	case protocolConnectProto, protocolConnectJSON:
+		writeConnectError(w, st)
+		return
+	}
+
+	var buf bytes.Buffer
+	writeFrame(&buf, frameTrailer, trailer(st))
+	_ = writeGRPCWeb(w, kind, contentType, buf.Bytes())
+}
+
+func writeGRPCWeb(w http.ResponseWriter, kind protocol, contentType string, body []byte) error {
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+
+	if kind == protocolGRPCWebText {
+		body = []byte(base64.StdEncoding.EncodeToString(body))
+	}
+	_, err := w.Write(body)
+	return err
+}
+
+func writeFrame(buf *bytes.Buffer, flag byte, payload []byte) {
+	var header [frameHeaderLen]byte
+	header[0] = flag
+	binary.BigEndian.PutUint32(header[1:], uint32(len(payload)))
+	buf.Write(header[:])
+	buf.Write(payload)
+}
+
+func trailer(st *status.Status) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "grpc-status: %d\r\n", st.Code())
+	if msg := st.Message(); msg != "" {
+		fmt.Fprintf(&b, "grpc-message: %s\r\n", encodeGRPCMessage(msg))
+	}
+	if details := st.Proto().GetDetails(); len(details) > 0 {
+		if raw, err := proto.Marshal(st.Proto()); err == nil {
+			fmt.Fprintf(&b, "grpc-status-details-bin: %s\r\n", base64.RawStdEncoding.EncodeToString(raw))
+		}
+	}
+	return []byte(b.String())
+}
+
+// encodeGRPCMessage percent-encodes a status message as required by the gRPC
+// wire format.
+func encodeGRPCMessage(msg string) string {
+	var b strings.Builder
+	for i := 0; i < len(msg); i++ {
+		c := msg[i]
+		if c < ' ' || c > '~' || c == '%' {
+			fmt.Fprintf(&b, "%%%02X", c)
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+type connectErrorDetail struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type connectError struct {
+	Code    string               `json:"code"`
+	Message string               `json:"message,omitempty"`
+	Details []connectErrorDetail `json:"details,omitempty"`
+}
+
+func writeConnectError(w http.ResponseWriter, st *status.Status) {
+	body := connectError{
+		Code:    connectCode(st.Code()),
+		Message: st.Message(),
+	}
+	for _, d := range st.Proto().GetDetails() {
+		body.Details = append(body.Details, connectErrorDetail{
+			Type:  strings.TrimPrefix(d.GetTypeUrl(), "type.googleapis.com/"),
+			Value: base64.RawStdEncoding.EncodeToString(d.GetValue()),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(connectHTTPStatus(st.Code()))
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func connectCode(code codes.Code) string {
+	switch code {
+	case codes.Canceled:
+		return "canceled"
+	case codes.InvalidArgument:
+		return "invalid_argument"
+	case codes.DeadlineExceeded:
+		return "deadline_exceeded"
+	case codes.NotFound:
+		return "not_found"
+	case codes.AlreadyExists:
+		return "already_exists"
+	case codes.PermissionDenied:
+		return "permission_denied"
+	case codes.ResourceExhausted:
+		return "resource_exhausted"
+	case codes.FailedPrecondition:
+		return "failed_precondition"
+	case codes.Aborted:
+		return "aborted"
+	case codes.OutOfRange:
+		return "out_of_range"
+	case codes.Unimplemented:
+		return "unimplemented"
+	case codes.Internal:
+		return "internal"
+	case codes.Unavailable:
+		return "unavailable"
+	case codes.DataLoss:
+		return "data_loss"
+	case codes.Unauthenticated:
+		return "unauthenticated"
+	}
+	return "unknown"
+}
+
+func connectHTTPStatus(code codes.Code) int {
+	switch code {
+	case codes.Canceled:
+		return 499
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	}
+	return http.StatusInternalServerError
+}
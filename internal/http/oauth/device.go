@@ -0,0 +1,245 @@
+package oauth
+
+import (
+	"errors"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/DavidG9999/my_grpc_app/internal/domain/models"
+	"github.com/DavidG9999/my_grpc_app/internal/services/auth"
+	"github.com/DavidG9999/my_grpc_app/internal/services/oauth"
+)
+
+var devicePage = template.Must(template.New("device").Parse(`<!DOCTYPE html>
+<html>
+<head>
+	<meta charset="utf-8">
+	<title>Connect a device</title>
+</head>
+<body>
+	{{if .Done}}
+	<h1>{{.Done}}</h1>
+	<p>You can close this window and return to your device.</p>
+	{{else if .AppName}}
+	<h1>Connect {{.AppName}}</h1>
+	<p>{{.AppName}} asks for access to your account{{if .Scopes}} with the scopes:{{else}}.{{end}}</p>
+	{{if .Scopes}}<ul>{{range .Scopes}}<li>{{.}}</li>{{end}}</ul>{{end}}
+	<p>Only allow it if you started connecting the device yourself and it shows the code {{.UserCode}}.</p>
+	{{if .Error}}<p role="alert">{{.Error}}</p>{{end}}
+	<form method="post" action="/device">
+		<input type="hidden" name="user_code" value="{{.UserCode}}">
+		<label>Email <input type="email" name="email" value="{{.Email}}" required autofocus></label>
+		<label>Password <input type="password" name="password" required></label>
+		<button type="submit" name="action" value="approve">Allow</button>
+		<button type="submit" name="action" value="deny">Deny</button>
+	</form>
+	{{else}}
+	<h1>Connect a device</h1>
+	{{if .Error}}<p role="alert">{{.Error}}</p>{{end}}
+	<form method="post" action="/device">
+		<label>Code <input type="text" name="user_code" value="{{.UserCode}}" required autocomplete="off" autofocus></label>
+		<button type="submit">Continue</button>
+	</form>
+	{{end}}
+</body>
+</html>
+`))
+
+// devicePageData is shown as the code form, the confirmation of the app and
+// scope once AppName is set, or the outcome once Done is.
+type devicePageData struct {
+	UserCode string
+	AppName  string
+	Scopes   []string
+	Email    string
+	Error    string
+	Done     string
+}
+
+type deviceAuthorizationResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int64  `json:"expires_in"`
+	Interval                int64  `json:"interval"`
+}
+
+func (h *handler) deviceAuthorization(w http.ResponseWriter, r *http.Request) {
+	const op = "http.oauth.deviceAuthorization"
+
+	log := h.log.With(slog.String("op", op))
+
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: errInvalidRequest, ErrorDescription: "malformed form"})
+		return
+	}
+
+	clientID, _, ok := r.BasicAuth()
+	if !ok {
+		clientID = r.PostForm.Get("client_id")
+	}
+
+	resp, err := h.oauth.StartDeviceAuthorization(r.Context(), clientID, r.PostForm.Get("scope"))
+	if err != nil {
+		if errors.Is(err, oauth.ErrInvalidClient) {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: errInvalidClient})
+			return
+		}
+		log.Error("failed to start device authorization", slog.String("error", err.Error()))
+
+		writeJSON(w, http.StatusInternalServerError, errorResponse{Error: errServerError})
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, deviceAuthorizationResponse{
+		DeviceCode:              resp.DeviceCode,
+		UserCode:                resp.UserCode,
+		VerificationURI:         resp.VerificationURI,
+		VerificationURIComplete: resp.VerificationURIComplete,
+		ExpiresIn:               int64(resp.ExpiresIn.Seconds()),
+		Interval:                int64(resp.Interval.Seconds()),
+	})
+}
+
+// devicePage asks for the user code, or confirms the app and scope right away
+// if the code came in the verification_uri_complete.
+func (h *handler) devicePage(w http.ResponseWriter, r *http.Request) {
+	data := devicePageData{UserCode: r.URL.Query().Get("user_code")}
+	if data.UserCode == "" {
+		h.renderDevice(w, http.StatusOK, data)
+		return
+	}
+	if _, err := h.deviceApp(r, &data); err != nil {
+		h.deviceError(w, data, err)
+		return
+	}
+	h.renderDevice(w, http.StatusOK, data)
+}
+
+// device shows the app and scope of the user code and, only once the user
+// has seen them and signed in, records their decision (RFC 8628 §5.4).
+func (h *handler) device(w http.ResponseWriter, r *http.Request) {
+	const op = "http.oauth.device"
+
+	log := h.log.With(slog.String("op", op))
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "malformed form", http.StatusBadRequest)
+		return
+	}
+
+	data := devicePageData{
+		UserCode: r.PostForm.Get("user_code"),
+		Email:    r.PostForm.Get("email"),
+	}
+
+	deviceApp, err := h.deviceApp(r, &data)
+	if err != nil {
+		h.deviceError(w, data, err)
+		return
+	}
+
+	action := r.PostForm.Get("action")
+	if action != "approve" && action != "deny" {
+		h.renderDevice(w, http.StatusOK, data)
+		return
+	}
+
+	user, err := h.auth.Authenticate(r.Context(), deviceApp.OrgID, data.Email, r.PostForm.Get("password"))
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidCredentials) {
+			data.Error = "Invalid email or password"
+			h.renderDevice(w, http.StatusUnauthorized, data)
+			return
+		}
+		if inactiveUser(err) {
+			data.Error = "This account is not active"
+			h.renderDevice(w, http.StatusForbidden, data)
+			return
+		}
+		log.Error("failed to authenticate user", slog.String("error", err.Error()))
+
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	approve := action == "approve"
+
+	app, err := h.oauth.ApproveDevice(r.Context(), data.UserCode, user.ID, approve)
+	if err != nil {
+		if errors.Is(err, oauth.ErrInvalidUserCode) {
+			h.deviceError(w, devicePageData{UserCode: data.UserCode}, err)
+			return
+		}
+		if errors.Is(err, oauth.ErrAccessDenied) {
+			data.Error = "Your account has no access to this app"
+			h.renderDevice(w, http.StatusForbidden, data)
+			return
+		}
+		log.Error("failed to handle device authorization", slog.String("error", err.Error()))
+
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	if approve {
+		data.Done = "Device connected to " + app.Name
+	} else {
+		data.Done = "Access denied for " + app.Name
+	}
+	h.renderDevice(w, http.StatusOK, data)
+}
+
+// deviceApp looks up the app and scope of the user code for the confirmation.
+func (h *handler) deviceApp(r *http.Request, data *devicePageData) (models.App, error) {
+	app, scope, err := h.oauth.DeviceApp(r.Context(), data.UserCode)
+	if err != nil {
+		return models.App{}, err
+	}
+	data.AppName = app.Name
+	data.Scopes = strings.Fields(scope)
+	return app, nil
+}
+
+// deviceError shows an invalid user code on the code form and fails on
+// anything else.
+func (h *handler) deviceError(w http.ResponseWriter, data devicePageData, err error) {
+	if errors.Is(err, oauth.ErrInvalidUserCode) {
+		data.Error = "The code is invalid or has expired"
+		h.renderDevice(w, http.StatusBadRequest, data)
+		return
+	}
+	h.log.Error("failed to get device authorization", slog.String("error", err.Error()))
+
+	http.Error(w, "internal error", http.StatusInternalServerError)
+}
+
+func (h *handler) renderDevice(w http.ResponseWriter, status int, data devicePageData) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.WriteHeader(status)
+
+	if err := devicePage.Execute(w, data); err != nil {
+		h.log.Error("failed to render device page", slog.String("error", err.Error()))
+	}
+}
+
+// deviceTokenError maps device flow polling errors to their RFC 8628 codes.
+func deviceTokenError(err error) (string, bool) {
+	switch {
+	case errors.Is(err, oauth.ErrAuthorizationPending):
+		return "authorization_pending", true
+	case errors.Is(err, oauth.ErrSlowDown):
+		return "slow_down", true
+	case errors.Is(err, oauth.ErrExpiredToken):
+		return "expired_token", true
+	case errors.Is(err, oauth.ErrAccessDenied):
+		return "access_denied", true
+	}
+	return "", false
+}
@@ -0,0 +1,454 @@
+package oauth
+
+import (
+	"encoding/json"
+	"errors"
+	"html/template"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/DavidG9999/my_grpc_app/internal/domain/models"
+	"github.com/DavidG9999/my_grpc_app/internal/lib/jwt"
+	"github.com/DavidG9999/my_grpc_app/internal/services/auth"
+	"github.com/DavidG9999/my_grpc_app/internal/services/oauth"
+)
+
+const (
+	errInvalidRequest          = "invalid_request"
+	errInvalidClient           = "invalid_client"
+	errInvalidGrant            = "invalid_grant"
+	errInvalidScope            = "invalid_scope"
+	errUnsupportedResponseType = "unsupported_response_type"
+	errUnsupportedGrantType    = "unsupported_grant_type"
+	errServerError             = "server_error"
+	errAccessDenied            = "access_denied"
+)
+
+var loginPage = template.Must(template.New("login").Parse(`<!DOCTYPE html>
+<html>
+<head>
+	<meta charset="utf-8">
+	<title>Sign in to {{.AppName}}</title>
+</head>
+<body>
+	<h1>Sign in to {{.AppName}}</h1>
+	{{if .Error}}<p role="alert">{{.Error}}</p>{{end}}
+	<form method="post" action="/authorize">
+		<input type="hidden" name="response_type" value="{{.Request.ResponseType}}">
+		<input type="hidden" name="client_id" value="{{.Request.ClientID}}">
+		<input type="hidden" name="redirect_uri" value="{{.Request.RedirectURI}}">
+		<input type="hidden" name="scope" value="{{.Request.Scope}}">
+		<input type="hidden" name="state" value="{{.Request.State}}">
+		<input type="hidden" name="code_challenge" value="{{.Request.CodeChallenge}}">
+		<input type="hidden" name="code_challenge_method" value="{{.Request.CodeChallengeMethod}}">
+		<input type="hidden" name="nonce" value="{{.Request.Nonce}}">
+		<label>Email <input type="email" name="email" value="{{.Email}}" required autofocus></label>
+		<label>Password <input type="password" name="password" required></label>
+		<button type="submit">Sign in</button>
+	</form>
+</body>
+</html>
+`))
+
+type loginPageData struct {
+	AppName string
+	Request oauth.AuthorizeRequest
+	Email   string
+	Error   string
+}
+
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+type userInfoResponse struct {
+	Sub   string `json:"sub"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+type discoveryDocument struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserInfoEndpoint                  string   `json:"userinfo_endpoint"`
+	DeviceAuthorizationEndpoint       string   `json:"device_authorization_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+}
+
+type jwksResponse struct {
+	Keys []jwt.JWK `json:"keys"`
+}
+
+type errorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+type handler struct {
+	log   *slog.Logger
+	oauth *oauth.OAuth
+	auth  *auth.Auth
+}
+
+func Register(mux *http.ServeMux, log *slog.Logger, oauth *oauth.OAuth, auth *auth.Auth) {
+	h := &handler{log: log, oauth: oauth, auth: auth}
+
+	mux.HandleFunc("GET /authorize", h.authorizePage)
+	mux.HandleFunc("POST /authorize", h.authorize)
+	mux.HandleFunc("POST /token", h.token)
+	mux.HandleFunc("POST /device_authorization", h.deviceAuthorization)
+	mux.HandleFunc("GET /device", h.devicePage)
+	mux.HandleFunc("POST /device", h.device)
+	mux.HandleFunc("GET /userinfo", h.userInfo)
+	mux.HandleFunc("POST /userinfo", h.userInfo)
+	mux.HandleFunc("GET /.well-known/openid-configuration", h.discovery)
+	mux.HandleFunc("GET /.well-known/jwks.json", h.jwks)
+}
+
+func (h *handler) authorizePage(w http.ResponseWriter, r *http.Request) {
+	req := authorizeRequest(r.URL.Query())
+
+	app, err := h.oauth.ValidateAuthorizeRequest(r.Context(), req)
+	if err != nil {
+		h.authorizeError(w, r, req, err)
+		return
+	}
+
+	h.renderLogin(w, http.StatusOK, loginPageData{AppName: app.Name, Request: req})
+}
+
+func (h *handler) authorize(w http.ResponseWriter, r *http.Request) {
+	const op = "http.oauth.authorize"
+
+	log := h.log.With(slog.String("op", op))
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "malformed form", http.StatusBadRequest)
+		return
+	}
+	req := authorizeRequest(r.PostForm)
+
+	app, err := h.oauth.ValidateAuthorizeRequest(r.Context(), req)
+	if err != nil {
+		h.authorizeError(w, r, req, err)
+		return
+	}
+
+	email := r.PostForm.Get("email")
+	user, err := h.auth.Authenticate(r.Context(), app.OrgID, email, r.PostForm.Get("password"))
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidCredentials) {
+			h.renderLogin(w, http.StatusUnauthorized, loginPageData{
+				AppName: app.Name,
+				Request: req,
+				Email:   email,
+				Error:   "Invalid email or password",
+			})
+			return
+		}
+		if inactiveUser(err) {
+			h.renderLogin(w, http.StatusForbidden, loginPageData{
+				AppName: app.Name,
+				Request: req,
+				Email:   email,
+				Error:   "This account is not active",
+			})
+			return
+		}
+		log.Error("failed to authenticate user", slog.String("error", err.Error()))
+
+		redirectError(w, r, req, errServerError, "")
+		return
+	}
+
+	code, err := h.oauth.IssueCode(r.Context(), req, user.ID)
+	if err != nil {
+		if errors.Is(err, oauth.ErrAccessDenied) {
+			redirectError(w, r, req, errAccessDenied, "user is not a member of the app")
+			return
+		}
+		log.Error("failed to issue authorization code", slog.String("error", err.Error()))
+
+		redirectError(w, r, req, errServerError, "")
+		return
+	}
+
+	params := url.Values{"code": {code}}
+	if req.State != "" {
+		params.Set("state", req.State)
+	}
+	redirect(w, r, req.RedirectURI, params)
+}
+
+func (h *handler) token(w http.ResponseWriter, r *http.Request) {
+	const op = "http.oauth.token"
+
+	log := h.log.With(slog.String("op", op))
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: errInvalidRequest, ErrorDescription: "malformed form"})
+		return
+	}
+
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientID = r.PostForm.Get("client_id")
+		clientSecret = r.PostForm.Get("client_secret")
+	}
+
+	if r.PostForm.Get("grant_type") == oauth.GrantTypeClientCredentials {
+		h.clientCredentials(w, r, clientID, clientSecret)
+		return
+	}
+
+	resp, err := h.oauth.Token(r.Context(), oauth.TokenRequest{
+		GrantType:    r.PostForm.Get("grant_type"),
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		Code:         r.PostForm.Get("code"),
+		RedirectURI:  r.PostForm.Get("redirect_uri"),
+		CodeVerifier: r.PostForm.Get("code_verifier"),
+		RefreshToken: r.PostForm.Get("refresh_token"),
+		DeviceCode:   r.PostForm.Get("device_code"),
+		Client:       clientInfo(r),
+	})
+	if err != nil {
+		if code, ok := deviceTokenError(err); ok {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: code})
+			return
+		}
+		switch {
+		case errors.Is(err, oauth.ErrInvalidClient):
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: errInvalidClient})
+		case errors.Is(err, oauth.ErrInvalidGrant):
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: errInvalidGrant})
+		case errors.Is(err, oauth.ErrUnsupportedGrantType):
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: errUnsupportedGrantType})
+		case errors.Is(err, oauth.ErrInvalidRequest):
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: errInvalidRequest})
+		default:
+			log.Error("failed to issue token", slog.String("error", err.Error()))
+
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: errServerError})
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, tokenResponse{
+		AccessToken:  resp.AccessToken,
+		TokenType:    resp.TokenType,
+		ExpiresIn:    resp.ExpiresIn,
+		RefreshToken: resp.RefreshToken,
+		IDToken:      resp.IDToken,
+		Scope:        resp.Scope,
+	})
+}
+
+func (h *handler) clientCredentials(w http.ResponseWriter, r *http.Request, clientID string, clientSecret string) {
+	const op = "http.oauth.clientCredentials"
+
+	log := h.log.With(slog.String("op", op))
+
+	appID, err := strconv.Atoi(clientID)
+	if err != nil || clientSecret == "" {
+		writeJSON(w, http.StatusUnauthorized, errorResponse{Error: errInvalidClient})
+		return
+	}
+
+	token, err := h.auth.ClientCredentials(r.Context(), appID, clientSecret, strings.Fields(r.PostForm.Get("scope")))
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrInvalidAppID), errors.Is(err, auth.ErrInvalidAppSecret):
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: errInvalidClient})
+		case errors.Is(err, auth.ErrInvalidScope):
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: errInvalidScope})
+		default:
+			log.Error("failed to issue app token", slog.String("error", err.Error()))
+
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: errServerError})
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, tokenResponse{
+		AccessToken: token.Token,
+		TokenType:   oauth.TokenTypeBearer,
+		ExpiresIn:   int64(token.ExpiresIn.Seconds()),
+		Scope:       strings.Join(token.Scopes, " "),
+	})
+}
+
+func (h *handler) userInfo(w http.ResponseWriter, r *http.Request) {
+	const op = "http.oauth.userInfo"
+
+	log := h.log.With(slog.String("op", op))
+
+	accessToken, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || accessToken == "" {
+		w.Header().Set("WWW-Authenticate", `Bearer`)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	user, err := h.oauth.UserInfo(r.Context(), accessToken)
+	if err != nil {
+		if errors.Is(err, oauth.ErrInvalidToken) {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		log.Error("failed to get user info", slog.String("error", err.Error()))
+
+		writeJSON(w, http.StatusInternalServerError, errorResponse{Error: errServerError})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, userInfoResponse{
+		Sub:   strconv.FormatInt(user.ID, 10),
+		Name:  user.Name,
+		Email: user.Email,
+	})
+}
+
+func (h *handler) discovery(w http.ResponseWriter, r *http.Request) {
+	issuer := strings.TrimSuffix(h.oauth.Issuer(), "/")
+
+	writeJSON(w, http.StatusOK, discoveryDocument{
+		Issuer:                            issuer,
+		AuthorizationEndpoint:             issuer + "/authorize",
+		TokenEndpoint:                     issuer + "/token",
+		UserInfoEndpoint:                  issuer + "/userinfo",
+		DeviceAuthorizationEndpoint:       issuer + "/device_authorization",
+		JWKSURI:                           issuer + "/.well-known/jwks.json",
+		ScopesSupported:                   []string{oauth.ScopeOpenID, "profile", "email"},
+		ResponseTypesSupported:            []string{oauth.ResponseTypeCode},
+		GrantTypesSupported:               []string{oauth.GrantTypeAuthorizationCode, oauth.GrantTypeRefreshToken, oauth.GrantTypeClientCredentials, oauth.GrantTypeDeviceCode},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  []string{"RS256"},
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:     []string{oauth.CodeChallengeMethodS256},
+		ClaimsSupported:                   []string{"sub", "iss", "aud", "exp", "iat", "auth_time", "nonce", "name", "email"},
+	})
+}
+
+// jwks serves the public keys clients verify ID tokens with.
+func (h *handler) jwks(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, jwksResponse{Keys: h.oauth.SigningKeys()})
+}
+
+// authorizeError reports errors of an authorization request. Errors about the
+// client or redirect URI are shown to the user, everything else is sent back
+// to the client's redirect URI.
+func (h *handler) authorizeError(w http.ResponseWriter, r *http.Request, req oauth.AuthorizeRequest, err error) {
+	switch {
+	case errors.Is(err, oauth.ErrInvalidClient):
+		http.Error(w, "unknown client", http.StatusBadRequest)
+	case errors.Is(err, oauth.ErrInvalidRedirectURI):
+		http.Error(w, "redirect_uri is not registered for this client", http.StatusBadRequest)
+	case errors.Is(err, oauth.ErrUnsupportedResponseType):
+		redirectError(w, r, req, errUnsupportedResponseType, "")
+	case errors.Is(err, oauth.ErrInvalidRequest):
+		redirectError(w, r, req, errInvalidRequest, "code_challenge with S256 method is required")
+	default:
+		h.log.Error("failed to validate authorization request", slog.String("error", err.Error()))
+
+		http.Error(w, "internal error", http.StatusInternalServerError)
+	}
+}
+
+func (h *handler) renderLogin(w http.ResponseWriter, status int, data loginPageData) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.WriteHeader(status)
+
+	if err := loginPage.Execute(w, data); err != nil {
+		h.log.Error("failed to render login page", slog.String("error", err.Error()))
+	}
+}
+
+func authorizeRequest(v url.Values) oauth.AuthorizeRequest {
+	return oauth.AuthorizeRequest{
+		ResponseType:        v.Get("response_type"),
+		ClientID:            v.Get("client_id"),
+		RedirectURI:         v.Get("redirect_uri"),
+		Scope:               v.Get("scope"),
+		State:               v.Get("state"),
+		CodeChallenge:       v.Get("code_challenge"),
+		CodeChallengeMethod: v.Get("code_challenge_method"),
+		Nonce:               v.Get("nonce"),
+	}
+}
+
+func redirectError(w http.ResponseWriter, r *http.Request, req oauth.AuthorizeRequest, code string, description string) {
+	params := url.Values{"error": {code}}
+	if description != "" {
+		params.Set("error_description", description)
+	}
+	if req.State != "" {
+		params.Set("state", req.State)
+	}
+	redirect(w, r, req.RedirectURI, params)
+}
+
+func redirect(w http.ResponseWriter, r *http.Request, redirectURI string, params url.Values) {
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+	q := u.Query()
+	for key, values := range params {
+		q[key] = values
+	}
+	u.RawQuery = q.Encode()
+
+	http.Redirect(w, r, u.String(), http.StatusFound)
+}
+
+// clientInfo describes the device that sent the request for its session.
+func clientInfo(r *http.Request) models.ClientInfo {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	return models.ClientInfo{
+		Device:    r.Header.Get("X-Device-Name"),
+		IP:        ip,
+		UserAgent: r.UserAgent(),
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// inactiveUser reports whether the user was refused for not being active.
+func inactiveUser(err error) bool {
+	return errors.Is(err, auth.ErrUserDisabled) ||
+		errors.Is(err, auth.ErrUserSuspended) ||
+		errors.Is(err, auth.ErrUserDeleted)
+}
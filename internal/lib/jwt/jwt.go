@@ -1,20 +1,131 @@
 package jwt
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/DavidG9999/my_grpc_app/internal/domain/models"
 	"github.com/dgrijalva/jwt-go"
 )
 
-func NewToken(user models.User, app models.App, duration time.Duration) (string, error) {
+var ErrInvalidToken = errors.New("invalid token")
+
+// Claims are the verified claims of an access token.
+type Claims struct {
+	UserID    int64
+	Email     string
+	OrgID     int64
+	AppID     int
+	SessionID int64
+	ExpiresAt time.Time
+	Scopes    []string
+	// Actor is the act claim of a delegated token, nil otherwise.
+	Actor map[string]any
+	// Groups are the names of the user's groups for apps that get them.
+	Groups []string
+	// APIKeyID is the API key the token was issued for, zero otherwise.
+	APIKeyID int64
+}
+
+// NewToken issues an access token for the user to the app. The groups claim
+// is only set if groups is not nil.
+func NewToken(user models.User, app models.App, key []byte, sessionID int64, groups []string, duration time.Duration) (string, error) {
+	token := jwt.New(jwt.SigningMethodHS256)
+
+	claims := token.Claims.(jwt.MapClaims)
+	claims["uid"] = user.ID
+	claims["email"] = user.Email
+	claims["org_id"] = user.OrgID
+	claims["exp"] = time.Now().Add(duration).Unix()
+	claims["app_id"] = app.ID
+	claims["sid"] = sessionID
+	if groups != nil {
+		claims["groups"] = groups
+	}
+	bind(claims, key)
+
+	tokenString, err := token.SignedString([]byte(app.Secret))
+	if err != nil {
+		return "", err
+	}
+	return tokenString, nil
+}
+
+// NewDelegatedToken issues a token for the user to the app on behalf of the
+// actor. The act claim records the actor and, nested within it, whoever the
+// actor itself was acting for.
+func NewDelegatedToken(user models.User, app models.App, key []byte, sessionID int64, scopes []string, groups []string, actor map[string]any, duration time.Duration) (string, error) {
+	token := jwt.New(jwt.SigningMethodHS256)
+
+	claims := token.Claims.(jwt.MapClaims)
+	claims["uid"] = user.ID
+	claims["email"] = user.Email
+	claims["org_id"] = user.OrgID
+	claims["exp"] = time.Now().Add(duration).Unix()
+	claims["app_id"] = app.ID
+	claims["sid"] = sessionID
+	claims["scope"] = strings.Join(scopes, " ")
+	claims["act"] = actor
+	if groups != nil {
+		claims["groups"] = groups
+	}
+	bind(claims, key)
+
+	tokenString, err := token.SignedString([]byte(app.Secret))
+	if err != nil {
+		return "", err
+	}
+	return tokenString, nil
+}
+
+// NewAPIKeyToken issues a token for the user to the app in exchange for one
+// of the user's API keys. It belongs to no session.
+func NewAPIKeyToken(user models.User, app models.App, key []byte, keyID int64, scopes []string, groups []string, duration time.Duration) (string, error) {
 	token := jwt.New(jwt.SigningMethodHS256)
 
 	claims := token.Claims.(jwt.MapClaims)
 	claims["uid"] = user.ID
 	claims["email"] = user.Email
+	claims["org_id"] = user.OrgID
 	claims["exp"] = time.Now().Add(duration).Unix()
 	claims["app_id"] = app.ID
+	claims["sid"] = 0
+	claims["scope"] = strings.Join(scopes, " ")
+	claims["api_key_id"] = keyID
+	if groups != nil {
+		claims["groups"] = groups
+	}
+	bind(claims, key)
+
+	tokenString, err := token.SignedString([]byte(app.Secret))
+	if err != nil {
+		return "", err
+	}
+	return tokenString, nil
+}
+
+// NewAppToken issues a token for the app itself. It has no uid claim, so it
+// is never accepted where a user's token is expected.
+func NewAppToken(app models.App, scopes []string, duration time.Duration) (string, error) {
+	token := jwt.New(jwt.SigningMethodHS256)
+
+	now := time.Now()
+
+	claims := token.Claims.(jwt.MapClaims)
+	claims["sub"] = strconv.Itoa(app.ID)
+	claims["client_id"] = strconv.Itoa(app.ID)
+	claims["app_id"] = app.ID
+	claims["org_id"] = app.OrgID
+	claims["scope"] = strings.Join(scopes, " ")
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(duration).Unix()
 
 	tokenString, err := token.SignedString([]byte(app.Secret))
 	if err != nil {
@@ -22,3 +133,160 @@ func NewToken(user models.User, app models.App, duration time.Duration) (string,
 	}
 	return tokenString, nil
 }
+
+// NewIDToken issues an OpenID Connect ID token for the app, signed with the
+// key so that the app verifies it with the key's JWK.
+func NewIDToken(user models.User, app models.App, key SigningKey, issuer string, nonce string, authTime time.Time, groups []string, duration time.Duration) (string, error) {
+	token := jwt.New(jwt.SigningMethodRS256)
+	token.Header["kid"] = key.ID
+
+	now := time.Now()
+
+	claims := token.Claims.(jwt.MapClaims)
+	claims["iss"] = issuer
+	claims["sub"] = strconv.FormatInt(user.ID, 10)
+	claims["aud"] = strconv.Itoa(app.ID)
+	claims["exp"] = now.Add(duration).Unix()
+	claims["iat"] = now.Unix()
+	claims["auth_time"] = authTime.Unix()
+	claims["email"] = user.Email
+	claims["name"] = user.Name
+	claims["org_id"] = user.OrgID
+	if nonce != "" {
+		claims["nonce"] = nonce
+	}
+	if groups != nil {
+		claims["groups"] = groups
+	}
+
+	tokenString, err := token.SignedString(key.key)
+	if err != nil {
+		return "", err
+	}
+	return tokenString, nil
+}
+
+// AppID returns the app the token claims to be issued for without verifying
+// it, so that the caller can look up the secret to verify it with.
+func AppID(tokenString string) (int, error) {
+	claims := jwt.MapClaims{}
+	if _, _, err := new(jwt.Parser).ParseUnverified(tokenString, claims); err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	appID, ok := claims["app_id"].(float64)
+	if !ok {
+		return 0, ErrInvalidToken
+	}
+	return int(appID), nil
+}
+
+// ParseToken verifies an access token issued for the app and bound with key,
+// and returns its claims.
+func ParseToken(tokenString string, app models.App, key []byte) (Claims, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+		}
+		return []byte(app.Secret), nil
+	})
+	if err != nil {
+		return Claims{}, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	mapClaims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid || !bound(mapClaims, key) {
+		return Claims{}, ErrInvalidToken
+	}
+
+	uid, ok := mapClaims["uid"].(float64)
+	if !ok {
+		return Claims{}, ErrInvalidToken
+	}
+	appID, ok := mapClaims["app_id"].(float64)
+	if !ok || int(appID) != app.ID {
+		return Claims{}, ErrInvalidToken
+	}
+	exp, ok := mapClaims["exp"].(float64)
+	if !ok {
+		return Claims{}, ErrInvalidToken
+	}
+	email, _ := mapClaims["email"].(string)
+	orgID, _ := mapClaims["org_id"].(float64)
+	sid, _ := mapClaims["sid"].(float64)
+	scope, _ := mapClaims["scope"].(string)
+	actor, _ := mapClaims["act"].(map[string]interface{})
+	keyID, _ := mapClaims["api_key_id"].(float64)
+	var groups []string
+	if list, ok := mapClaims["groups"].([]interface{}); ok {
+		for _, group := range list {
+			if name, ok := group.(string); ok {
+				groups = append(groups, name)
+			}
+		}
+	}
+
+	return Claims{
+		UserID:    int64(uid),
+		Email:     email,
+		OrgID:     int64(orgID),
+		AppID:     int(appID),
+		SessionID: int64(sid),
+		ExpiresAt: time.Unix(int64(exp), 0),
+		Scopes:    strings.Fields(scope),
+		Actor:     actor,
+		Groups:    groups,
+		APIKeyID:  int64(keyID),
+	}, nil
+}
+
+// SessionAppID returns the app the session of the token was opened for.
+// Exchanged tokens keep the session of the token they were exchanged for, so
+// it is the app of the innermost actor that was a client; other tokens
+// belong to sessions of their own app.
+func (c Claims) SessionAppID() int {
+	appID := c.AppID
+	for act := c.Actor; act != nil; act, _ = act["act"].(map[string]any) {
+		if clientID, ok := act["client_id"].(string); ok {
+			if id, err := strconv.Atoi(clientID); err == nil {
+				appID = id
+			}
+		}
+	}
+	return appID
+}
+
+// bindingClaim holds a MAC of the other claims under the server's key. Apps
+// know their secret and can sign any claims with it, the binding is what
+// tells the tokens issued by the SSO itself apart.
+const bindingClaim = "sso"
+
+func bind(claims jwt.MapClaims, key []byte) {
+	claims[bindingClaim] = binding(claims, key)
+}
+
+func bound(claims jwt.MapClaims, key []byte) bool {
+	mac, ok := claims[bindingClaim].(string)
+	if !ok || mac == "" {
+		return false
+	}
+	return hmac.Equal([]byte(mac), []byte(binding(claims, key)))
+}
+
+// binding is the MAC of the claims other than the binding claim. Claims are
+// marshaled with sorted keys, and numbers print the same whether they were
+// issued as integers or parsed as floats, so it is the same on both sides.
+func binding(claims jwt.MapClaims, key []byte) string {
+	unbound := make(map[string]any, len(claims))
+	for name, value := range claims {
+		if name != bindingClaim {
+			unbound[name] = value
+		}
+	}
+	payload, err := json.Marshal(unbound)
+	if err != nil {
+		return ""
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(payload)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
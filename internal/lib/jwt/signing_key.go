@@ -0,0 +1,97 @@
+package jwt
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+const signingKeyBits = 2048
+
+// SigningKey signs ID tokens with RS256. Its public half is published in the
+// JWKS, so that clients verify ID tokens without knowing any secret.
+type SigningKey struct {
+	// ID is the kid of the key, its RFC 7638 thumbprint.
+	ID  string
+	key *rsa.PrivateKey
+}
+
+// JWK is the public half of a signing key as a JSON Web Key.
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// LoadSigningKey reads an RSA private key from a PEM file.
+func LoadSigningKey(path string) (SigningKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return SigningKey{}, err
+	}
+	key, err := jwt.ParseRSAPrivateKeyFromPEM(data)
+	if err != nil {
+		return SigningKey{}, fmt.Errorf("parse signing key: %w", err)
+	}
+	return NewSigningKey(key)
+}
+
+// GenerateSigningKey generates a new RSA key. ID tokens signed with it no
+// longer verify once it is gone.
+func GenerateSigningKey() (SigningKey, error) {
+	key, err := rsa.GenerateKey(rand.Reader, signingKeyBits)
+	if err != nil {
+		return SigningKey{}, err
+	}
+	return NewSigningKey(key)
+}
+
+func NewSigningKey(key *rsa.PrivateKey) (SigningKey, error) {
+	if key.N.BitLen() < signingKeyBits {
+		return SigningKey{}, fmt.Errorf("signing key has %d bits, at least %d are required", key.N.BitLen(), signingKeyBits)
+	}
+	k := SigningKey{key: key}
+
+	// The thumbprint is the hash of the required members in lexical order.
+	thumbprint, err := json.Marshal(struct {
+		E   string `json:"e"`
+		Kty string `json:"kty"`
+		N   string `json:"n"`
+	}{E: k.exponent(), Kty: "RSA", N: k.modulus()})
+	if err != nil {
+		return SigningKey{}, err
+	}
+	sum := sha256.Sum256(thumbprint)
+	k.ID = base64.RawURLEncoding.EncodeToString(sum[:])
+	return k, nil
+}
+
+// JWK returns the public half of the key.
+func (k SigningKey) JWK() JWK {
+	return JWK{
+		Kty: "RSA",
+		Use: "sig",
+		Alg: jwt.SigningMethodRS256.Alg(),
+		Kid: k.ID,
+		N:   k.modulus(),
+		E:   k.exponent(),
+	}
+}
+
+func (k SigningKey) modulus() string {
+	return base64.RawURLEncoding.EncodeToString(k.key.N.Bytes())
+}
+
+func (k SigningKey) exponent() string {
+	return base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.key.E)).Bytes())
+}
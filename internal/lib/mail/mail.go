@@ -0,0 +1,43 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrHeaderInjection is returned for header values with line breaks, which
+// would start headers of their own.
+var ErrHeaderInjection = errors.New("mail header value contains a line break")
+
+// Sender delivers mail to users.
+type Sender interface {
+	Send(ctx context.Context, to string, subject string, body string) error
+}
+
+// Outbox writes mail to files in a directory instead of delivering it, one
+// file per recipient holding the latest mail. It stands in for delivery in
+// development and tests.
+type Outbox struct {
+	Dir string
+}
+
+func (o Outbox) Send(ctx context.Context, to string, subject string, body string) error {
+	if strings.ContainsAny(to, "\r\n") || strings.ContainsAny(subject, "\r\n") {
+		return ErrHeaderInjection
+	}
+	if err := os.MkdirAll(o.Dir, 0o700); err != nil {
+		return err
+	}
+	message := fmt.Sprintf("To: %s\r\nSubject: %s\r\n\r\n%s\r\n", to, subject, body)
+	return os.WriteFile(o.Path(to), []byte(message), 0o600)
+}
+
+// Path returns the file the latest mail to the recipient is written to.
+func (o Outbox) Path(to string) string {
+	return filepath.Join(o.Dir, url.PathEscape(to)+".eml")
+}
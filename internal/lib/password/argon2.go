@@ -0,0 +1,140 @@
+package password
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	AlgorithmArgon2id = "argon2id"
+
+	argon2SaltLen = 16
+	argon2KeyLen  = 32
+
+	// Longer salts and keys of imported hashes are not verified.
+	maxSaltLen = 64
+	maxKeyLen  = 64
+)
+
+// Argon2id hashes passwords in the PHC string format:
+// $argon2id$v=19$m=<memory>,t=<time>,p=<parallelism>$<salt>$<key>.
+type Argon2id struct {
+	// Memory is in KiB.
+	Memory      uint32
+	Time        uint32
+	Parallelism uint8
+	Limits      Limits
+}
+
+type argon2Hash struct {
+	version     int
+	memory      uint32
+	time        uint32
+	parallelism uint8
+	salt        []byte
+	key         []byte
+}
+
+func (a Argon2id) Hash(password string) ([]byte, error) {
+	salt := make([]byte, argon2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	key := argon2.IDKey([]byte(password), salt, a.Time, a.Memory, a.Parallelism, argon2KeyLen)
+
+	return []byte(fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, a.Memory, a.Time, a.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	)), nil
+}
+
+func (a Argon2id) Verify(hash []byte, password string) error {
+	h, err := a.parse(hash)
+	if err != nil {
+		return err
+	}
+	key := argon2.IDKey([]byte(password), h.salt, h.time, h.memory, h.parallelism, uint32(len(h.key)))
+	if subtle.ConstantTimeCompare(key, h.key) != 1 {
+		return ErrMismatch
+	}
+	return nil
+}
+
+func (a Argon2id) Recognizes(hash []byte) bool {
+	return bytes.HasPrefix(hash, []byte("$argon2id$"))
+}
+
+func (a Argon2id) Check(hash []byte) error {
+	_, err := a.parse(hash)
+	return err
+}
+
+func (a Argon2id) Outdated(hash []byte) bool {
+	h, err := parseArgon2Hash(hash)
+	if err != nil {
+		return true
+	}
+	return h.version != argon2.Version || h.memory != a.Memory || h.time != a.Time || h.parallelism != a.Parallelism
+}
+
+// parse parses the hash and checks its parameters against the limits.
+func (a Argon2id) parse(hash []byte) (argon2Hash, error) {
+	h, err := parseArgon2Hash(hash)
+	if err != nil {
+		return argon2Hash{}, err
+	}
+	if err := a.checkParams(h.memory, h.time, h.parallelism); err != nil {
+		return argon2Hash{}, err
+	}
+	if len(h.salt) > maxSaltLen || len(h.key) > maxKeyLen {
+		return argon2Hash{}, ErrHashLimits
+	}
+	return h, nil
+}
+
+// checkParams returns ErrHashLimits unless the parameters are at least one
+// and at most the limits.
+func (a Argon2id) checkParams(memory uint32, time uint32, parallelism uint8) error {
+	if memory < 1 || time < 1 || parallelism < 1 {
+		return ErrHashLimits
+	}
+	if memory > a.Limits.Argon2Memory || time > a.Limits.Argon2Time || parallelism > a.Limits.Argon2Parallelism {
+		return ErrHashLimits
+	}
+	return nil
+}
+
+func parseArgon2Hash(hash []byte) (argon2Hash, error) {
+	// The leading $ yields an empty first part.
+	parts := strings.Split(string(hash), "$")
+	if len(parts) != 6 || parts[1] != AlgorithmArgon2id {
+		return argon2Hash{}, ErrUnknownHash
+	}
+
+	var h argon2Hash
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &h.version); err != nil {
+		return argon2Hash{}, fmt.Errorf("%w: %v", ErrUnknownHash, err)
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &h.memory, &h.time, &h.parallelism); err != nil {
+		return argon2Hash{}, fmt.Errorf("%w: %v", ErrUnknownHash, err)
+	}
+
+	var err error
+	if h.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return argon2Hash{}, fmt.Errorf("%w: %v", ErrUnknownHash, err)
+	}
+	if h.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil {
+		return argon2Hash{}, fmt.Errorf("%w: %v", ErrUnknownHash, err)
+	}
+	if len(h.key) == 0 {
+		return argon2Hash{}, ErrUnknownHash
+	}
+	return h, nil
+}
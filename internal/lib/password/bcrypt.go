@@ -0,0 +1,58 @@
+package password
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const AlgorithmBcrypt = "bcrypt"
+
+// Bcrypt hashes passwords in the modular crypt format, e.g. $2a$10$....
+type Bcrypt struct {
+	Cost   int
+	Limits Limits
+}
+
+func (b Bcrypt) Hash(password string) ([]byte, error) {
+	return bcrypt.GenerateFromPassword([]byte(password), b.Cost)
+}
+
+func (b Bcrypt) Verify(hash []byte, password string) error {
+	if err := b.Check(hash); err != nil {
+		return err
+	}
+	err := bcrypt.CompareHashAndPassword(hash, []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return ErrMismatch
+	}
+	return err
+}
+
+func (b Bcrypt) Recognizes(hash []byte) bool {
+	return bytes.HasPrefix(hash, []byte("$2a$")) ||
+		bytes.HasPrefix(hash, []byte("$2b$")) ||
+		bytes.HasPrefix(hash, []byte("$2y$"))
+}
+
+func (b Bcrypt) Check(hash []byte) error {
+	cost, err := bcrypt.Cost(hash)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUnknownHash, err)
+	}
+	return b.checkCost(cost)
+}
+
+func (b Bcrypt) checkCost(cost int) error {
+	if cost < bcrypt.MinCost || cost > b.Limits.BcryptCost {
+		return ErrHashLimits
+	}
+	return nil
+}
+
+func (b Bcrypt) Outdated(hash []byte) bool {
+	cost, err := bcrypt.Cost(hash)
+	return err != nil || cost != b.Cost
+}
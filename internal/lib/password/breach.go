@@ -0,0 +1,236 @@
+package password
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+)
+
+const (
+	sha1HexLen = 2 * sha1.Size
+
+	// maxHIBPLineLen bounds a line of an HIBP file: the hash, a colon and
+	// the count.
+	maxHIBPLineLen = 128
+)
+
+var bloomMagic = []byte("SSOBLM01")
+
+// BreachedList tells whether a password is known from data breaches.
+type BreachedList interface {
+	Contains(password string) (bool, error)
+}
+
+// OpenBreachedList opens either a bloom filter made by BuildBloomFilter or
+// an HIBP file of uppercase SHA-1 hashes sorted in ascending order, one
+// HASH:COUNT per line.
+func OpenBreachedList(path string) (BreachedList, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	magic := make([]byte, len(bloomMagic))
+	if _, err := io.ReadFull(f, magic); err == nil && bytes.Equal(magic, bloomMagic) {
+		defer f.Close()
+
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		return ReadBloomFilter(bufio.NewReader(f))
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &HIBPFile{f: f, size: info.Size()}, nil
+}
+
+// HIBPFile searches a sorted HIBP file on disk without loading it.
+type HIBPFile struct {
+	f    *os.File
+	size int64
+}
+
+func (h *HIBPFile) Contains(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	target := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	// Binary search over byte offsets: the line being looked for always
+	// starts within [lo, hi).
+	lo, hi := int64(0), h.size
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+
+		start, line, err := h.lineAt(mid)
+		if err != nil {
+			return false, err
+		}
+		if line == nil || start >= hi {
+			hi = mid
+			continue
+		}
+		if len(line) < sha1HexLen {
+			return false, fmt.Errorf("malformed line at offset %d", start)
+		}
+
+		switch strings.Compare(strings.ToUpper(string(line[:sha1HexLen])), target) {
+		case 0:
+			return true, nil
+		case -1:
+			lo = start + int64(len(line)) + 1
+		default:
+			hi = mid
+		}
+	}
+	return false, nil
+}
+
+func (h *HIBPFile) Close() error {
+	return h.f.Close()
+}
+
+// lineAt returns the first line starting at or after the offset, or nil if
+// there is none.
+func (h *HIBPFile) lineAt(offset int64) (int64, []byte, error) {
+	start := offset
+	if offset > 0 {
+		// Skip the rest of the line the byte before the offset belongs to.
+		buf := make([]byte, maxHIBPLineLen)
+		n, err := h.f.ReadAt(buf, offset-1)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return 0, nil, err
+		}
+		i := bytes.IndexByte(buf[:n], '\n')
+		if i < 0 {
+			return 0, nil, nil
+		}
+		start = offset + int64(i)
+	}
+
+	buf := make([]byte, maxHIBPLineLen)
+	n, err := h.f.ReadAt(buf, start)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return 0, nil, err
+	}
+	if n == 0 {
+		return 0, nil, nil
+	}
+	line := buf[:n]
+	if i := bytes.IndexByte(line, '\n'); i >= 0 {
+		line = line[:i]
+	}
+	return start, line, nil
+}
+
+// BloomFilter is a compact, in-memory BreachedList with false positives at
+// the rate it was built for and no false negatives.
+type BloomFilter struct {
+	bits []uint64
+	m    uint64
+	k    uint32
+}
+
+// NewBloomFilter sizes a filter for n hashes at the false positive rate.
+func NewBloomFilter(n uint64, fpRate float64) *BloomFilter {
+	n = max(n, 1)
+	m := uint64(math.Ceil(-float64(n) * math.Log(fpRate) / (math.Ln2 * math.Ln2)))
+	k := uint32(math.Max(1, math.Round(float64(m)/float64(n)*math.Ln2)))
+	return &BloomFilter{
+		bits: make([]uint64, (m+63)/64),
+		m:    m,
+		k:    k,
+	}
+}
+
+// Add adds a SHA-1 digest of a password.
+func (b *BloomFilter) Add(digest [sha1.Size]byte) {
+	h1, h2 := bloomHashes(digest)
+	for i := uint64(0); i < uint64(b.k); i++ {
+		bit := (h1 + i*h2) % b.m
+		b.bits[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+func (b *BloomFilter) Contains(password string) (bool, error) {
+	h1, h2 := bloomHashes(sha1.Sum([]byte(password)))
+	for i := uint64(0); i < uint64(b.k); i++ {
+		bit := (h1 + i*h2) % b.m
+		if b.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// WriteTo writes the filter in the format ReadBloomFilter reads.
+func (b *BloomFilter) WriteTo(w io.Writer) (int64, error) {
+	header := make([]byte, 0, len(bloomMagic)+12)
+	header = append(header, bloomMagic...)
+	header = binary.BigEndian.AppendUint64(header, b.m)
+	header = binary.BigEndian.AppendUint32(header, b.k)
+
+	n, err := w.Write(header)
+	if err != nil {
+		return int64(n), err
+	}
+	if err := binary.Write(w, binary.BigEndian, b.bits); err != nil {
+		return int64(n), err
+	}
+	return int64(n) + int64(len(b.bits))*8, nil
+}
+
+func ReadBloomFilter(r io.Reader) (*BloomFilter, error) {
+	header := make([]byte, len(bloomMagic)+12)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(header[:len(bloomMagic)], bloomMagic) {
+		return nil, errors.New("not a bloom filter")
+	}
+	m := binary.BigEndian.Uint64(header[len(bloomMagic):])
+	k := binary.BigEndian.Uint32(header[len(bloomMagic)+8:])
+	if m == 0 || k == 0 {
+		return nil, errors.New("malformed bloom filter")
+	}
+
+	b := &BloomFilter{
+		bits: make([]uint64, (m+63)/64),
+		m:    m,
+		k:    k,
+	}
+	if err := binary.Read(r, binary.BigEndian, b.bits); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// ParseHIBPLine returns the digest of a HASH:COUNT line of an HIBP file.
+func ParseHIBPLine(line string) ([sha1.Size]byte, error) {
+	var digest [sha1.Size]byte
+
+	hash, _, _ := strings.Cut(strings.TrimSpace(line), ":")
+	if len(hash) != sha1HexLen {
+		return digest, fmt.Errorf("malformed line %q", line)
+	}
+	if _, err := hex.Decode(digest[:], []byte(hash)); err != nil {
+		return digest, fmt.Errorf("malformed line %q: %w", line, err)
+	}
+	return digest, nil
+}
+
+// bloomHashes derives the two hashes of double hashing from the digest,
+// which is already uniformly distributed.
+func bloomHashes(digest [sha1.Size]byte) (uint64, uint64) {
+	return binary.BigEndian.Uint64(digest[0:8]), binary.BigEndian.Uint64(digest[8:16]) | 1
+}
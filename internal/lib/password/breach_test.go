@@ -0,0 +1,234 @@
+package password
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_BloomFilter_RoundTrip(t *testing.T) {
+	passwords := make([]string, 1000)
+	for i := range passwords {
+		passwords[i] = fmt.Sprintf("breached-%d", i)
+	}
+
+	filter := NewBloomFilter(uint64(len(passwords)), 0.001)
+	for _, password := range passwords {
+		filter.Add(sha1.Sum([]byte(password)))
+	}
+
+	var buf bytes.Buffer
+	n, err := filter.WriteTo(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, int64(buf.Len()), n)
+
+	read, err := ReadBloomFilter(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, filter, read)
+
+	for _, password := range passwords {
+		contains, err := read.Contains(password)
+		require.NoError(t, err)
+		assert.True(t, contains, password)
+	}
+
+	falsePositives := 0
+	for i := range 1000 {
+		contains, err := read.Contains(fmt.Sprintf("unbreached-%d", i))
+		require.NoError(t, err)
+		if contains {
+			falsePositives++
+		}
+	}
+	assert.Less(t, falsePositives, 10)
+}
+
+func Test_ReadBloomFilter_Malformed(t *testing.T) {
+	var valid bytes.Buffer
+	_, err := NewBloomFilter(10, 0.01).WriteTo(&valid)
+	require.NoError(t, err)
+
+	header := func(m uint64, k uint32) []byte {
+		data := append([]byte{}, bloomMagic...)
+		data = binary.BigEndian.AppendUint64(data, m)
+		return binary.BigEndian.AppendUint32(data, k)
+	}
+
+	tests := []struct {
+		data []byte
+	}{
+		{
+			//Empty
+			data: nil,
+		},
+		{
+			//Truncated magic
+			data: bloomMagic[:4],
+		},
+		{
+			//Truncated header
+			data: valid.Bytes()[:len(bloomMagic)+6],
+		},
+		{
+			//Wrong magic
+			data: append([]byte("SSOBLM99"), valid.Bytes()[len(bloomMagic):]...),
+		},
+		{
+			//No bits
+			data: header(0, 3),
+		},
+		{
+			//No hashes
+			data: header(64, 0),
+		},
+		{
+			//Truncated bits
+			data: valid.Bytes()[:valid.Len()-1],
+		},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("Test_ReadBloomFilter_Malformed №%d", i), func(t *testing.T) {
+			_, err := ReadBloomFilter(bytes.NewReader(test.data))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func Test_HIBPFile_Contains(t *testing.T) {
+	// The passwords sorted by the hashes of the file, of which the first,
+	// one in the middle and the last are left out to miss before, between
+	// and after the lines.
+	passwords := make([]string, 101)
+	for i := range passwords {
+		passwords[i] = fmt.Sprintf("password-%d", i)
+	}
+	slices.SortFunc(passwords, func(a, b string) int {
+		return strings.Compare(hibpHash(a), hibpHash(b))
+	})
+	before, between, after := passwords[0], passwords[50], passwords[100]
+	breached := slices.Concat(passwords[1:50], passwords[51:100])
+
+	tests := []struct {
+		hash            func(password string) string
+		trailingNewline bool
+	}{
+		{
+			//Uppercase hashes
+			hash:            hibpHash,
+			trailingNewline: true,
+		},
+		{
+			//Lowercase hashes
+			hash:            func(password string) string { return strings.ToLower(hibpHash(password)) },
+			trailingNewline: true,
+		},
+		{
+			//No newline after the last line
+			hash: hibpHash,
+		},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("Test_HIBPFile_Contains №%d", i), func(t *testing.T) {
+			lines := make([]string, len(breached))
+			for j, password := range breached {
+				lines[j] = fmt.Sprintf("%s:%d", test.hash(password), j*1000+1)
+			}
+			data := strings.Join(lines, "\n")
+			if test.trailingNewline {
+				data += "\n"
+			}
+			list := openHIBPFile(t, data)
+
+			for _, password := range []string{breached[0], breached[len(breached)-1]} {
+				contains, err := list.Contains(password)
+				require.NoError(t, err)
+				assert.True(t, contains, password)
+			}
+			for _, password := range breached {
+				contains, err := list.Contains(password)
+				require.NoError(t, err)
+				assert.True(t, contains, password)
+			}
+			for _, password := range []string{before, between, after} {
+				contains, err := list.Contains(password)
+				require.NoError(t, err)
+				assert.False(t, contains, password)
+			}
+		})
+	}
+}
+
+func Test_HIBPFile_Malformed(t *testing.T) {
+	list := openHIBPFile(t, "0123456789:1\n")
+
+	_, err := list.Contains("password")
+	assert.ErrorContains(t, err, "malformed line")
+}
+
+func Test_OpenBreachedList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "breached.bloom")
+
+	filter := NewBloomFilter(1, 0.001)
+	filter.Add(sha1.Sum([]byte("breached")))
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	_, err = filter.WriteTo(f)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	list, err := OpenBreachedList(path)
+	require.NoError(t, err)
+	require.IsType(t, &BloomFilter{}, list)
+	contains, err := list.Contains("breached")
+	require.NoError(t, err)
+	assert.True(t, contains)
+
+	// Files too short for the magic are HIBP files.
+	list = openHIBPFile(t, "")
+	contains, err = list.Contains("breached")
+	require.NoError(t, err)
+	assert.False(t, contains)
+}
+
+func Test_ParseHIBPLine(t *testing.T) {
+	digest := sha1.Sum([]byte("password"))
+	hash := hex.EncodeToString(digest[:])
+
+	for _, line := range []string{strings.ToUpper(hash) + ":3861493", hash + ":1\r", strings.ToUpper(hash)} {
+		parsed, err := ParseHIBPLine(line)
+		require.NoError(t, err)
+		assert.Equal(t, digest, parsed)
+	}
+	for _, line := range []string{"", hash[:sha1HexLen-1] + ":1", "Z" + hash[1:] + ":1"} {
+		_, err := ParseHIBPLine(line)
+		assert.Error(t, err, line)
+	}
+}
+
+func hibpHash(password string) string {
+	sum := sha1.Sum([]byte(password))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+func openHIBPFile(t *testing.T, data string) BreachedList {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+
+	list, err := OpenBreachedList(path)
+	require.NoError(t, err)
+	require.IsType(t, &HIBPFile{}, list)
+	t.Cleanup(func() { list.(*HIBPFile).Close() })
+	return list
+}
@@ -0,0 +1,160 @@
+package password
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrMismatch    = errors.New("password does not match")
+	ErrUnknownHash = errors.New("unknown password hash format")
+	ErrHashLimits  = errors.New("password hash parameters are out of limits")
+)
+
+// Verifier checks passwords against hashes of one format.
+type Verifier interface {
+	// Verify returns ErrMismatch if the password does not match the hash.
+	// The hash is checked first.
+	Verify(hash []byte, password string) error
+	// Recognizes reports whether the hash is in the verifier's format.
+	Recognizes(hash []byte) bool
+	// Check returns ErrUnknownHash if the hash is malformed and
+	// ErrHashLimits if its parameters are out of the limits.
+	Check(hash []byte) error
+}
+
+// Limits bound the parameters of the hashes that are verified. Hashes carry
+// their own parameters, and those of imported hashes are not ours to choose:
+// without limits a single hash could exhaust the memory or CPU.
+type Limits struct {
+	// Argon2Memory is in KiB.
+	Argon2Memory      uint32
+	Argon2Time        uint32
+	Argon2Parallelism uint8
+	BcryptCost        int
+	PBKDF2Iterations  int
+	// ScryptCost bounds N·r·p.
+	ScryptCost int
+}
+
+// Algorithm is a password hashing algorithm with its parameters.
+type Algorithm interface {
+	Verifier
+	// Hash returns the encoded hash of the password.
+	Hash(password string) ([]byte, error)
+	// Outdated reports whether the hash was produced with other parameters
+	// than the configured ones.
+	Outdated(hash []byte) bool
+}
+
+// Hasher hashes new passwords with the current algorithm and verifies
+// hashes in any of the known formats.
+type Hasher struct {
+	current Algorithm
+	known   []Verifier
+}
+
+func NewHasher(current Algorithm, legacy ...Verifier) *Hasher {
+	return &Hasher{
+		current: current,
+		known:   append([]Verifier{current}, legacy...),
+	}
+}
+
+func (h *Hasher) Hash(password string) ([]byte, error) {
+	return h.current.Hash(password)
+}
+
+// Verify checks the password against the hash. When they match, rehash
+// reports whether the hash should be replaced with a new one from Hash.
+func (h *Hasher) Verify(hash []byte, password string) (rehash bool, err error) {
+	for i, v := range h.known {
+		if !v.Recognizes(hash) {
+			continue
+		}
+		if err := v.Verify(hash, password); err != nil {
+			return false, err
+		}
+		// The current algorithm comes first.
+		return i != 0 || h.current.Outdated(hash), nil
+	}
+	return false, ErrUnknownHash
+}
+
+// Recognizes reports whether the hash is in one of the known formats.
+func (h *Hasher) Recognizes(hash []byte) bool {
+	for _, v := range h.known {
+		if v.Recognizes(hash) {
+			return true
+		}
+	}
+	return false
+}
+
+// Check returns ErrUnknownHash if the hash is in none of the known formats or
+// malformed, and ErrHashLimits if its parameters are out of the limits.
+func (h *Hasher) Check(hash []byte) error {
+	for _, v := range h.known {
+		if v.Recognizes(hash) {
+			return v.Check(hash)
+		}
+	}
+	return ErrUnknownHash
+}
+
+// HashParams configures the supported algorithms.
+type HashParams struct {
+	BcryptCost        int
+	Argon2Memory      uint32
+	Argon2Time        uint32
+	Argon2Parallelism uint8
+	Limits            Limits
+}
+
+// NewHasherFor returns a Hasher that hashes with the named algorithm and
+// still verifies hashes of every other supported one, including the legacy
+// PBKDF2 and scrypt formats of imported users.
+func NewHasherFor(name string, params HashParams) (*Hasher, error) {
+	current, err := newAlgorithm(name, params)
+	if err != nil {
+		return nil, err
+	}
+
+	legacy := []Verifier{PBKDF2SHA256{Limits: params.Limits}, Scrypt{Limits: params.Limits}}
+	for _, other := range []string{AlgorithmArgon2id, AlgorithmBcrypt} {
+		if other == name {
+			continue
+		}
+		alg, err := newAlgorithm(other, params)
+		if err != nil {
+			return nil, err
+		}
+		legacy = append(legacy, alg)
+	}
+	return NewHasher(current, legacy...), nil
+}
+
+// newAlgorithm returns the named algorithm with the given parameters. They
+// have to be within the limits, or its own hashes would not verify.
+func newAlgorithm(name string, params HashParams) (Algorithm, error) {
+	switch name {
+	case AlgorithmArgon2id:
+		alg := Argon2id{
+			Memory:      params.Argon2Memory,
+			Time:        params.Argon2Time,
+			Parallelism: params.Argon2Parallelism,
+			Limits:      params.Limits,
+		}
+		if err := alg.checkParams(alg.Memory, alg.Time, alg.Parallelism); err != nil {
+			return nil, fmt.Errorf("argon2id parameters: %w", err)
+		}
+		return alg, nil
+	case AlgorithmBcrypt:
+		alg := Bcrypt{Cost: params.BcryptCost, Limits: params.Limits}
+		if err := alg.checkCost(alg.Cost); err != nil {
+			return nil, fmt.Errorf("bcrypt cost: %w", err)
+		}
+		return alg, nil
+	}
+	return nil, fmt.Errorf("unsupported password hash algorithm %q", name)
+}
@@ -0,0 +1,155 @@
+package password
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+)
+
+// PBKDF2SHA256 verifies hashes imported from older systems in the PHC
+// string format $pbkdf2-sha256$i=<iterations>$<salt>$<key>, with salt and
+// key in unpadded standard base64. New passwords are never hashed with it.
+type PBKDF2SHA256 struct {
+	Limits Limits
+}
+
+type pbkdf2Hash struct {
+	iterations int
+	salt       []byte
+	key        []byte
+}
+
+func (v PBKDF2SHA256) Verify(hash []byte, password string) error {
+	h, err := v.parse(hash)
+	if err != nil {
+		return err
+	}
+
+	derived := pbkdf2.Key([]byte(password), h.salt, h.iterations, len(h.key), sha256.New)
+	if subtle.ConstantTimeCompare(derived, h.key) != 1 {
+		return ErrMismatch
+	}
+	return nil
+}
+
+func (v PBKDF2SHA256) Check(hash []byte) error {
+	_, err := v.parse(hash)
+	return err
+}
+
+func (v PBKDF2SHA256) parse(hash []byte) (pbkdf2Hash, error) {
+	parts, err := splitPHC(hash, "pbkdf2-sha256")
+	if err != nil {
+		return pbkdf2Hash{}, err
+	}
+
+	var h pbkdf2Hash
+	if _, err := fmt.Sscanf(parts[0], "i=%d", &h.iterations); err != nil {
+		return pbkdf2Hash{}, ErrUnknownHash
+	}
+	if h.iterations < 1 || h.iterations > v.Limits.PBKDF2Iterations {
+		return pbkdf2Hash{}, ErrHashLimits
+	}
+	if h.salt, h.key, err = decodeSaltAndKey(parts[1], parts[2]); err != nil {
+		return pbkdf2Hash{}, err
+	}
+	return h, nil
+}
+
+func (PBKDF2SHA256) Recognizes(hash []byte) bool {
+	return bytes.HasPrefix(hash, []byte("$pbkdf2-sha256$"))
+}
+
+// Scrypt verifies hashes imported from older systems in the PHC string
+// format $scrypt$ln=<log2 N>,r=<r>,p=<p>$<salt>$<key>, with salt and key in
+// unpadded standard base64. New passwords are never hashed with it.
+type Scrypt struct {
+	Limits Limits
+}
+
+type scryptHash struct {
+	n, r, p int
+	salt    []byte
+	key     []byte
+}
+
+func (v Scrypt) Verify(hash []byte, password string) error {
+	h, err := v.parse(hash)
+	if err != nil {
+		return err
+	}
+
+	derived, err := scrypt.Key([]byte(password), h.salt, h.n, h.r, h.p, len(h.key))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUnknownHash, err)
+	}
+	if subtle.ConstantTimeCompare(derived, h.key) != 1 {
+		return ErrMismatch
+	}
+	return nil
+}
+
+func (v Scrypt) Check(hash []byte) error {
+	_, err := v.parse(hash)
+	return err
+}
+
+func (v Scrypt) parse(hash []byte) (scryptHash, error) {
+	parts, err := splitPHC(hash, "scrypt")
+	if err != nil {
+		return scryptHash{}, err
+	}
+
+	var ln int
+	var h scryptHash
+	if _, err := fmt.Sscanf(parts[0], "ln=%d,r=%d,p=%d", &ln, &h.r, &h.p); err != nil {
+		return scryptHash{}, ErrUnknownHash
+	}
+	// N·r·p is compared step by step so that it can not overflow.
+	if ln < 1 || ln >= 31 || h.r < 1 || h.p < 1 {
+		return scryptHash{}, ErrHashLimits
+	}
+	h.n = 1 << ln
+	if h.n > v.Limits.ScryptCost || h.r > v.Limits.ScryptCost/h.n || h.p > v.Limits.ScryptCost/(h.n*h.r) {
+		return scryptHash{}, ErrHashLimits
+	}
+	if h.salt, h.key, err = decodeSaltAndKey(parts[1], parts[2]); err != nil {
+		return scryptHash{}, err
+	}
+	return h, nil
+}
+
+func (Scrypt) Recognizes(hash []byte) bool {
+	return bytes.HasPrefix(hash, []byte("$scrypt$"))
+}
+
+// splitPHC returns the parameters, salt and key of a PHC string hash.
+func splitPHC(hash []byte, id string) ([]string, error) {
+	// The leading $ yields an empty first part.
+	parts := strings.Split(string(hash), "$")
+	if len(parts) != 5 || parts[0] != "" || parts[1] != id {
+		return nil, ErrUnknownHash
+	}
+	return parts[2:], nil
+}
+
+func decodeSaltAndKey(encodedSalt string, encodedKey string) ([]byte, []byte, error) {
+	salt, err := base64.RawStdEncoding.DecodeString(encodedSalt)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrUnknownHash, err)
+	}
+	key, err := base64.RawStdEncoding.DecodeString(encodedKey)
+	if err != nil || len(key) == 0 {
+		return nil, nil, ErrUnknownHash
+	}
+	if len(salt) > maxSaltLen || len(key) > maxKeyLen {
+		return nil, nil, ErrHashLimits
+	}
+	return salt, key, nil
+}
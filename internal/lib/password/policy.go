@@ -0,0 +1,195 @@
+package password
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// minPersonalInfoLen is the shortest name or email part that a password is
+// not allowed to contain; shorter ones would reject too many passwords.
+const minPersonalInfoLen = 3
+
+// Policy describes the passwords accepted by the service. Zero values
+// disable the corresponding rule.
+type Policy struct {
+	MinLength           int
+	MaxLength           int
+	RequireUpper        bool
+	RequireLower        bool
+	RequireDigit        bool
+	RequireSymbol       bool
+	MaxRepeated         int
+	ForbiddenSubstrings []string
+	ForbidPersonalInfo  bool
+	// MinStrengthScore is the lowest EstimateStrength score accepted.
+	MinStrengthScore int
+}
+
+// Policies holds the default policy and the per-app ones that replace it.
+type Policies struct {
+	Default Policy
+	Apps    map[int]Policy
+}
+
+// For returns the policy of the app, or the default one.
+func (p Policies) For(appID int) Policy {
+	if policy, ok := p.Apps[appID]; ok {
+		return policy
+	}
+	return p.Default
+}
+
+// Strictest returns a policy that only accepts passwords all the policies of
+// the apps accept, or the default one if there are no apps.
+func (p Policies) Strictest(appIDs ...int) Policy {
+	if len(appIDs) == 0 {
+		return p.Default
+	}
+	strictest := p.For(appIDs[0])
+	for _, appID := range appIDs[1:] {
+		policy := p.For(appID)
+
+		strictest.MinLength = max(strictest.MinLength, policy.MinLength)
+		strictest.MaxLength = minLimit(strictest.MaxLength, policy.MaxLength)
+		strictest.RequireUpper = strictest.RequireUpper || policy.RequireUpper
+		strictest.RequireLower = strictest.RequireLower || policy.RequireLower
+		strictest.RequireDigit = strictest.RequireDigit || policy.RequireDigit
+		strictest.RequireSymbol = strictest.RequireSymbol || policy.RequireSymbol
+		strictest.MaxRepeated = minLimit(strictest.MaxRepeated, policy.MaxRepeated)
+		strictest.ForbiddenSubstrings = append(slices.Clip(strictest.ForbiddenSubstrings), policy.ForbiddenSubstrings...)
+		strictest.ForbidPersonalInfo = strictest.ForbidPersonalInfo || policy.ForbidPersonalInfo
+		strictest.MinStrengthScore = max(strictest.MinStrengthScore, policy.MinStrengthScore)
+	}
+	return strictest
+}
+
+// minLimit returns the lower of two limits, zero being no limit.
+func minLimit(a int, b int) int {
+	if a == 0 || b == 0 {
+		return max(a, b)
+	}
+	return min(a, b)
+}
+
+// Violation is a single rule the password breaks.
+type Violation struct {
+	Field       string
+	Description string
+}
+
+// PolicyError reports every rule a password breaks.
+type PolicyError struct {
+	Violations []Violation
+	// Strength is set when the password is too weak.
+	Strength *Strength
+}
+
+func (e *PolicyError) Error() string {
+	descriptions := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		descriptions = append(descriptions, v.Description)
+	}
+	return "password does not satisfy policy: " + strings.Join(descriptions, "; ")
+}
+
+// Validate checks the password against the policy. personalInfo are values
+// such as the user's name and email that the password must not contain. It
+// returns a *PolicyError with all violations, or nil.
+func (p Policy) Validate(password string, personalInfo ...string) error {
+	var violations []Violation
+	violate := func(format string, args ...any) {
+		violations = append(violations, Violation{
+			Field:       "password",
+			Description: fmt.Sprintf(format, args...),
+		})
+	}
+
+	length := utf8.RuneCountInString(password)
+	if p.MinLength > 0 && length < p.MinLength {
+		violate("must be at least %d characters long", p.MinLength)
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		violate("must be at most %d characters long", p.MaxLength)
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case !unicode.IsLetter(r):
+			symbol = true
+		}
+	}
+	if p.RequireUpper && !upper {
+		violate("must contain an uppercase letter")
+	}
+	if p.RequireLower && !lower {
+		violate("must contain a lowercase letter")
+	}
+	if p.RequireDigit && !digit {
+		violate("must contain a digit")
+	}
+	if p.RequireSymbol && !symbol {
+		violate("must contain a symbol")
+	}
+
+	if p.MaxRepeated > 0 && maxRepeated(password) > p.MaxRepeated {
+		violate("must not repeat a character more than %d times in a row", p.MaxRepeated)
+	}
+
+	lowered := strings.ToLower(password)
+	for _, s := range p.ForbiddenSubstrings {
+		if s != "" && strings.Contains(lowered, strings.ToLower(s)) {
+			violate("must not contain %q", s)
+		}
+	}
+	if p.ForbidPersonalInfo {
+		for _, info := range personalInfo {
+			// Only the local part of an email is something people reuse.
+			info, _, _ = strings.Cut(strings.ToLower(info), "@")
+			if utf8.RuneCountInString(info) >= minPersonalInfoLen && strings.Contains(lowered, info) {
+				violate("must not contain your name or email")
+				break
+			}
+		}
+	}
+
+	var weak *Strength
+	if p.MinStrengthScore > 0 {
+		strength := EstimateStrength(password, personalInfo...)
+		if strength.Score < p.MinStrengthScore {
+			violate("is too weak")
+			weak = &strength
+		}
+	}
+
+	if len(violations) > 0 {
+		return &PolicyError{Violations: violations, Strength: weak}
+	}
+	return nil
+}
+
+func maxRepeated(s string) int {
+	var (
+		longest, run int
+		prev         rune = -1
+	)
+	for _, r := range s {
+		if r == prev {
+			run++
+		} else {
+			run = 1
+			prev = r
+		}
+		longest = max(longest, run)
+	}
+	return longest
+}
@@ -0,0 +1,41 @@
+package password
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Policies_Strictest(t *testing.T) {
+	policies := Policies{
+		Default: Policy{MinLength: 8, MaxLength: 72, ForbidPersonalInfo: true},
+		Apps: map[int]Policy{
+			1: {MinLength: 12, RequireUpper: true, MaxRepeated: 3, ForbiddenSubstrings: []string{"acme"}},
+			2: {MinLength: 10, MaxLength: 64, RequireDigit: true, MaxRepeated: 2, MinStrengthScore: 3},
+		},
+	}
+
+	assert.Equal(t, policies.Default, policies.Strictest())
+	assert.Equal(t, policies.Apps[1], policies.Strictest(1))
+	assert.Equal(t, Policy{
+		MinLength:           12,
+		MaxLength:           72,
+		RequireUpper:        true,
+		MaxRepeated:         3,
+		ForbiddenSubstrings: []string{"acme"},
+		ForbidPersonalInfo:  true,
+	}, policies.Strictest(1, 3))
+	assert.Equal(t, Policy{
+		MinLength:           12,
+		MaxLength:           64,
+		RequireUpper:        true,
+		RequireDigit:        true,
+		MaxRepeated:         2,
+		ForbiddenSubstrings: []string{"acme"},
+		MinStrengthScore:    3,
+	}, policies.Strictest(1, 2))
+
+	// Merging does not change the policies it merges.
+	policies.Strictest(1, 2, 1)
+	assert.Equal(t, []string{"acme"}, policies.Apps[1].ForbiddenSubstrings)
+}
@@ -0,0 +1,594 @@
+package password
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// The estimator follows zxcvbn: it splits the password into the patterns an
+// attacker would guess first, estimates the guesses needed for each and
+// searches for the split with the fewest guesses overall.
+
+const (
+	// bruteforceCardinality is the guesses per character not matched by
+	// any pattern.
+	bruteforceCardinality = 10
+	minSubmatchGuesses    = 50
+	// minGuessesBetweenMatches penalizes passwords made of many patterns.
+	minGuessesBetweenMatches = 10000
+	minYearSpace             = 20
+	minDictionaryMatchLen    = 3
+	// maxEstimatedLen bounds the work on long passwords, which are strong
+	// anyway.
+	maxEstimatedLen = 100
+	maxYear         = 2099
+	minYear         = 1900
+)
+
+// Strength is the estimated strength of a password.
+type Strength struct {
+	// Score is from 0, too guessable, to 4, very unguessable.
+	Score       int
+	Guesses     float64
+	Warning     string
+	Suggestions []string
+}
+
+type patternKind int
+
+const (
+	patternBruteforce patternKind = iota
+	patternDictionary
+	patternSpatial
+	patternSequence
+	patternRepeat
+	patternYear
+)
+
+type dictionaryKind int
+
+const (
+	dictionaryPasswords dictionaryKind = iota
+	dictionaryWords
+	dictionaryUserInputs
+)
+
+type match struct {
+	kind    patternKind
+	i, j    int // runes [i, j]
+	token   string
+	guesses float64
+
+	dictionary dictionaryKind
+	rank       int
+	l33t       bool
+	reversed   bool
+	baseToken  string
+}
+
+var (
+	rankedPasswords = rankedDictionary(commonPasswords)
+	rankedWords     = rankedDictionary(commonWords)
+
+	keyboardRows = []string{"qwertyuiop", "asdfghjkl", "zxcvbnm", "1234567890", "!@#$%^&*()"}
+
+	l33tTable = map[rune]rune{
+		'4': 'a', '@': 'a', '8': 'b', '(': 'c', '3': 'e', '6': 'g', '1': 'i', '!': 'i',
+		'|': 'l', '0': 'o', '$': 's', '5': 's', '7': 't', '+': 't', '2': 'z',
+	}
+)
+
+// EstimateStrength estimates how hard the password is to guess for an
+// attacker who also knows the userInputs, such as the user's name and email.
+func EstimateStrength(password string, userInputs ...string) Strength {
+	runes := []rune(password)
+	if len(runes) > maxEstimatedLen {
+		runes = runes[:maxEstimatedLen]
+	}
+	if len(runes) == 0 {
+		return Strength{Warning: "Password is empty", Suggestions: defaultSuggestions()}
+	}
+
+	matches := omnimatch(runes, rankedDictionary(splitUserInputs(userInputs)))
+	guesses, sequence := mostGuessableSequence(runes, matches)
+
+	strength := Strength{
+		Score:   guessesToScore(guesses),
+		Guesses: guesses,
+	}
+	strength.Warning, strength.Suggestions = feedback(strength.Score, sequence)
+	return strength
+}
+
+func omnimatch(runes []rune, userInputs map[string]int) []match {
+	lower := []rune(strings.ToLower(string(runes)))
+
+	var matches []match
+	matches = append(matches, dictionaryMatches(runes, lower, userInputs)...)
+	matches = append(matches, spatialMatches(runes, lower)...)
+	matches = append(matches, sequenceMatches(runes)...)
+	matches = append(matches, repeatMatches(runes, userInputs)...)
+	matches = append(matches, yearMatches(runes)...)
+	return matches
+}
+
+func dictionaryMatches(runes []rune, lower []rune, userInputs map[string]int) []match {
+	dictionaries := []struct {
+		kind  dictionaryKind
+		ranks map[string]int
+	}{
+		{dictionaryPasswords, rankedPasswords},
+		{dictionaryWords, rankedWords},
+		{dictionaryUserInputs, userInputs},
+	}
+
+	translated := make([]rune, len(lower))
+	hasL33t := false
+	for k, r := range lower {
+		if sub, ok := l33tTable[r]; ok {
+			translated[k] = sub
+			hasL33t = true
+		} else {
+			translated[k] = r
+		}
+	}
+	reversed := slices.Clone(lower)
+	slices.Reverse(reversed)
+
+	var matches []match
+	n := len(lower)
+	for i := 0; i < n; i++ {
+		for j := i + minDictionaryMatchLen - 1; j < n; j++ {
+			token := string(runes[i : j+1])
+			for _, dict := range dictionaries {
+				if rank, ok := dict.ranks[string(lower[i:j+1])]; ok {
+					m := match{kind: patternDictionary, i: i, j: j, token: token, dictionary: dict.kind, rank: rank, baseToken: string(lower[i : j+1])}
+					m.guesses = dictionaryGuesses(m)
+					matches = append(matches, m)
+				}
+				if rank, ok := dict.ranks[string(reversed[n-1-j:n-i])]; ok {
+					m := match{kind: patternDictionary, i: i, j: j, token: token, dictionary: dict.kind, rank: rank, reversed: true, baseToken: string(reversed[n-1-j : n-i])}
+					m.guesses = dictionaryGuesses(m)
+					matches = append(matches, m)
+				}
+				if hasL33t && string(translated[i:j+1]) != string(lower[i:j+1]) {
+					if rank, ok := dict.ranks[string(translated[i:j+1])]; ok {
+						m := match{kind: patternDictionary, i: i, j: j, token: token, dictionary: dict.kind, rank: rank, l33t: true, baseToken: string(translated[i : j+1])}
+						m.guesses = dictionaryGuesses(m)
+						matches = append(matches, m)
+					}
+				}
+			}
+		}
+	}
+	return matches
+}
+
+func dictionaryGuesses(m match) float64 {
+	guesses := float64(m.rank) * uppercaseVariations(m.token)
+	if m.l33t {
+		guesses *= l33tVariations(m.token)
+	}
+	if m.reversed {
+		guesses *= 2
+	}
+	return guesses
+}
+
+func uppercaseVariations(token string) float64 {
+	runes := []rune(token)
+	var upper, lower int
+	for _, r := range runes {
+		if unicode.IsUpper(r) {
+			upper++
+		} else if unicode.IsLower(r) {
+			lower++
+		}
+	}
+	if upper == 0 || lower == 0 {
+		if upper == 0 {
+			return 1
+		}
+		// All uppercase.
+		return 2
+	}
+	if unicode.IsUpper(runes[0]) && upper == 1 || unicode.IsUpper(runes[len(runes)-1]) && upper == 1 {
+		return 2
+	}
+	return combinations(upper, lower)
+}
+
+func l33tVariations(token string) float64 {
+	var subbed, unsubbed int
+	for _, r := range strings.ToLower(token) {
+		if _, ok := l33tTable[r]; ok {
+			subbed++
+		} else {
+			unsubbed++
+		}
+	}
+	return max(2, combinations(subbed, unsubbed))
+}
+
+// combinations is the number of ways to pick up to min(a, b) of a+b.
+func combinations(a int, b int) float64 {
+	var sum float64
+	for k := 1; k <= min(a, b); k++ {
+		sum += binomial(a+b, k)
+	}
+	return max(sum, 1)
+}
+
+func binomial(n int, k int) float64 {
+	result := 1.0
+	for d := 1; d <= k; d++ {
+		result = result * float64(n-k+d) / float64(d)
+	}
+	return result
+}
+
+// spatialMatches finds straight runs of neighbouring keys.
+func spatialMatches(runes []rune, lower []rune) []match {
+	var matches []match
+	for _, row := range keyboardRows {
+		rowRunes := []rune(row)
+		for _, direction := range []int{1, -1} {
+			for i := 0; i < len(lower); {
+				j := i
+				for j+1 < len(lower) && keyboardNeighbour(rowRunes, lower[j], lower[j+1], direction) {
+					j++
+				}
+				if j-i+1 >= 3 {
+					length := float64(j - i + 1)
+					matches = append(matches, match{
+						kind:    patternSpatial,
+						i:       i,
+						j:       j,
+						token:   string(runes[i : j+1]),
+						guesses: float64(len(rowRunes)) * 2 * length,
+					})
+				}
+				i = j + 1
+			}
+		}
+	}
+	return matches
+}
+
+func keyboardNeighbour(row []rune, a rune, b rune, direction int) bool {
+	k := slices.Index(row, a)
+	return k >= 0 && k+direction >= 0 && k+direction < len(row) && row[k+direction] == b
+}
+
+// sequenceMatches finds runs like abc, 6543 or ACEG.
+func sequenceMatches(runes []rune) []match {
+	var matches []match
+	for i := 0; i+2 < len(runes); {
+		delta := int(runes[i+1]) - int(runes[i])
+		j := i + 1
+		for j+1 < len(runes) && int(runes[j+1])-int(runes[j]) == delta && sameClass(runes[j], runes[j+1]) {
+			j++
+		}
+		if j-i+1 >= 3 && delta != 0 && abs(delta) <= 5 && sameClass(runes[i], runes[i+1]) {
+			matches = append(matches, match{
+				kind:    patternSequence,
+				i:       i,
+				j:       j,
+				token:   string(runes[i : j+1]),
+				guesses: sequenceGuesses(runes[i], delta, j-i+1),
+			})
+			i = j
+			continue
+		}
+		i++
+	}
+	return matches
+}
+
+func sequenceGuesses(first rune, delta int, length int) float64 {
+	var base float64
+	switch {
+	case strings.ContainsRune("aAzZ019", first):
+		base = 4
+	case unicode.IsDigit(first):
+		base = 10
+	default:
+		base = 26
+	}
+	if delta < 0 {
+		base *= 2
+	}
+	return base * float64(length)
+}
+
+func sameClass(a rune, b rune) bool {
+	return unicode.IsDigit(a) && unicode.IsDigit(b) ||
+		unicode.IsLower(a) && unicode.IsLower(b) ||
+		unicode.IsUpper(a) && unicode.IsUpper(b)
+}
+
+// repeatMatches finds a unit repeated back to back, like aaa or abcabc.
+func repeatMatches(runes []rune, userInputs map[string]int) []match {
+	var matches []match
+	for i := 0; i < len(runes); {
+		bestLen, bestUnit := 0, 0
+		for unit := 1; i+2*unit <= len(runes); unit++ {
+			repeats := 1
+			for i+(repeats+1)*unit <= len(runes) && slices.Equal(runes[i:i+unit], runes[i+repeats*unit:i+(repeats+1)*unit]) {
+				repeats++
+			}
+			if repeats >= 2 && repeats*unit > bestLen {
+				bestLen, bestUnit = repeats*unit, unit
+			}
+		}
+		if bestLen == 0 {
+			i++
+			continue
+		}
+
+		unit := runes[i : i+bestUnit]
+		baseGuesses, _ := mostGuessableSequence(unit, omnimatch(unit, userInputs))
+		matches = append(matches, match{
+			kind:      patternRepeat,
+			i:         i,
+			j:         i + bestLen - 1,
+			token:     string(runes[i : i+bestLen]),
+			baseToken: string(unit),
+			guesses:   baseGuesses * float64(bestLen/bestUnit),
+		})
+		i += bestLen
+	}
+	return matches
+}
+
+// yearMatches finds years, which people often append to passwords.
+func yearMatches(runes []rune) []match {
+	var matches []match
+	for i := 0; i+4 <= len(runes); i++ {
+		year := 0
+		digits := true
+		for _, r := range runes[i : i+4] {
+			if !unicode.IsDigit(r) || r > '9' {
+				digits = false
+				break
+			}
+			year = year*10 + int(r-'0')
+		}
+		if !digits || year < minYear || year > maxYear {
+			continue
+		}
+		matches = append(matches, match{
+			kind:    patternYear,
+			i:       i,
+			j:       i + 3,
+			token:   string(runes[i : i+4]),
+			guesses: float64(max(abs(year-time.Now().Year()), minYearSpace)),
+		})
+	}
+	return matches
+}
+
+// mostGuessableSequence finds the split of the password into matches and
+// bruteforced runs that needs the fewest guesses, the way zxcvbn does.
+func mostGuessableSequence(runes []rune, matches []match) (float64, []match) {
+	n := len(runes)
+
+	// Every run of unmatched characters could be bruteforced.
+	for i := 0; i < n; i++ {
+		for j := i; j < n; j++ {
+			matches = append(matches, match{
+				kind:    patternBruteforce,
+				i:       i,
+				j:       j,
+				token:   string(runes[i : j+1]),
+				guesses: math.Pow(bruteforceCardinality, float64(j-i+1)),
+			})
+		}
+	}
+
+	byEnd := make([][]int, n)
+	for k, m := range matches {
+		byEnd[m.j] = append(byEnd[m.j], k)
+	}
+
+	// best[k][l] is the fewest guesses for the first k runes split into l
+	// matches, prev[k][l] the last of them.
+	best := make([][]float64, n+1)
+	prev := make([][]int, n+1)
+	for k := range best {
+		best[k] = make([]float64, n+1)
+		prev[k] = make([]int, n+1)
+		for l := range best[k] {
+			best[k][l] = math.Inf(1)
+			prev[k][l] = -1
+		}
+	}
+	best[0][0] = 1
+
+	for k := 1; k <= n; k++ {
+		for _, idx := range byEnd[k-1] {
+			m := matches[idx]
+			guesses := m.guesses
+			if m.kind != patternBruteforce {
+				minGuesses := float64(minSubmatchGuesses)
+				if m.j == m.i {
+					minGuesses = bruteforceCardinality
+				}
+				guesses = max(guesses, minGuesses)
+			}
+			for l := 1; l <= k; l++ {
+				if math.IsInf(best[m.i][l-1], 1) {
+					continue
+				}
+				// Adjacent bruteforce runs are one run.
+				if m.kind == patternBruteforce && m.i > 0 && prev[m.i][l-1] >= 0 && matches[prev[m.i][l-1]].kind == patternBruteforce {
+					continue
+				}
+				if g := best[m.i][l-1] * guesses; g < best[k][l] {
+					best[k][l] = g
+					prev[k][l] = idx
+				}
+			}
+		}
+	}
+
+	guesses, length := math.Inf(1), 0
+	for l := 1; l <= n; l++ {
+		if math.IsInf(best[n][l], 1) {
+			continue
+		}
+		g := factorial(l)*best[n][l] + math.Pow(minGuessesBetweenMatches, float64(l-1))
+		if g < guesses {
+			guesses, length = g, l
+		}
+	}
+
+	sequence := make([]match, length)
+	for k, l := n, length; l > 0; l-- {
+		m := matches[prev[k][l]]
+		sequence[l-1] = m
+		k = m.i
+	}
+	return guesses, sequence
+}
+
+func factorial(n int) float64 {
+	result := 1.0
+	for k := 2; k <= n; k++ {
+		result *= float64(k)
+	}
+	return result
+}
+
+func guessesToScore(guesses float64) int {
+	switch {
+	case guesses < 1e3+5:
+		return 0
+	case guesses < 1e6+5:
+		return 1
+	case guesses < 1e8+5:
+		return 2
+	case guesses < 1e10+5:
+		return 3
+	default:
+		return 4
+	}
+}
+
+func feedback(score int, sequence []match) (string, []string) {
+	if score > 2 {
+		return "", nil
+	}
+	if len(sequence) == 0 {
+		return "", defaultSuggestions()
+	}
+
+	longest := sequence[0]
+	for _, m := range sequence[1:] {
+		if len(m.token) > len(longest.token) {
+			longest = m
+		}
+	}
+	warning, suggestions := matchFeedback(longest, len(sequence) == 1)
+	return warning, append([]string{"Add another word or two. Uncommon words are better."}, suggestions...)
+}
+
+func matchFeedback(m match, sole bool) (string, []string) {
+	switch m.kind {
+	case patternDictionary:
+		return dictionaryFeedback(m, sole)
+	case patternSpatial:
+		return "Straight rows of keys are easy to guess", []string{"Use a longer keyboard pattern with more turns"}
+	case patternRepeat:
+		if len([]rune(m.baseToken)) == 1 {
+			return `Repeats like "aaa" are easy to guess`, []string{"Avoid repeated words and characters"}
+		}
+		return `Repeats like "abcabcabc" are only slightly harder to guess than "abc"`, []string{"Avoid repeated words and characters"}
+	case patternSequence:
+		return "Sequences like abc or 6543 are easy to guess", []string{"Avoid sequences"}
+	case patternYear:
+		return "Recent years are easy to guess", []string{"Avoid recent years", "Avoid years that are associated with you"}
+	}
+	return "", nil
+}
+
+func dictionaryFeedback(m match, sole bool) (string, []string) {
+	var warning string
+	switch m.dictionary {
+	case dictionaryPasswords:
+		switch {
+		case sole && !m.l33t && !m.reversed && m.rank <= 10:
+			warning = "This is a top-10 common password"
+		case sole && !m.l33t && !m.reversed && m.rank <= 100:
+			warning = "This is a top-100 common password"
+		case sole:
+			warning = "This is a very common password"
+		default:
+			warning = "This is similar to a commonly used password"
+		}
+	case dictionaryWords:
+		if sole {
+			warning = "A word by itself is easy to guess"
+		}
+	case dictionaryUserInputs:
+		warning = "Avoid using your name or email"
+	}
+
+	var suggestions []string
+	runes := []rune(m.token)
+	switch {
+	case strings.ToUpper(m.token) == m.token && strings.ToLower(m.token) != m.token:
+		suggestions = append(suggestions, "All-uppercase is almost as easy to guess as all-lowercase")
+	case unicode.IsUpper(runes[0]):
+		suggestions = append(suggestions, "Capitalization doesn't help very much")
+	}
+	if m.reversed && len(runes) >= 4 {
+		suggestions = append(suggestions, "Reversed words aren't much harder to guess")
+	}
+	if m.l33t {
+		suggestions = append(suggestions, "Predictable substitutions like '@' instead of 'a' don't help very much")
+	}
+	return warning, suggestions
+}
+
+func defaultSuggestions() []string {
+	return []string{
+		"Use a few words, avoid common phrases",
+		"No need for symbols, digits, or uppercase letters",
+	}
+}
+
+// splitUserInputs returns the user inputs and their parts, e.g. the words
+// of a name and the local part of an email, lowercased.
+func splitUserInputs(userInputs []string) []string {
+	var words []string
+	for _, input := range userInputs {
+		input = strings.ToLower(input)
+		local, _, _ := strings.Cut(input, "@")
+		words = append(words, input, local)
+		words = append(words, strings.FieldsFunc(local, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})...)
+	}
+	return words
+}
+
+func rankedDictionary(words []string) map[string]int {
+	ranks := make(map[string]int, len(words))
+	for k, word := range words {
+		if _, ok := ranks[word]; !ok && word != "" {
+			ranks[word] = k + 1
+		}
+	}
+	return ranks
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,65 @@
+package password
+
+// commonPasswords are the most used passwords from public breach corpora,
+// most common first.
+var commonPasswords = []string{
+	"123456", "password", "12345678", "qwerty", "123456789", "12345", "1234", "111111", "1234567", "dragon",
+	"123123", "baseball", "abc123", "football", "monkey", "letmein", "696969", "shadow", "master", "666666",
+	"qwertyuiop", "123321", "mustang", "1234567890", "michael", "654321", "superman", "1qaz2wsx", "7777777", "121212",
+	"000000", "qazwsx", "123qwe", "killer", "trustno1", "jordan", "jennifer", "zxcvbnm", "asdfgh", "hunter",
+	"buster", "soccer", "harley", "batman", "andrew", "tigger", "sunshine", "iloveyou", "2000", "charlie",
+	"robert", "thomas", "hockey", "ranger", "daniel", "starwars", "klaster", "112233", "george", "computer",
+	"michelle", "jessica", "pepper", "1111", "zxcvbn", "555555", "11111111", "131313", "freedom", "777777",
+	"pass", "maggie", "159753", "aaaaaa", "ginger", "princess", "joshua", "cheese", "amanda", "summer",
+	"love", "ashley", "nicole", "chelsea", "biteme", "matthew", "access", "yankees", "987654321", "dallas",
+	"austin", "thunder", "taylor", "matrix", "william", "corvette", "hello", "martin", "heather", "secret",
+	"merlin", "diamond", "1234qwer", "gfhjkm", "hammer", "silver", "222222", "88888888", "anthony", "justin",
+	"test", "bailey", "q1w2e3r4t5", "patrick", "internet", "scooter", "orange", "11111", "golfer", "cookie",
+	"richard", "samantha", "bigdog", "guitar", "jackson", "whatever", "mickey", "chicken", "sparky", "snoopy",
+	"maverick", "phoenix", "camaro", "peanut", "morgan", "welcome", "falcon", "cowboy", "ferrari", "samsung",
+	"andrea", "smokey", "steelers", "joseph", "mercedes", "dakota", "arsenal", "eagles", "melissa", "boomer",
+	"booboo", "spider", "nascar", "monster", "tigers", "yellow", "xxxxxx", "123123123", "gateway", "marina",
+	"diablo", "bulldog", "qwer1234", "compaq", "purple", "hardcore", "banana", "junior", "hannah", "123654",
+	"porsche", "lakers", "iceman", "money", "cowboys", "987654", "london", "tennis", "999999", "ncc1701",
+	"coffee", "scooby", "0000", "miller", "boston", "q1w2e3r4", "brandon", "yamaha", "chester", "mother",
+	"forever", "johnny", "edward", "333333", "oliver", "redsox", "player", "nikita", "knight", "fender",
+	"barney", "midnight", "please", "brandy", "chicago", "badboy", "slayer", "rangers", "charles", "angel",
+	"flower", "rabbit", "wizard", "bigdick", "jasper", "enter", "rachel", "chris", "steven", "winner",
+	"adidas", "victoria", "natasha", "1q2w3e4r", "jasmine", "winter", "prince", "panties", "marine", "ghbdtn",
+	"fishing", "cocacola", "casper", "james", "232323", "raiders", "888888", "marlboro", "gandalf", "asdfasdf",
+	"crystal", "87654321", "12344321", "golden", "8675309", "apple", "admin", "welcome1", "qwerty123", "password1",
+}
+
+// commonWords are frequent English words and names, most common first.
+var commonWords = []string{
+	"the", "you", "and", "that", "was", "for", "are", "with", "his", "they",
+	"this", "have", "from", "one", "had", "word", "but", "not", "what", "all",
+	"were", "when", "your", "can", "said", "there", "use", "each", "which", "she",
+	"how", "their", "will", "other", "about", "out", "many", "then", "them", "these",
+	"some", "her", "would", "make", "like", "him", "into", "time", "has", "look",
+	"two", "more", "write", "see", "number", "way", "could", "people", "than", "first",
+	"water", "been", "call", "who", "oil", "its", "now", "find", "long", "down",
+	"day", "did", "get", "come", "made", "may", "part", "over", "new", "sound",
+	"take", "only", "little", "work", "know", "place", "year", "live", "back", "give",
+	"most", "very", "after", "thing", "our", "just", "name", "good", "sentence", "man",
+	"think", "say", "great", "where", "help", "through", "much", "before", "line", "right",
+	"too", "mean", "old", "any", "same", "tell", "boy", "follow", "came", "want",
+	"show", "also", "around", "form", "three", "small", "set", "put", "end", "does",
+	"another", "well", "large", "must", "big", "even", "such", "because", "turn", "here",
+	"why", "ask", "went", "men", "read", "need", "land", "different", "home", "move",
+	"try", "kind", "hand", "picture", "again", "change", "off", "play", "spell", "air",
+	"away", "animal", "house", "point", "page", "letter", "mother", "answer", "found", "study",
+	"still", "learn", "should", "world", "high", "every", "near", "add", "food", "between",
+	"own", "below", "country", "plant", "last", "school", "father", "keep", "tree", "never",
+	"start", "city", "earth", "eye", "light", "thought", "head", "under", "story", "saw",
+	"left", "few", "while", "along", "might", "close", "something", "seem", "next", "hard",
+	"open", "example", "begin", "life", "always", "those", "both", "paper", "together", "got",
+	"group", "often", "run", "important", "until", "children", "side", "feet", "car", "mile",
+	"night", "walk", "white", "sea", "began", "grow", "took", "river", "four", "carry",
+	"state", "once", "book", "hear", "stop", "without", "second", "later", "miss", "idea",
+	"enough", "eat", "face", "watch", "far", "indian", "really", "almost", "let", "above",
+	"girl", "sometimes", "mountain", "cut", "young", "talk", "soon", "list", "song", "being",
+	"leave", "family", "horse", "correct", "battery", "staple", "dog", "cat", "sun", "moon",
+	"star", "love", "happy", "blue", "red", "green", "black", "fire", "king", "queen",
+	"john", "mary", "james", "david", "anna", "maria", "alex", "max", "sam", "kate",
+}
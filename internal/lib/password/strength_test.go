@@ -0,0 +1,188 @@
+package password
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const addWord = "Add another word or two. Uncommon words are better."
+
+func Test_EstimateStrength(t *testing.T) {
+	tests := []struct {
+		password    string
+		userInputs  []string
+		score       int
+		warning     string
+		suggestions []string
+	}{
+		{
+			//Empty password
+			password:    "",
+			score:       0,
+			warning:     "Password is empty",
+			suggestions: defaultSuggestions(),
+		},
+		{
+			//Top-10 common password
+			password:    "password",
+			score:       0,
+			warning:     "This is a top-10 common password",
+			suggestions: []string{addWord},
+		},
+		{
+			//Capitalized common password
+			password:    "Password",
+			score:       0,
+			warning:     "This is a top-10 common password",
+			suggestions: []string{addWord, "Capitalization doesn't help very much"},
+		},
+		{
+			//All-uppercase common password
+			password:    "PASSWORD",
+			score:       0,
+			warning:     "This is a top-10 common password",
+			suggestions: []string{addWord, "All-uppercase is almost as easy to guess as all-lowercase"},
+		},
+		{
+			//Common password with l33t substitutions
+			password:    "p@ssw0rd",
+			score:       0,
+			warning:     "This is a very common password",
+			suggestions: []string{addWord, "Predictable substitutions like '@' instead of 'a' don't help very much"},
+		},
+		{
+			//Reversed common password
+			password:    "drowssap",
+			score:       0,
+			warning:     "This is a very common password",
+			suggestions: []string{addWord, "Reversed words aren't much harder to guess"},
+		},
+		{
+			//Top-100 common password
+			password:    "sunshine",
+			score:       0,
+			warning:     "This is a top-100 common password",
+			suggestions: []string{addWord},
+		},
+		{
+			//Common password with a year
+			password:    "iloveyou1990",
+			score:       1,
+			warning:     "This is similar to a commonly used password",
+			suggestions: []string{addWord},
+		},
+		{
+			//Keyboard row
+			password:    "hjkl;'",
+			score:       1,
+			warning:     "Straight rows of keys are easy to guess",
+			suggestions: []string{addWord, "Use a longer keyboard pattern with more turns"},
+		},
+		{
+			//Repeated character
+			password:    "aaaaaaaa",
+			score:       0,
+			warning:     `Repeats like "aaa" are easy to guess`,
+			suggestions: []string{addWord, "Avoid repeated words and characters"},
+		},
+		{
+			//Repeated sequence
+			password:    "abcabcabc",
+			score:       0,
+			warning:     `Repeats like "abcabcabc" are only slightly harder to guess than "abc"`,
+			suggestions: []string{addWord, "Avoid repeated words and characters"},
+		},
+		{
+			//Alphabet sequence
+			password:    "abcdefgh",
+			score:       0,
+			warning:     "Sequences like abc or 6543 are easy to guess",
+			suggestions: []string{addWord, "Avoid sequences"},
+		},
+		{
+			//Descending digits
+			password:    "9876543210",
+			score:       0,
+			warning:     "Sequences like abc or 6543 are easy to guess",
+			suggestions: []string{addWord, "Avoid sequences"},
+		},
+		{
+			//Recent year
+			password:    "2023",
+			score:       0,
+			warning:     "Recent years are easy to guess",
+			suggestions: []string{addWord, "Avoid recent years", "Avoid years that are associated with you"},
+		},
+		{
+			//User's email
+			password:    "johnsmith",
+			userInputs:  []string{"John Smith", "johnsmith@example.com"},
+			score:       0,
+			warning:     "Avoid using your name or email",
+			suggestions: []string{addWord},
+		},
+		{
+			//Same password without user inputs
+			password:    "johnsmith",
+			score:       2,
+			suggestions: []string{addWord},
+		},
+		{
+			//Two uncommon words
+			password: "mountainbicycle",
+			score:    3,
+		},
+		{
+			//Passphrase
+			password: "correcthorsebatterystaple",
+			score:    4,
+		},
+		{
+			//Random characters
+			password: "kX9#vQ2!mZ7@",
+			score:    4,
+		},
+		{
+			//Long repetition is cut off before estimating
+			password:    strings.Repeat("a", 2*maxEstimatedLen),
+			score:       1,
+			warning:     `Repeats like "aaa" are easy to guess`,
+			suggestions: []string{addWord, "Avoid repeated words and characters"},
+		},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("Test_EstimateStrength №%d", i), func(t *testing.T) {
+			strength := EstimateStrength(test.password, test.userInputs...)
+			assert.Equal(t, test.score, strength.Score)
+			assert.Equal(t, test.score, guessesToScore(strength.Guesses))
+			assert.Equal(t, test.warning, strength.Warning)
+			assert.Equal(t, test.suggestions, strength.Suggestions)
+		})
+	}
+}
+
+func Test_GuessesToScore(t *testing.T) {
+	tests := []struct {
+		guesses float64
+		score   int
+	}{
+		{guesses: 0, score: 0},
+		{guesses: 1e3 + 4, score: 0},
+		{guesses: 1e3 + 5, score: 1},
+		{guesses: 1e6 + 4, score: 1},
+		{guesses: 1e6 + 5, score: 2},
+		{guesses: 1e8 + 4, score: 2},
+		{guesses: 1e8 + 5, score: 3},
+		{guesses: 1e10 + 4, score: 3},
+		{guesses: 1e10 + 5, score: 4},
+		{guesses: 1e20, score: 4},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("Test_GuessesToScore №%d", i), func(t *testing.T) {
+			assert.Equal(t, test.score, guessesToScore(test.guesses))
+		})
+	}
+}
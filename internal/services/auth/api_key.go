@@ -0,0 +1,278 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/DavidG9999/my_grpc_app/internal/domain/models"
+	"github.com/DavidG9999/my_grpc_app/internal/lib/jwt"
+	"github.com/DavidG9999/my_grpc_app/internal/storage"
+)
+
+const (
+	// apiKeyPrefix makes keys easy to recognize, e.g. by secret scanners.
+	apiKeyPrefix = "sso_"
+	apiKeyLen    = 32
+)
+
+// CreateAPIKey creates a key for the actor to get tokens to the app with,
+// limited to the scopes or, if there are none, to all scopes of the app. A
+// zero ttl creates a key that does not expire. The key is returned only here.
+// Tokens of API keys, impersonations and exchanges cannot create keys.
+func (a *Auth) CreateAPIKey(
+	ctx context.Context,
+	actor jwt.Claims,
+	appID int,
+	name string,
+	scopes []string,
+	ttl time.Duration,
+) (string, models.APIKey, error) {
+	const op = "auth.CreateAPIKey"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int64("actor_id", actor.UserID),
+		slog.Int("app_id", appID),
+	)
+
+	if actor.APIKeyID != 0 || actor.Actor != nil {
+		log.Warn("delegated tokens cannot create api keys")
+
+		return "", models.APIKey{}, fmt.Errorf("%s: %w", op, ErrPermissionDenied)
+	}
+
+	user, err := a.authSrv.UserByID(ctx, actor.UserID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return "", models.APIKey{}, fmt.Errorf("%s: %w", op, ErrPermissionDenied)
+		}
+		return "", models.APIKey{}, fmt.Errorf("%s: %w", op, err)
+	}
+	app, err := a.authSrv.App(ctx, appID)
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			return "", models.APIKey{}, fmt.Errorf("%s: %w", op, ErrInvalidAppID)
+		}
+		return "", models.APIKey{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if app.OrgID != user.OrgID {
+		log.Warn("app is in another organization")
+
+		return "", models.APIKey{}, fmt.Errorf("%s: %w", op, ErrInvalidAppID)
+	}
+	if err := a.checkAppAccess(ctx, app, user.ID); err != nil {
+		log.Warn("user is not allowed to use app")
+
+		return "", models.APIKey{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	allowed, err := a.authSrv.AppScopes(ctx, app.ID)
+	if err != nil {
+		return "", models.APIKey{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if len(scopes) == 0 {
+		scopes = allowed
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(allowed, scope) {
+			log.Warn("scope is not allowed", slog.String("scope", scope))
+
+			return "", models.APIKey{}, fmt.Errorf("%s: %w", op, ErrInvalidScope)
+		}
+	}
+
+	secret, err := randomAPIKey()
+	if err != nil {
+		return "", models.APIKey{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	now := time.Now().UTC()
+	key := models.APIKey{
+		UserID:    user.ID,
+		AppID:     app.ID,
+		Name:      name,
+		KeyHash:   hashAPIKey(secret),
+		Scope:     strings.Join(scopes, " "),
+		CreatedAt: now,
+	}
+	if ttl > 0 {
+		expiresAt := now.Add(ttl)
+		key.ExpiresAt = &expiresAt
+	}
+
+	key.ID, err = a.authSrv.SaveAPIKey(ctx, key)
+	if err != nil {
+		log.Error("failed to save api key")
+
+		return "", models.APIKey{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("api key created", slog.Int64("api_key_id", key.ID))
+	return secret, key, nil
+}
+
+// ListAPIKeys returns the keys of the user that are not revoked.
+func (a *Auth) ListAPIKeys(ctx context.Context, actorID int64, userID int64) ([]models.APIKey, error) {
+	const op = "auth.ListAPIKeys"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int64("actor_id", actorID),
+		slog.Int64("user_id", userID),
+	)
+
+	if err := a.authorizeUser(ctx, actorID, userID); err != nil {
+		log.Warn("not allowed to list api keys")
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	keys, err := a.authSrv.UserAPIKeys(ctx, userID)
+	if err != nil {
+		log.Error("failed to get api keys")
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return keys, nil
+}
+
+func (a *Auth) RevokeAPIKey(ctx context.Context, actorID int64, keyID int64) error {
+	const op = "auth.RevokeAPIKey"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int64("actor_id", actorID),
+		slog.Int64("api_key_id", keyID),
+	)
+
+	key, err := a.authSrv.APIKeyByID(ctx, keyID)
+	if err != nil {
+		if errors.Is(err, storage.ErrAPIKeyNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrAPIKeyNotFound)
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := a.authorizeUser(ctx, actorID, key.UserID); err != nil {
+		log.Warn("not allowed to revoke api key")
+
+		// Do not reveal that somebody else's key exists.
+		if errors.Is(err, ErrPermissionDenied) {
+			return fmt.Errorf("%s: %w", op, ErrAPIKeyNotFound)
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.authSrv.RevokeAPIKey(ctx, keyID); err != nil {
+		log.Error("failed to revoke api key")
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("api key revoked")
+	return nil
+}
+
+// ExchangeAPIKey issues a short-lived token for the app of the key. Requested
+// scopes can only narrow the scopes of the key.
+func (a *Auth) ExchangeAPIKey(ctx context.Context, secret string, scopes []string) (ScopedToken, error) {
+	const op = "auth.ExchangeAPIKey"
+
+	log := a.log.With(slog.String("op", op))
+
+	key, err := a.authSrv.APIKeyByHash(ctx, hashAPIKey(secret))
+	if err != nil {
+		if errors.Is(err, storage.ErrAPIKeyNotFound) {
+			log.Warn("api key not found")
+
+			return ScopedToken{}, fmt.Errorf("%s: %w", op, ErrInvalidAPIKey)
+		}
+		return ScopedToken{}, fmt.Errorf("%s: %w", op, err)
+	}
+	log = log.With(slog.Int64("api_key_id", key.ID))
+
+	now := time.Now()
+	if key.RevokedAt != nil || (key.ExpiresAt != nil && !now.Before(*key.ExpiresAt)) {
+		log.Warn("api key is revoked or expired")
+
+		return ScopedToken{}, fmt.Errorf("%s: %w", op, ErrInvalidAPIKey)
+	}
+
+	allowed := strings.Fields(key.Scope)
+	if len(scopes) == 0 {
+		scopes = allowed
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(allowed, scope) {
+			log.Warn("scope is not allowed", slog.String("scope", scope))
+
+			return ScopedToken{}, fmt.Errorf("%s: %w", op, ErrInvalidScope)
+		}
+	}
+
+	user, err := a.authSrv.UserByID(ctx, key.UserID)
+	if err == nil && !user.Active(now) {
+		err = storage.ErrUserNotFound
+	}
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return ScopedToken{}, fmt.Errorf("%s: %w", op, ErrInvalidAPIKey)
+		}
+		return ScopedToken{}, fmt.Errorf("%s: %w", op, err)
+	}
+	app, err := a.authSrv.App(ctx, key.AppID)
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			return ScopedToken{}, fmt.Errorf("%s: %w", op, ErrInvalidAPIKey)
+		}
+		return ScopedToken{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := a.checkAppAccess(ctx, app, user.ID); err != nil {
+		log.Warn("user is no longer allowed to use app")
+
+		return ScopedToken{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	groups, err := a.tokenGroups(ctx, app, user.ID)
+	if err != nil {
+		return ScopedToken{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	token, err := jwt.NewAPIKeyToken(user, app, a.tokenSecret, key.ID, scopes, groups, a.tokenTTL)
+	if err != nil {
+		log.Error("failed to generate token")
+
+		return ScopedToken{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.authSrv.TouchAPIKey(ctx, key.ID, now.UTC()); err != nil {
+		log.Warn("failed to touch api key", slog.String("error", err.Error()))
+	}
+
+	log.Info("api key exchanged", slog.Int64("user_id", user.ID))
+	return ScopedToken{
+		Token:     token,
+		Scopes:    scopes,
+		ExpiresIn: a.tokenTTL,
+	}, nil
+}
+
+func randomAPIKey() (string, error) {
+	b := make([]byte, apiKeyLen)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return apiKeyPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashAPIKey(key string) []byte {
+	sum := sha256.Sum256([]byte(key))
+	return sum[:]
+}
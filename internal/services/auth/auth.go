@@ -2,58 +2,198 @@ package auth
 
 import (
 	"context"
+	"crypto/subtle"
 	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
+	"strconv"
 	"time"
 
 	"github.com/DavidG9999/my_grpc_app/internal/domain/models"
 	"github.com/DavidG9999/my_grpc_app/internal/lib/jwt"
+	"github.com/DavidG9999/my_grpc_app/internal/lib/mail"
+	"github.com/DavidG9999/my_grpc_app/internal/lib/password"
 	"github.com/DavidG9999/my_grpc_app/internal/storage"
-	"golang.org/x/crypto/bcrypt"
 )
 
 type Auth struct {
-	log      *slog.Logger
-	authSrv  AuthService
-	tokenTTL time.Duration
+	log              *slog.Logger
+	authSrv          AuthService
+	tokenSecret      []byte
+	tokenTTL         time.Duration
+	impersonationTTL time.Duration
+	changeTTL        time.Duration
+	verificationTTL  time.Duration
+	bootstrapToken   string
+	passwords        password.Policies
+	hasher           *password.Hasher
+	breached         password.BreachedList
+	mailer           mail.Sender
 }
 
 type UserSaver interface {
-	SaveUser(ctx context.Context, name string, email string, passwordHash []byte, isAdmin bool) (uid int64, err error)
+	SaveUser(ctx context.Context, orgID int64, name string, email string, passwordHash []byte, isAdmin bool) (uid int64, err error)
+	SaveFirstAdmin(ctx context.Context, orgID int64, name string, email string, passwordHash []byte) (uid int64, err error)
+	UpdatePasswordHash(ctx context.Context, userID int64, passwordHash []byte) error
+	UpdateUser(ctx context.Context, userID int64, name string, email string) error
+	SetOrgAdmin(ctx context.Context, userID int64, isOrgAdmin bool) error
+	SetAdmin(ctx context.Context, userID int64, isAdmin bool) error
+	SetUserStatus(ctx context.Context, userID int64, status models.UserStatus, reason string, suspendedUntil *time.Time) error
 }
 
 type UserProvider interface {
-	User(ctx context.Context, email string) (models.User, error)
+	User(ctx context.Context, orgID int64, email string) (models.User, error)
+	UserByID(ctx context.Context, userID int64) (models.User, error)
+	ListUsers(ctx context.Context, query models.UserListQuery) ([]models.User, error)
+	SearchUsers(ctx context.Context, orgID int64, query string, limit int) ([]models.UserMatch, error)
 	IsAdmin(ctx context.Context, userID int64) (bool, error)
 }
 
 type AppProvider interface {
 	App(ctx context.Context, appID int) (models.App, error)
+	UpdateAppSecret(ctx context.Context, appID int, secret string) error
+	DeleteApp(ctx context.Context, appID int) error
+	OrgAppIDs(ctx context.Context, orgID int64) ([]int, error)
+	AppScopes(ctx context.Context, appID int) ([]string, error)
+	ExchangeScopes(ctx context.Context, sourceAppID int, targetAppID int) ([]string, error)
+	AddAppMember(ctx context.Context, appID int, userID int64) error
+	RemoveAppMember(ctx context.Context, appID int, userID int64) error
+	IsAppMember(ctx context.Context, appID int, userID int64) (bool, error)
+}
+
+type OrganizationStorage interface {
+	SaveOrganization(ctx context.Context, name string) (int64, error)
+	OrganizationByID(ctx context.Context, orgID int64) (models.Organization, error)
+}
+
+type GroupStorage interface {
+	SaveGroup(ctx context.Context, orgID int64, name string) (int64, error)
+	GroupByID(ctx context.Context, groupID int64) (models.Group, error)
+	AddGroupUser(ctx context.Context, groupID int64, userID int64) error
+	AddGroupChild(ctx context.Context, groupID int64, childID int64) error
+	GroupUsers(ctx context.Context, groupID int64, effective bool) ([]models.User, error)
+	GroupChildren(ctx context.Context, groupID int64, effective bool) ([]models.Group, error)
+	UserGroups(ctx context.Context, userID int64, effective bool) ([]models.Group, error)
+}
+
+type APIKeyStorage interface {
+	SaveAPIKey(ctx context.Context, key models.APIKey) (int64, error)
+	APIKeyByID(ctx context.Context, keyID int64) (models.APIKey, error)
+	APIKeyByHash(ctx context.Context, keyHash []byte) (models.APIKey, error)
+	UserAPIKeys(ctx context.Context, userID int64) ([]models.APIKey, error)
+	TouchAPIKey(ctx context.Context, keyID int64, lastUsedAt time.Time) error
+	RevokeAPIKey(ctx context.Context, keyID int64) error
+}
+
+type AuditStorage interface {
+	SaveAuditEvent(ctx context.Context, event models.AuditEvent) (int64, error)
+}
+
+type ChangeStorage interface {
+	SaveChange(ctx context.Context, change models.PendingChange) (int64, error)
+	ChangeByID(ctx context.Context, changeID int64) (models.PendingChange, error)
+	DecideChange(ctx context.Context, changeID int64, status models.ChangeStatus, deciderID int64, decidedAt time.Time) error
+	ReopenChange(ctx context.Context, changeID int64) error
+	ExpireChanges(ctx context.Context, now time.Time) (int64, error)
+}
+
+type EmailVerificationStorage interface {
+	SaveEmailVerification(ctx context.Context, verification models.EmailVerification) (int64, error)
+	UseEmailVerification(ctx context.Context, tokenHash []byte) (models.EmailVerification, error)
+	VerifyEmail(ctx context.Context, userID int64, email string) error
 }
 
 type AuthService interface {
 	UserSaver
 	UserProvider
 	AppProvider
+	SessionStorage
+	OrganizationStorage
+	GroupStorage
+	APIKeyStorage
+	AuditStorage
+	ChangeStorage
+	EmailVerificationStorage
 }
 
 var (
-	ErrInvalidCredentials = errors.New("invalid credentials")
-	ErrInvalidAppID       = errors.New("invalid app ID")
-	ErrUserExist          = errors.New("user already exist")
-	ErrUserNotFound       = errors.New("user not found")
+	ErrInvalidCredentials   = errors.New("invalid credentials")
+	ErrInvalidAppID         = errors.New("invalid app ID")
+	ErrUserExist            = errors.New("user already exist")
+	ErrUserNotFound         = errors.New("user not found")
+	ErrInvalidAppSecret     = errors.New("invalid app secret")
+	ErrInvalidScope         = errors.New("invalid scope")
+	ErrInvalidToken         = errors.New("invalid token")
+	ErrExchangeNotAllowed   = errors.New("token exchange not allowed")
+	ErrSessionNotFound      = errors.New("session not found")
+	ErrPermissionDenied     = errors.New("permission denied")
+	ErrNotAppMember         = errors.New("user is not a member of the app")
+	ErrOrganizationExists   = errors.New("organization already exists")
+	ErrOrganizationNotFound = errors.New("organization not found")
+	ErrGroupExists          = errors.New("group already exists")
+	ErrGroupNotFound        = errors.New("group not found")
+	ErrGroupCycle           = errors.New("group would contain itself")
+	ErrAPIKeyNotFound       = errors.New("api key not found")
+	ErrInvalidAPIKey        = errors.New("invalid api key")
+	ErrLastAdmin            = errors.New("cannot demote the last admin")
+	ErrApprovalRequired     = errors.New("change requires approval")
+	ErrChangeNotFound       = errors.New("change not found")
+	ErrChangeDecided        = errors.New("change already decided")
+	ErrChangeExpired        = errors.New("change expired")
+	ErrSelfApproval         = errors.New("change cannot be approved by its proposer")
+	ErrUserDisabled         = errors.New("user is disabled")
+	ErrUserSuspended        = errors.New("user is suspended")
+	ErrUserDeleted          = errors.New("user is deleted")
+	ErrUserIsAdmin          = errors.New("user is an admin")
+	ErrInvalidEmail         = errors.New("invalid email")
+	ErrEmailVerified        = errors.New("email is already verified")
+	ErrInvalidVerification  = errors.New("invalid email verification token")
 )
 
-func NewAuth(log *slog.Logger, authSrv AuthService, tokenTTL time.Duration) *Auth {
+// ScopedToken is a token issued with an explicit set of scopes.
+type ScopedToken struct {
+	Token     string
+	Scopes    []string
+	ExpiresIn time.Duration
+}
+
+// NewAuth creates the service. breached may be nil to skip checking passwords
+// against data breaches. bootstrapToken lets the first admin sign up, it may
+// be empty if admins are created otherwise. Proposed changes that are not
+// approved within changeTTL expire, mailed email verification tokens within
+// verificationTTL.
+func NewAuth(
+	log *slog.Logger,
+	authSrv AuthService,
+	tokenSecret string,
+	tokenTTL time.Duration,
+	impersonationTTL time.Duration,
+	changeTTL time.Duration,
+	verificationTTL time.Duration,
+	bootstrapToken string,
+	passwords password.Policies,
+	hasher *password.Hasher,
+	breached password.BreachedList,
+	mailer mail.Sender,
+) *Auth {
 	return &Auth{
-		log:      log,
-		authSrv:  authSrv,
-		tokenTTL: tokenTTL,
+		log:              log,
+		authSrv:          authSrv,
+		tokenSecret:      []byte(tokenSecret),
+		tokenTTL:         tokenTTL,
+		impersonationTTL: impersonationTTL,
+		changeTTL:        changeTTL,
+		verificationTTL:  verificationTTL,
+		bootstrapToken:   bootstrapToken,
+		passwords:        passwords,
+		hasher:           hasher,
+		breached:         breached,
+		mailer:           mailer,
 	}
 }
 
-func (a *Auth) SignIn(ctx context.Context, email string, password string, appId int) (string, error) {
+func (a *Auth) SignIn(ctx context.Context, email string, password string, appId int, client models.ClientInfo) (string, error) {
 	const op = "auth.SignIn"
 
 	log := a.log.With(
@@ -62,36 +202,41 @@ func (a *Auth) SignIn(ctx context.Context, email string, password string, appId
 
 	log.Info("logining user")
 
-	user, err := a.authSrv.User(ctx, email)
+	app, err := a.authSrv.App(ctx, appId)
 	if err != nil {
-		if errors.Is(err, storage.ErrUserNotFound) {
-			a.log.Warn("user not found")
+		if errors.Is(err, storage.ErrAppNotFound) {
+			a.log.Warn("app not found")
 
-			return "", fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+			return "", fmt.Errorf("%s: %w", op, ErrInvalidAppID)
 		}
-		a.log.Error("failed to get user")
+		a.log.Error("failed to get app")
 
 		return "", fmt.Errorf("%s: %w", op, err)
 	}
-	if err := bcrypt.CompareHashAndPassword(user.PasswordHash, []byte(password)); err != nil {
-		a.log.Info("invalid credentials")
+	user, err := a.Authenticate(ctx, app.OrgID, email, password)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	if err := a.checkAppAccess(ctx, app, user.ID); err != nil {
+		a.log.Warn("user is not allowed to sign in to app", slog.Int("app_id", app.ID))
 
-		return "", fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+		return "", fmt.Errorf("%s: %w", op, err)
 	}
-	app, err := a.authSrv.App(ctx, appId)
+	sessionID, err := a.openSession(ctx, user, app, client)
 	if err != nil {
-		if errors.Is(err, storage.ErrAppNotFound) {
-			a.log.Warn("app not found")
-
-			return "", fmt.Errorf("%s: %w", op, ErrInvalidAppID)
-		}
-		a.log.Error("failed to get app")
+		a.log.Error("failed to open session")
 
 		return "", fmt.Errorf("%s: %w", op, err)
 	}
-	log.Info("user logged in sucessfully")
+	log.Info("user logged in sucessfully", slog.Int64("session_id", sessionID))
+
+	groups, err := a.tokenGroups(ctx, app, user.ID)
+	if err != nil {
+		a.log.Error("failed to get user groups")
 
-	token, err := jwt.NewToken(user, app, a.tokenTTL)
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	token, err := jwt.NewToken(user, app, a.tokenSecret, sessionID, groups, a.tokenTTL)
 	if err != nil {
 		a.log.Error("failed to generate token")
 
@@ -101,7 +246,112 @@ func (a *Auth) SignIn(ctx context.Context, email string, password string, appId
 	return token, nil
 }
 
-func (a *Auth) SighUp(ctx context.Context, name string, email string, password string, isAdmin bool) (id int64, err error) {
+// Authenticate checks the credentials of a user of the organization without
+// issuing a token. A hash made with an outdated algorithm or parameters is
+// replaced on success.
+func (a *Auth) Authenticate(ctx context.Context, orgID int64, email string, password string) (models.User, error) {
+	const op = "auth.Authenticate"
+
+	user, err := a.authSrv.User(ctx, orgID, email)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			a.log.Warn("user not found")
+
+			return models.User{}, fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+		}
+		a.log.Error("failed to get user")
+
+		return models.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+	rehash, err := a.hasher.Verify(user.PasswordHash, password)
+	if err != nil {
+		a.log.Info("invalid credentials")
+
+		return models.User{}, fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+	}
+	// The status is only told to those who know the password.
+	if err := statusError(user, time.Now()); err != nil {
+		a.log.Info("user is not active", slog.String("status", string(user.Status)))
+
+		return models.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if rehash {
+		a.rehash(ctx, user.ID, password)
+	}
+	return user, nil
+}
+
+// validatePassword checks a new password against the policy and the breached
+// passwords, reporting all violations in a *password.PolicyError.
+func (a *Auth) validatePassword(policy password.Policy, newPassword string, name string, email string) error {
+	policyErr := &password.PolicyError{}
+
+	err := policy.Validate(newPassword, name, email)
+	if err != nil && !errors.As(err, &policyErr) {
+		return err
+	}
+
+	if a.breached != nil {
+		breached, err := a.breached.Contains(newPassword)
+		if err != nil {
+			return fmt.Errorf("failed to check breached passwords: %w", err)
+		}
+		if breached {
+			policyErr.Violations = append(policyErr.Violations, password.Violation{
+				Field:       "password",
+				Description: "has appeared in a data breach",
+			})
+		}
+	}
+
+	if len(policyErr.Violations) > 0 {
+		return policyErr
+	}
+	return nil
+}
+
+// rehash upgrades the user's password hash. The sign in goes on if it fails,
+// the hash is then upgraded next time.
+func (a *Auth) rehash(ctx context.Context, userID int64, password string) {
+	const op = "auth.rehash"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int64("user_id", userID),
+	)
+
+	passHash, err := a.hasher.Hash(password)
+	if err != nil {
+		log.Error("failed to generate password hash", slog.String("error", err.Error()))
+
+		return
+	}
+	if err := a.authSrv.UpdatePasswordHash(ctx, userID, passHash); err != nil {
+		log.Error("failed to update password hash", slog.String("error", err.Error()))
+
+		return
+	}
+	log.Info("password hash upgraded")
+}
+
+// SighUp registers a user whose password satisfies the policy of the app,
+// or the default one when appID is zero. The user joins the organization of
+// the app, or the default one. A *password.PolicyError lists every rule the
+// password breaks. Admins are only created with the bootstrap token while
+// there is no admin yet; global admins, given by actorID, promote users
+// through approved changes instead. Other sign-ups asking for an admin create
+// a regular user and are audited.
+func (a *Auth) SighUp(
+	ctx context.Context,
+	actorID int64,
+	name string,
+	email string,
+	password string,
+	isAdmin bool,
+	appID int,
+	bootstrapToken string,
+	client models.ClientInfo,
+) (id int64, admin bool, err error) {
 	const op = "auth.SignUp"
 
 	log := a.log.With(
@@ -109,36 +359,126 @@ func (a *Auth) SighUp(ctx context.Context, name string, email string, password s
 	)
 	log.Info("registering user")
 
-	passHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	orgID := models.DefaultOrgID
+	if appID != 0 {
+		app, err := a.authSrv.App(ctx, appID)
+		if err != nil {
+			if errors.Is(err, storage.ErrAppNotFound) {
+				log.Warn("app not found")
+
+				return 0, false, fmt.Errorf("%s: %w", op, ErrInvalidAppID)
+			}
+			return 0, false, fmt.Errorf("%s: %w", op, err)
+		}
+		orgID = app.OrgID
+	}
+
+	if err := a.validatePassword(a.passwords.For(appID), password, name, email); err != nil {
+		log.Info("password does not satisfy policy")
+
+		return 0, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var denied string
+	if isAdmin {
+		denied, err = a.adminSignUpDenial(ctx, actorID, bootstrapToken)
+		if err != nil {
+			return 0, false, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+	admin = isAdmin && denied == ""
+
+	passHash, err := a.hasher.Hash(password)
 	if err != nil {
 		log.Error("failed to generate password hash")
-		return 0, fmt.Errorf("%s: %w", op, err)
+		return 0, false, fmt.Errorf("%s: %w", op, err)
+	}
+	if admin {
+		// The bootstrap token only creates the first admin.
+		id, err = a.authSrv.SaveFirstAdmin(ctx, orgID, name, email, passHash)
+		if errors.Is(err, storage.ErrAdminExists) {
+			denied = "bootstrap token already used"
+			admin = false
+		}
+	}
+	if !admin {
+		id, err = a.authSrv.SaveUser(ctx, orgID, name, email, passHash, false)
 	}
-	id, err = a.authSrv.SaveUser(ctx, name, email, passHash, isAdmin)
 	if err != nil {
 		if errors.Is(err, storage.ErrUserExists) {
 			a.log.Warn("user already exist")
 
-			return 0, fmt.Errorf("%s: %w", op, ErrUserExist)
+			return 0, false, fmt.Errorf("%s: %w", op, ErrUserExist)
 		}
 		log.Error("failed to save user")
 
-		return 0, fmt.Errorf("%s: %w", op, err)
+		return 0, false, fmt.Errorf("%s: %w", op, err)
 	}
 
-	log.Info("user registered")
-	return id, nil
+	if isAdmin {
+		event := models.AuditEvent{
+			ActorID:      actorID,
+			Action:       models.AuditAdminSignUp,
+			TargetUserID: id,
+			AppID:        appID,
+			Reason:       denied,
+			IP:           client.IP,
+			CreatedAt:    time.Now().UTC(),
+		}
+		if !admin {
+			event.Action = models.AuditAdminSignUpDenied
+			log.Warn("admin sign-up denied", slog.String("reason", denied))
+		}
+		if _, err := a.authSrv.SaveAuditEvent(ctx, event); err != nil {
+			log.Error("failed to audit admin sign-up", slog.String("error", err.Error()))
+		}
+	}
+
+	log.Info("user registered", slog.Bool("is_admin", admin))
+	return id, admin, nil
+}
+
+// adminSignUpDenial returns why a sign-up may not create an admin, or an
+// empty string if it may, provided there is no admin yet.
+func (a *Auth) adminSignUpDenial(ctx context.Context, actorID int64, bootstrapToken string) (string, error) {
+	if actorID != 0 {
+		isAdmin, err := a.authSrv.IsAdmin(ctx, actorID)
+		if err != nil && !errors.Is(err, storage.ErrUserNotFound) {
+			return "", err
+		}
+		if isAdmin {
+			return "admins are promoted through approved changes", nil
+		}
+		return "caller is not a global admin", nil
+	}
+	if bootstrapToken == "" {
+		return "caller is not authenticated", nil
+	}
+	if a.bootstrapToken == "" ||
+		subtle.ConstantTimeCompare([]byte(bootstrapToken), []byte(a.bootstrapToken)) != 1 {
+		return "invalid bootstrap token", nil
+	}
+	return "", nil
 }
 
-func (a *Auth) IsAdmin(ctx context.Context, userId int64) (isAdmin bool, err error) {
+// IsAdmin reports whether the user is a global admin. Users may only check
+// themselves, admins the users they administer.
+func (a *Auth) IsAdmin(ctx context.Context, actorID int64, userId int64) (isAdmin bool, err error) {
 	const op = "auth.IsAdmin"
 
 	log := a.log.With(
 		slog.String("op", op),
+		slog.Int64("actor_id", actorID),
 		slog.Int64("user_id", userId),
 	)
 	log.Info("cheking if user is admin")
 
+	if err := a.authorizeUser(ctx, actorID, userId); err != nil {
+		log.Warn("not allowed to check user")
+
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
 	isAdmin, err = a.authSrv.IsAdmin(ctx, userId)
 	if err != nil {
 		if errors.Is(err, storage.ErrUserNotFound) {
@@ -151,3 +491,183 @@ func (a *Auth) IsAdmin(ctx context.Context, userId int64) (isAdmin bool, err err
 	log.Info("checked if user is admin", slog.Bool("is_admin", isAdmin))
 	return isAdmin, nil
 }
+
+// ClientCredentials issues a token to an app authenticated by its secret. When
+// no scopes are requested, all scopes registered for the app are granted.
+func (a *Auth) ClientCredentials(ctx context.Context, appID int, appSecret string, scopes []string) (ScopedToken, error) {
+	const op = "auth.ClientCredentials"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int("app_id", appID),
+	)
+	log.Info("issuing app token")
+
+	app, err := a.authSrv.App(ctx, appID)
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			log.Warn("app not found")
+
+			return ScopedToken{}, fmt.Errorf("%s: %w", op, ErrInvalidAppID)
+		}
+		log.Error("failed to get app")
+
+		return ScopedToken{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if subtle.ConstantTimeCompare([]byte(appSecret), []byte(app.Secret)) != 1 {
+		log.Warn("invalid app secret")
+
+		return ScopedToken{}, fmt.Errorf("%s: %w", op, ErrInvalidAppSecret)
+	}
+
+	allowed, err := a.authSrv.AppScopes(ctx, appID)
+	if err != nil {
+		log.Error("failed to get app scopes")
+
+		return ScopedToken{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if len(scopes) == 0 {
+		scopes = allowed
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(allowed, scope) {
+			log.Warn("scope is not allowed", slog.String("scope", scope))
+
+			return ScopedToken{}, fmt.Errorf("%s: %w", op, ErrInvalidScope)
+		}
+	}
+
+	token, err := jwt.NewAppToken(app, scopes, a.tokenTTL)
+	if err != nil {
+		log.Error("failed to generate token")
+
+		return ScopedToken{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("app token issued")
+	return ScopedToken{
+		Token:     token,
+		Scopes:    scopes,
+		ExpiresIn: a.tokenTTL,
+	}, nil
+}
+
+// ExchangeToken issues a token for targetAppID on behalf of the user of the
+// subject token. The actor must be the app the subject token was issued to,
+// and the exchange must be allowed by a policy from the actor to the target.
+// Requested scopes can only narrow what the policy and the subject token allow.
+func (a *Auth) ExchangeToken(ctx context.Context, subjectToken string, actorAppID int, actorSecret string, targetAppID int, scopes []string) (ScopedToken, error) {
+	const op = "auth.ExchangeToken"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int("actor_app_id", actorAppID),
+		slog.Int("target_app_id", targetAppID),
+	)
+	log.Info("exchanging token")
+
+	actor, err := a.authSrv.App(ctx, actorAppID)
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			log.Warn("actor app not found")
+
+			return ScopedToken{}, fmt.Errorf("%s: %w", op, ErrInvalidAppID)
+		}
+		return ScopedToken{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if subtle.ConstantTimeCompare([]byte(actorSecret), []byte(actor.Secret)) != 1 {
+		log.Warn("invalid actor secret")
+
+		return ScopedToken{}, fmt.Errorf("%s: %w", op, ErrInvalidAppSecret)
+	}
+
+	subject, err := a.ValidateToken(ctx, subjectToken)
+	if err != nil || subject.AppID != actor.ID {
+		log.Warn("invalid subject token")
+
+		return ScopedToken{}, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+
+	target, err := a.authSrv.App(ctx, targetAppID)
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			log.Warn("target app not found")
+
+			return ScopedToken{}, fmt.Errorf("%s: %w", op, ErrInvalidAppID)
+		}
+		return ScopedToken{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	allowed, err := a.authSrv.ExchangeScopes(ctx, actor.ID, target.ID)
+	if err != nil {
+		return ScopedToken{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if len(allowed) == 0 {
+		log.Warn("no exchange policy")
+
+		return ScopedToken{}, fmt.Errorf("%s: %w", op, ErrExchangeNotAllowed)
+	}
+	if len(subject.Scopes) > 0 {
+		allowed = slices.DeleteFunc(allowed, func(scope string) bool {
+			return !slices.Contains(subject.Scopes, scope)
+		})
+	}
+	if len(scopes) == 0 {
+		scopes = allowed
+	}
+	if len(scopes) == 0 {
+		return ScopedToken{}, fmt.Errorf("%s: %w", op, ErrInvalidScope)
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(allowed, scope) {
+			log.Warn("scope is not allowed", slog.String("scope", scope))
+
+			return ScopedToken{}, fmt.Errorf("%s: %w", op, ErrInvalidScope)
+		}
+	}
+
+	user, err := a.authSrv.UserByID(ctx, subject.UserID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return ScopedToken{}, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+		}
+		return ScopedToken{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if target.OrgID != user.OrgID {
+		log.Warn("target app is in another organization")
+
+		return ScopedToken{}, fmt.Errorf("%s: %w", op, ErrExchangeNotAllowed)
+	}
+	if err := a.checkAppAccess(ctx, target, user.ID); err != nil {
+		log.Warn("user is not allowed to use target app")
+
+		return ScopedToken{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	act := map[string]any{
+		"sub":       strconv.Itoa(actor.ID),
+		"client_id": strconv.Itoa(actor.ID),
+	}
+	if subject.Actor != nil {
+		act["act"] = subject.Actor
+	}
+
+	groups, err := a.tokenGroups(ctx, target, user.ID)
+	if err != nil {
+		return ScopedToken{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	token, err := jwt.NewDelegatedToken(user, target, a.tokenSecret, subject.SessionID, scopes, groups, act, a.tokenTTL)
+	if err != nil {
+		log.Error("failed to generate token")
+
+		return ScopedToken{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("token exchanged", slog.Int64("user_id", user.ID))
+	return ScopedToken{
+		Token:     token,
+		Scopes:    scopes,
+		ExpiresIn: a.tokenTTL,
+	}, nil
+}
@@ -0,0 +1,321 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/DavidG9999/my_grpc_app/internal/domain/models"
+	"github.com/DavidG9999/my_grpc_app/internal/lib/jwt"
+	"github.com/DavidG9999/my_grpc_app/internal/storage"
+)
+
+const appSecretLen = 32
+
+// ChangeResult is the outcome of an approved change. AppSecret is the new
+// secret of the app for ChangeRotateAppSecret, it is not shown again.
+type ChangeResult struct {
+	Change    models.PendingChange
+	AppSecret string
+}
+
+// ProposeChange queues a privileged change until another global admin
+// approves or rejects it. Changes that are not decided in time expire.
+func (a *Auth) ProposeChange(
+	ctx context.Context,
+	actor jwt.Claims,
+	kind models.ChangeKind,
+	userID int64,
+	appID int,
+	reason string,
+	client models.ClientInfo,
+) (models.PendingChange, error) {
+	const op = "auth.ProposeChange"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int64("actor_id", actor.UserID),
+		slog.String("kind", string(kind)),
+	)
+
+	if err := a.requireGlobalAdmin(ctx, actor); err != nil {
+		log.Warn("not allowed to propose change")
+
+		return models.PendingChange{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	change := models.PendingChange{
+		Kind:       kind,
+		ProposerID: actor.UserID,
+		Reason:     reason,
+		Status:     models.ChangePending,
+	}
+	switch kind {
+	case models.ChangePromoteAdmin, models.ChangePromoteOrgAdmin:
+		user, err := a.authSrv.UserByID(ctx, userID)
+		if err != nil {
+			if errors.Is(err, storage.ErrUserNotFound) {
+				return models.PendingChange{}, fmt.Errorf("%s: %w", op, ErrUserNotFound)
+			}
+			return models.PendingChange{}, fmt.Errorf("%s: %w", op, err)
+		}
+		if kind == models.ChangePromoteOrgAdmin && user.IsAdmin {
+			return models.PendingChange{}, fmt.Errorf("%s: %w", op, ErrUserIsAdmin)
+		}
+		change.TargetUserID = userID
+	case models.ChangeRotateAppSecret, models.ChangeDeleteApp:
+		if _, err := a.authSrv.App(ctx, appID); err != nil {
+			if errors.Is(err, storage.ErrAppNotFound) {
+				return models.PendingChange{}, fmt.Errorf("%s: %w", op, ErrInvalidAppID)
+			}
+			return models.PendingChange{}, fmt.Errorf("%s: %w", op, err)
+		}
+		change.AppID = appID
+	default:
+		return models.PendingChange{}, fmt.Errorf("%s: unknown change kind %q", op, kind)
+	}
+
+	now := time.Now().UTC()
+	if expired, err := a.authSrv.ExpireChanges(ctx, now); err != nil {
+		log.Warn("failed to expire changes", slog.String("error", err.Error()))
+	} else if expired > 0 {
+		log.Info("changes expired", slog.Int64("expired", expired))
+	}
+
+	change.CreatedAt = now
+	change.ExpiresAt = now.Add(a.changeTTL)
+	id, err := a.authSrv.SaveChange(ctx, change)
+	if err != nil {
+		log.Error("failed to save change")
+
+		return models.PendingChange{}, fmt.Errorf("%s: %w", op, err)
+	}
+	change.ID = id
+
+	a.auditChange(ctx, log, actor.UserID, models.AuditProposeChange, change, reason, client)
+
+	log.Info("change proposed", slog.Int64("change_id", id))
+	return change, nil
+}
+
+// ApproveChange carries out a pending change. The proposer cannot approve
+// their own change. No change is carried out unless its approval has been
+// recorded.
+func (a *Auth) ApproveChange(ctx context.Context, actor jwt.Claims, changeID int64, client models.ClientInfo) (ChangeResult, error) {
+	const op = "auth.ApproveChange"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int64("actor_id", actor.UserID),
+		slog.Int64("change_id", changeID),
+	)
+
+	change, err := a.decideChange(ctx, actor, changeID, models.ChangeApproved)
+	if err != nil {
+		log.Warn("failed to approve change", slog.String("error", err.Error()))
+
+		return ChangeResult{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = a.authSrv.SaveAuditEvent(ctx, models.AuditEvent{
+		ActorID:      actor.UserID,
+		Action:       models.AuditApproveChange,
+		TargetUserID: change.TargetUserID,
+		AppID:        change.AppID,
+		ChangeID:     change.ID,
+		Reason:       string(change.Kind),
+		IP:           client.IP,
+		CreatedAt:    time.Now().UTC(),
+	})
+	if err != nil {
+		log.Error("failed to audit approval")
+
+		a.reopenChange(ctx, log, change.ID)
+		return ChangeResult{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	result, err := a.applyChange(ctx, change)
+	if err != nil {
+		log.Error("failed to apply change", slog.String("error", err.Error()))
+
+		a.reopenChange(ctx, log, change.ID)
+		return ChangeResult{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("change approved", slog.String("kind", string(change.Kind)))
+	return result, nil
+}
+
+// RejectChange drops a pending change. Proposers may withdraw their own
+// changes this way.
+func (a *Auth) RejectChange(ctx context.Context, actor jwt.Claims, changeID int64, reason string, client models.ClientInfo) error {
+	const op = "auth.RejectChange"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int64("actor_id", actor.UserID),
+		slog.Int64("change_id", changeID),
+	)
+
+	change, err := a.decideChange(ctx, actor, changeID, models.ChangeRejected)
+	if err != nil {
+		log.Warn("failed to reject change", slog.String("error", err.Error()))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	a.auditChange(ctx, log, actor.UserID, models.AuditRejectChange, change, reason, client)
+
+	log.Info("change rejected")
+	return nil
+}
+
+// decideChange takes the one decision on a pending change. Only the status
+// ChangeRejected may be set by the proposer.
+func (a *Auth) decideChange(ctx context.Context, actor jwt.Claims, changeID int64, status models.ChangeStatus) (models.PendingChange, error) {
+	if err := a.requireGlobalAdmin(ctx, actor); err != nil {
+		return models.PendingChange{}, err
+	}
+
+	change, err := a.authSrv.ChangeByID(ctx, changeID)
+	if err != nil {
+		if errors.Is(err, storage.ErrChangeNotFound) {
+			return models.PendingChange{}, ErrChangeNotFound
+		}
+		return models.PendingChange{}, err
+	}
+	if status == models.ChangeApproved && change.ProposerID == actor.UserID {
+		return models.PendingChange{}, ErrSelfApproval
+	}
+
+	now := time.Now().UTC()
+	if err := a.authSrv.DecideChange(ctx, change.ID, status, actor.UserID, now); err != nil {
+		if !errors.Is(err, storage.ErrChangeDecided) {
+			return models.PendingChange{}, err
+		}
+		if change.Status == models.ChangePending && !now.Before(change.ExpiresAt) {
+			if _, err := a.authSrv.ExpireChanges(ctx, now); err != nil {
+				return models.PendingChange{}, err
+			}
+			return models.PendingChange{}, ErrChangeExpired
+		}
+		return models.PendingChange{}, ErrChangeDecided
+	}
+
+	change.Status = status
+	change.DecidedBy = &actor.UserID
+	change.DecidedAt = &now
+	return change, nil
+}
+
+func (a *Auth) applyChange(ctx context.Context, change models.PendingChange) (ChangeResult, error) {
+	result := ChangeResult{Change: change}
+
+	switch change.Kind {
+	case models.ChangePromoteAdmin:
+		if err := a.authSrv.SetAdmin(ctx, change.TargetUserID, true); err != nil {
+			if errors.Is(err, storage.ErrUserNotFound) {
+				return ChangeResult{}, ErrUserNotFound
+			}
+			return ChangeResult{}, err
+		}
+	case models.ChangePromoteOrgAdmin:
+		user, err := a.authSrv.UserByID(ctx, change.TargetUserID)
+		if err != nil {
+			if errors.Is(err, storage.ErrUserNotFound) {
+				return ChangeResult{}, ErrUserNotFound
+			}
+			return ChangeResult{}, err
+		}
+		if user.IsAdmin {
+			return ChangeResult{}, ErrUserIsAdmin
+		}
+		if err := a.authSrv.SetOrgAdmin(ctx, change.TargetUserID, true); err != nil {
+			return ChangeResult{}, err
+		}
+	case models.ChangeRotateAppSecret:
+		secret, err := randomAppSecret()
+		if err != nil {
+			return ChangeResult{}, err
+		}
+		if err := a.authSrv.UpdateAppSecret(ctx, change.AppID, secret); err != nil {
+			if errors.Is(err, storage.ErrAppNotFound) {
+				return ChangeResult{}, ErrInvalidAppID
+			}
+			return ChangeResult{}, err
+		}
+		result.AppSecret = secret
+	case models.ChangeDeleteApp:
+		if err := a.authSrv.DeleteApp(ctx, change.AppID); err != nil {
+			if errors.Is(err, storage.ErrAppNotFound) {
+				return ChangeResult{}, ErrInvalidAppID
+			}
+			return ChangeResult{}, err
+		}
+	default:
+		return ChangeResult{}, fmt.Errorf("unknown change kind %q", change.Kind)
+	}
+	return result, nil
+}
+
+// reopenChange puts an approved change that was not carried out back in the
+// queue.
+func (a *Auth) reopenChange(ctx context.Context, log *slog.Logger, changeID int64) {
+	if err := a.authSrv.ReopenChange(ctx, changeID); err != nil {
+		log.Error("failed to reopen change", slog.String("error", err.Error()))
+	}
+}
+
+// auditChange records a proposal or a rejection. Neither changes privileges,
+// so a failure is only logged.
+func (a *Auth) auditChange(
+	ctx context.Context,
+	log *slog.Logger,
+	actorID int64,
+	action models.AuditAction,
+	change models.PendingChange,
+	reason string,
+	client models.ClientInfo,
+) {
+	_, err := a.authSrv.SaveAuditEvent(ctx, models.AuditEvent{
+		ActorID:      actorID,
+		Action:       action,
+		TargetUserID: change.TargetUserID,
+		AppID:        change.AppID,
+		ChangeID:     change.ID,
+		Reason:       reason,
+		IP:           client.IP,
+		CreatedAt:    time.Now().UTC(),
+	})
+	if err != nil {
+		log.Error("failed to audit change", slog.String("error", err.Error()))
+	}
+}
+
+// requireGlobalAdmin lets through global admins acting with their own
+// tokens.
+func (a *Auth) requireGlobalAdmin(ctx context.Context, actor jwt.Claims) error {
+	if actor.Actor != nil || actor.APIKeyID != 0 {
+		return ErrPermissionDenied
+	}
+	scope, err := a.adminScope(ctx, actor.UserID)
+	if err != nil {
+		return err
+	}
+	if scope != allOrgs {
+		return ErrPermissionDenied
+	}
+	return nil
+}
+
+func randomAppSecret() (string, error) {
+	b := make([]byte, appSecretLen)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
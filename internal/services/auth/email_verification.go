@@ -0,0 +1,123 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/DavidG9999/my_grpc_app/internal/domain/models"
+	"github.com/DavidG9999/my_grpc_app/internal/lib/jwt"
+	"github.com/DavidG9999/my_grpc_app/internal/storage"
+)
+
+const verificationTokenLen = 32
+
+// SendEmailVerification mails the user a token that verifies their email
+// with VerifyEmail. Earlier tokens stay valid until they expire.
+func (a *Auth) SendEmailVerification(ctx context.Context, actor jwt.Claims) error {
+	const op = "auth.SendEmailVerification"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int64("user_id", actor.UserID),
+	)
+
+	user, err := a.authSrv.UserByID(ctx, actor.UserID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrUserNotFound)
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if user.EmailVerified {
+		log.Warn("email is already verified")
+
+		return fmt.Errorf("%s: %w", op, ErrEmailVerified)
+	}
+
+	if err := a.sendEmailVerification(ctx, user); err != nil {
+		log.Error("failed to send email verification", slog.String("error", err.Error()))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("email verification sent")
+	return nil
+}
+
+// VerifyEmail marks the email the token was mailed to as verified, unless the
+// user has changed it since.
+func (a *Auth) VerifyEmail(ctx context.Context, token string) error {
+	const op = "auth.VerifyEmail"
+
+	log := a.log.With(slog.String("op", op))
+
+	verification, err := a.authSrv.UseEmailVerification(ctx, hashVerificationToken(token))
+	if err != nil {
+		if errors.Is(err, storage.ErrEmailVerificationNotFound) {
+			log.Warn("unknown or used verification token")
+
+			return fmt.Errorf("%s: %w", op, ErrInvalidVerification)
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	log = log.With(slog.Int64("user_id", verification.UserID))
+
+	if !time.Now().Before(verification.ExpiresAt) {
+		log.Warn("verification token expired")
+
+		return fmt.Errorf("%s: %w", op, ErrInvalidVerification)
+	}
+	if err := a.authSrv.VerifyEmail(ctx, verification.UserID, verification.Email); err != nil {
+		if errors.Is(err, storage.ErrEmailVerificationNotFound) {
+			log.Warn("email changed since the token was sent")
+
+			return fmt.Errorf("%s: %w", op, ErrInvalidVerification)
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("email verified")
+	return nil
+}
+
+func (a *Auth) sendEmailVerification(ctx context.Context, user models.User) error {
+	token, err := randomVerificationToken()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	_, err = a.authSrv.SaveEmailVerification(ctx, models.EmailVerification{
+		UserID:    user.ID,
+		Email:     user.Email,
+		TokenHash: hashVerificationToken(token),
+		CreatedAt: now,
+		ExpiresAt: now.Add(a.verificationTTL),
+	})
+	if err != nil {
+		return err
+	}
+
+	body := fmt.Sprintf("Hello %s,\r\n\r\nverify your email with this token, it expires in %s:\r\n\r\n%s",
+		user.Name, a.verificationTTL, token)
+	return a.mailer.Send(ctx, user.Email, "Verify your email", body)
+}
+
+func randomVerificationToken() (string, error) {
+	b := make([]byte, verificationTokenLen)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashVerificationToken(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
@@ -0,0 +1,218 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/DavidG9999/my_grpc_app/internal/domain/models"
+	"github.com/DavidG9999/my_grpc_app/internal/storage"
+)
+
+// GroupMembers are the users and groups in a group.
+type GroupMembers struct {
+	Users  []models.User
+	Groups []models.Group
+}
+
+// CreateGroup adds a group to the organization. Only the admins of the
+// organization may manage its groups.
+func (a *Auth) CreateGroup(ctx context.Context, actorID int64, orgID int64, name string) (int64, error) {
+	const op = "auth.CreateGroup"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int64("actor_id", actorID),
+		slog.Int64("org_id", orgID),
+	)
+
+	scope, err := a.adminScope(ctx, actorID)
+	if err == nil && !administers(scope, orgID) {
+		err = ErrPermissionDenied
+	}
+	if err != nil {
+		log.Warn("not allowed to create group")
+
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if _, err := a.authSrv.OrganizationByID(ctx, orgID); err != nil {
+		if errors.Is(err, storage.ErrOrganizationNotFound) {
+			return 0, fmt.Errorf("%s: %w", op, ErrOrganizationNotFound)
+		}
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	id, err := a.authSrv.SaveGroup(ctx, orgID, name)
+	if err != nil {
+		if errors.Is(err, storage.ErrGroupExists) {
+			log.Warn("group already exists")
+
+			return 0, fmt.Errorf("%s: %w", op, ErrGroupExists)
+		}
+		log.Error("failed to save group")
+
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("group created", slog.Int64("group_id", id))
+	return id, nil
+}
+
+// AddGroupMember adds the user, or the member group if userID is zero, to
+// the group. Both have to be of the group's organization, and a group cannot
+// end up nested in itself.
+func (a *Auth) AddGroupMember(ctx context.Context, actorID int64, groupID int64, userID int64, memberGroupID int64) error {
+	const op = "auth.AddGroupMember"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int64("actor_id", actorID),
+		slog.Int64("group_id", groupID),
+	)
+
+	group, err := a.authorizeGroup(ctx, actorID, groupID)
+	if err != nil {
+		log.Warn("not allowed to add group member")
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if userID != 0 {
+		user, err := a.authSrv.UserByID(ctx, userID)
+		if err != nil {
+			if errors.Is(err, storage.ErrUserNotFound) {
+				return fmt.Errorf("%s: %w", op, ErrUserNotFound)
+			}
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		if user.OrgID != group.OrgID {
+			log.Warn("user is in another organization")
+
+			return fmt.Errorf("%s: %w", op, ErrUserNotFound)
+		}
+		if err := a.authSrv.AddGroupUser(ctx, groupID, userID); err != nil {
+			log.Error("failed to add group user")
+
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		log.Info("user added to group", slog.Int64("user_id", userID))
+		return nil
+	}
+
+	member, err := a.authSrv.GroupByID(ctx, memberGroupID)
+	if err != nil {
+		if errors.Is(err, storage.ErrGroupNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrGroupNotFound)
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if member.OrgID != group.OrgID {
+		log.Warn("member group is in another organization")
+
+		return fmt.Errorf("%s: %w", op, ErrGroupNotFound)
+	}
+	if err := a.authSrv.AddGroupChild(ctx, groupID, memberGroupID); err != nil {
+		if errors.Is(err, storage.ErrGroupCycle) {
+			log.Warn("group would contain itself")
+
+			return fmt.Errorf("%s: %w", op, ErrGroupCycle)
+		}
+		log.Error("failed to add group child")
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("group added to group", slog.Int64("member_group_id", memberGroupID))
+	return nil
+}
+
+// ListGroupMembers returns the users and groups directly in the group or,
+// if effective is set, in it or in any group nested in it.
+func (a *Auth) ListGroupMembers(ctx context.Context, actorID int64, groupID int64, effective bool) (GroupMembers, error) {
+	const op = "auth.ListGroupMembers"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int64("actor_id", actorID),
+		slog.Int64("group_id", groupID),
+	)
+
+	if _, err := a.authorizeGroup(ctx, actorID, groupID); err != nil {
+		log.Warn("not allowed to list group members")
+
+		return GroupMembers{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	users, err := a.authSrv.GroupUsers(ctx, groupID, effective)
+	if err != nil {
+		return GroupMembers{}, fmt.Errorf("%s: %w", op, err)
+	}
+	groups, err := a.authSrv.GroupChildren(ctx, groupID, effective)
+	if err != nil {
+		return GroupMembers{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return GroupMembers{Users: users, Groups: groups}, nil
+}
+
+// ListUserGroups returns the groups the user is directly in or, if effective
+// is set, also the groups those are nested in.
+func (a *Auth) ListUserGroups(ctx context.Context, actorID int64, userID int64, effective bool) ([]models.Group, error) {
+	const op = "auth.ListUserGroups"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int64("actor_id", actorID),
+		slog.Int64("user_id", userID),
+	)
+
+	if err := a.authorizeUser(ctx, actorID, userID); err != nil {
+		log.Warn("not allowed to list user groups")
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	groups, err := a.authSrv.UserGroups(ctx, userID, effective)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return groups, nil
+}
+
+// authorizeGroup checks that the actor administers the organization of the
+// group and returns the group. Groups of other organizations are not found.
+func (a *Auth) authorizeGroup(ctx context.Context, actorID int64, groupID int64) (models.Group, error) {
+	scope, err := a.adminScope(ctx, actorID)
+	if err != nil {
+		return models.Group{}, err
+	}
+	group, err := a.authSrv.GroupByID(ctx, groupID)
+	if err != nil {
+		if errors.Is(err, storage.ErrGroupNotFound) {
+			return models.Group{}, ErrGroupNotFound
+		}
+		return models.Group{}, err
+	}
+	if !administers(scope, group.OrgID) {
+		return models.Group{}, ErrGroupNotFound
+	}
+	return group, nil
+}
+
+// tokenGroups returns the names of the user's effective groups if the app
+// gets them in tokens, nil otherwise.
+func (a *Auth) tokenGroups(ctx context.Context, app models.App, userID int64) ([]string, error) {
+	if !app.GroupsClaim {
+		return nil, nil
+	}
+	groups, err := a.authSrv.UserGroups(ctx, userID, true)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(groups))
+	for _, group := range groups {
+		names = append(names, group.Name)
+	}
+	return names, nil
+}
@@ -0,0 +1,137 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/DavidG9999/my_grpc_app/internal/domain/models"
+	"github.com/DavidG9999/my_grpc_app/internal/lib/jwt"
+	"github.com/DavidG9999/my_grpc_app/internal/storage"
+)
+
+// Impersonate issues a short-lived token for the user to the app, with an
+// act claim naming the admin. The token belongs to a session of its own that
+// the user and admins can see and revoke. Admins cannot be impersonated, and
+// no token is issued unless the impersonation has been recorded.
+func (a *Auth) Impersonate(
+	ctx context.Context,
+	actor jwt.Claims,
+	userID int64,
+	appID int,
+	reason string,
+	client models.ClientInfo,
+) (ScopedToken, error) {
+	const op = "auth.Impersonate"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int64("actor_id", actor.UserID),
+		slog.Int64("user_id", userID),
+		slog.Int("app_id", appID),
+	)
+
+	// Impersonation must not be chained or started from delegated tokens.
+	if actor.Actor != nil || actor.APIKeyID != 0 {
+		log.Warn("delegated tokens cannot impersonate")
+
+		return ScopedToken{}, fmt.Errorf("%s: %w", op, ErrPermissionDenied)
+	}
+	scope, err := a.adminScope(ctx, actor.UserID)
+	if err != nil {
+		log.Warn("not allowed to impersonate")
+
+		return ScopedToken{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	user, err := a.authSrv.UserByID(ctx, userID)
+	if err == nil && !administers(scope, user.OrgID) {
+		err = storage.ErrUserNotFound
+	}
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return ScopedToken{}, fmt.Errorf("%s: %w", op, ErrUserNotFound)
+		}
+		return ScopedToken{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if _, err := scopeOf(user); err == nil {
+		log.Warn("admins cannot be impersonated")
+
+		return ScopedToken{}, fmt.Errorf("%s: %w", op, ErrPermissionDenied)
+	}
+
+	app, err := a.authSrv.App(ctx, appID)
+	if err == nil && app.OrgID != user.OrgID {
+		err = storage.ErrAppNotFound
+	}
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			return ScopedToken{}, fmt.Errorf("%s: %w", op, ErrInvalidAppID)
+		}
+		return ScopedToken{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := a.checkAppAccess(ctx, app, user.ID); err != nil {
+		log.Warn("user is not allowed to use app")
+
+		return ScopedToken{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	now := time.Now()
+	sessionID, err := a.authSrv.SaveSession(ctx, models.Session{
+		UserID:         user.ID,
+		AppID:          app.ID,
+		Device:         client.Device,
+		IP:             client.IP,
+		UserAgent:      client.UserAgent,
+		CreatedAt:      now,
+		LastSeenAt:     now,
+		ImpersonatorID: &actor.UserID,
+	})
+	if err != nil {
+		log.Error("failed to save session")
+
+		return ScopedToken{}, fmt.Errorf("%s: %w", op, err)
+	}
+	_, err = a.authSrv.SaveAuditEvent(ctx, models.AuditEvent{
+		ActorID:      actor.UserID,
+		Action:       models.AuditImpersonate,
+		TargetUserID: user.ID,
+		AppID:        app.ID,
+		SessionID:    sessionID,
+		Reason:       reason,
+		IP:           client.IP,
+		CreatedAt:    now.UTC(),
+	})
+	if err != nil {
+		log.Error("failed to audit impersonation")
+
+		if err := a.authSrv.RevokeSession(ctx, sessionID); err != nil {
+			log.Error("failed to revoke unaudited session", slog.String("error", err.Error()))
+		}
+		return ScopedToken{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	groups, err := a.tokenGroups(ctx, app, user.ID)
+	if err != nil {
+		return ScopedToken{}, fmt.Errorf("%s: %w", op, err)
+	}
+	act := map[string]any{
+		"sub": strconv.FormatInt(actor.UserID, 10),
+	}
+
+	token, err := jwt.NewDelegatedToken(user, app, a.tokenSecret, sessionID, nil, groups, act, a.impersonationTTL)
+	if err != nil {
+		log.Error("failed to generate token")
+
+		return ScopedToken{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("user impersonated", slog.Int64("session_id", sessionID), slog.String("reason", reason))
+	return ScopedToken{
+		Token:     token,
+		ExpiresIn: a.impersonationTTL,
+	}, nil
+}
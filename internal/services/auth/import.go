@@ -0,0 +1,104 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/DavidG9999/my_grpc_app/internal/lib/password"
+	"github.com/DavidG9999/my_grpc_app/internal/storage"
+)
+
+var (
+	ErrUnknownPasswordHash = errors.New("unknown password hash format")
+	ErrPasswordHashLimits  = errors.New("password hash parameters are out of limits")
+)
+
+// ImportedUser is a user migrated from another system together with the
+// password hash it had there.
+type ImportedUser struct {
+	Name         string
+	Email        string
+	PasswordHash []byte
+	IsAdmin      bool
+}
+
+// ImportFailure is a user that could not be imported.
+type ImportFailure struct {
+	Index int
+	Email string
+	Err   error
+}
+
+type ImportResult struct {
+	Imported int64
+	Failures []ImportFailure
+}
+
+// ImportUsers stores users of the organization with their password hashes
+// as they are, so that they keep their passwords. The hashes are upgraded to
+// the current algorithm on their first sign in. Only the admins of the
+// organization may import users. Admins are not imported, they are promoted
+// through approved changes.
+func (a *Auth) ImportUsers(ctx context.Context, actorID int64, orgID int64, users []ImportedUser) (ImportResult, error) {
+	const op = "auth.ImportUsers"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int64("actor_id", actorID),
+		slog.Int64("org_id", orgID),
+	)
+
+	scope, err := a.adminScope(ctx, actorID)
+	if err == nil && !administers(scope, orgID) {
+		err = ErrPermissionDenied
+	}
+	if err != nil {
+		log.Warn("not allowed to import users")
+
+		return ImportResult{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if _, err := a.authSrv.OrganizationByID(ctx, orgID); err != nil {
+		if errors.Is(err, storage.ErrOrganizationNotFound) {
+			return ImportResult{}, fmt.Errorf("%s: %w", op, ErrOrganizationNotFound)
+		}
+		return ImportResult{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var result ImportResult
+	for i, user := range users {
+		if err := a.hasher.Check(user.PasswordHash); err != nil {
+			failure := ErrUnknownPasswordHash
+			if errors.Is(err, password.ErrHashLimits) {
+				failure = ErrPasswordHashLimits
+			}
+			result.Failures = append(result.Failures, ImportFailure{Index: i, Email: user.Email, Err: failure})
+			continue
+		}
+		if user.IsAdmin {
+			// Admins are promoted through approved changes.
+			err := ErrApprovalRequired
+			if scope != allOrgs {
+				err = ErrPermissionDenied
+			}
+			result.Failures = append(result.Failures, ImportFailure{Index: i, Email: user.Email, Err: err})
+			continue
+		}
+
+		_, err := a.authSrv.SaveUser(ctx, orgID, user.Name, user.Email, user.PasswordHash, user.IsAdmin)
+		if err != nil {
+			if errors.Is(err, storage.ErrUserExists) {
+				result.Failures = append(result.Failures, ImportFailure{Index: i, Email: user.Email, Err: ErrUserExist})
+				continue
+			}
+			log.Error("failed to save user", slog.Int("index", i))
+
+			return result, fmt.Errorf("%s: %w", op, err)
+		}
+		result.Imported++
+	}
+
+	log.Info("users imported", slog.Int64("imported", result.Imported), slog.Int("failed", len(result.Failures)))
+	return result, nil
+}
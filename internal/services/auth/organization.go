@@ -0,0 +1,239 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/DavidG9999/my_grpc_app/internal/domain/models"
+	"github.com/DavidG9999/my_grpc_app/internal/lib/jwt"
+	"github.com/DavidG9999/my_grpc_app/internal/storage"
+)
+
+// allOrgs is the admin scope of global admins.
+const allOrgs int64 = 0
+
+// CreateOrganization adds a tenant. Only global admins may create
+// organizations.
+func (a *Auth) CreateOrganization(ctx context.Context, actorID int64, name string) (int64, error) {
+	const op = "auth.CreateOrganization"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int64("actor_id", actorID),
+	)
+
+	scope, err := a.adminScope(ctx, actorID)
+	if err == nil && scope != allOrgs {
+		err = ErrPermissionDenied
+	}
+	if err != nil {
+		log.Warn("not allowed to create organization")
+
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	id, err := a.authSrv.SaveOrganization(ctx, name)
+	if err != nil {
+		if errors.Is(err, storage.ErrOrganizationExists) {
+			log.Warn("organization already exists")
+
+			return 0, fmt.Errorf("%s: %w", op, ErrOrganizationExists)
+		}
+		log.Error("failed to save organization")
+
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("organization created", slog.Int64("org_id", id))
+	return id, nil
+}
+
+// SetOrgAdmin takes away the administration of the user's organization.
+// Global admins and the admins of that organization may do it. Grants are
+// promotions of admins and need the approval of a second global admin: they go
+// through ProposeChange. Global admins are not made org admins.
+func (a *Auth) SetOrgAdmin(ctx context.Context, actorID int64, userID int64, isOrgAdmin bool, client models.ClientInfo) error {
+	const op = "auth.SetOrgAdmin"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int64("actor_id", actorID),
+		slog.Int64("user_id", userID),
+		slog.Bool("is_org_admin", isOrgAdmin),
+	)
+
+	scope, err := a.adminScope(ctx, actorID)
+	if err != nil {
+		log.Warn("not allowed to set org admin")
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	user, err := a.authSrv.UserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrUserNotFound)
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if !administers(scope, user.OrgID) {
+		log.Warn("user is in another organization")
+
+		return fmt.Errorf("%s: %w", op, ErrUserNotFound)
+	}
+	if user.IsAdmin {
+		log.Warn("user is a global admin")
+
+		return fmt.Errorf("%s: %w", op, ErrUserIsAdmin)
+	}
+	if user.IsOrgAdmin == isOrgAdmin {
+		return nil
+	}
+	if isOrgAdmin {
+		log.Warn("grant requires approval")
+
+		return fmt.Errorf("%s: %w", op, ErrApprovalRequired)
+	}
+
+	if err := a.authSrv.SetOrgAdmin(ctx, userID, false); err != nil {
+		log.Error("failed to set org admin")
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = a.authSrv.SaveAuditEvent(ctx, models.AuditEvent{
+		ActorID:      actorID,
+		Action:       models.AuditRevokeOrgAdmin,
+		TargetUserID: userID,
+		IP:           client.IP,
+		CreatedAt:    time.Now().UTC(),
+	})
+	if err != nil {
+		log.Error("failed to audit org admin change")
+
+		// Unaudited changes of admins are not kept.
+		if err := a.authSrv.SetOrgAdmin(ctx, userID, true); err != nil {
+			log.Error("failed to undo unaudited org admin change", slog.String("error", err.Error()))
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("org admin set")
+	return nil
+}
+
+// SetAdmin demotes the user from global admin. Only global admins may do it,
+// and not with delegated tokens. Promotions need the approval of a second
+// admin and go through ProposeChange. The last admin cannot be demoted.
+// Admin checks read the users table, so the change applies to the very next
+// request and token.
+func (a *Auth) SetAdmin(ctx context.Context, actor jwt.Claims, userID int64, isAdmin bool, client models.ClientInfo) error {
+	const op = "auth.SetAdmin"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int64("actor_id", actor.UserID),
+		slog.Int64("user_id", userID),
+		slog.Bool("is_admin", isAdmin),
+	)
+
+	if err := a.requireGlobalAdmin(ctx, actor); err != nil {
+		log.Warn("not allowed to set admin")
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	user, err := a.authSrv.UserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrUserNotFound)
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if user.IsAdmin == isAdmin {
+		return nil
+	}
+	if isAdmin {
+		log.Warn("promotion requires approval")
+
+		return fmt.Errorf("%s: %w", op, ErrApprovalRequired)
+	}
+
+	if err := a.authSrv.SetAdmin(ctx, userID, false); err != nil {
+		switch {
+		case errors.Is(err, storage.ErrLastAdmin):
+			log.Warn("cannot demote the last admin")
+
+			return fmt.Errorf("%s: %w", op, ErrLastAdmin)
+		case errors.Is(err, storage.ErrUserNotFound):
+			return fmt.Errorf("%s: %w", op, ErrUserNotFound)
+		}
+		log.Error("failed to set admin")
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = a.authSrv.SaveAuditEvent(ctx, models.AuditEvent{
+		ActorID:      actor.UserID,
+		Action:       models.AuditDemoteAdmin,
+		TargetUserID: userID,
+		IP:           client.IP,
+		CreatedAt:    time.Now().UTC(),
+	})
+	if err != nil {
+		log.Error("failed to audit admin change")
+
+		// Unaudited changes of admins are not kept.
+		if err := a.authSrv.SetAdmin(ctx, userID, true); err != nil {
+			log.Error("failed to undo unaudited admin change", slog.String("error", err.Error()))
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("admin set")
+	return nil
+}
+
+// IsAdministrator reports whether the user is a global or an organization
+// admin.
+func (a *Auth) IsAdministrator(ctx context.Context, userID int64) (bool, error) {
+	const op = "auth.IsAdministrator"
+
+	if _, err := a.adminScope(ctx, userID); err != nil {
+		if errors.Is(err, ErrPermissionDenied) {
+			return false, nil
+		}
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	return true, nil
+}
+
+// adminScope returns the organization the actor administers, or allOrgs
+// for global admins. Anybody else gets ErrPermissionDenied.
+func (a *Auth) adminScope(ctx context.Context, actorID int64) (int64, error) {
+	actor, err := a.authSrv.UserByID(ctx, actorID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return 0, ErrPermissionDenied
+		}
+		return 0, err
+	}
+	return scopeOf(actor)
+}
+
+func scopeOf(actor models.User) (int64, error) {
+	switch {
+	case actor.IsAdmin:
+		return allOrgs, nil
+	case actor.IsOrgAdmin:
+		return actor.OrgID, nil
+	}
+	return 0, ErrPermissionDenied
+}
+
+// administers reports whether an admin scope covers the organization.
+func administers(scope int64, orgID int64) bool {
+	return scope == allOrgs || scope == orgID
+}
@@ -0,0 +1,249 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/DavidG9999/my_grpc_app/internal/domain/models"
+	"github.com/DavidG9999/my_grpc_app/internal/lib/jwt"
+	"github.com/DavidG9999/my_grpc_app/internal/storage"
+)
+
+// sessionTouchInterval limits how often validating a token updates the
+// last-seen time of its session.
+const sessionTouchInterval = time.Minute
+
+type SessionStorage interface {
+	SaveSession(ctx context.Context, session models.Session) (int64, error)
+	SessionByID(ctx context.Context, sessionID int64) (models.Session, error)
+	UserSessions(ctx context.Context, userID int64) ([]models.Session, error)
+	TouchSession(ctx context.Context, sessionID int64, lastSeenAt time.Time) error
+	RevokeSession(ctx context.Context, sessionID int64) error
+	RevokeSessions(ctx context.Context, userID int64, keepSessionID int64) (int64, error)
+}
+
+func (a *Auth) openSession(ctx context.Context, user models.User, app models.App, client models.ClientInfo) (int64, error) {
+	now := time.Now()
+	return a.authSrv.SaveSession(ctx, models.Session{
+		UserID:     user.ID,
+		AppID:      app.ID,
+		Device:     client.Device,
+		IP:         client.IP,
+		UserAgent:  client.UserAgent,
+		CreatedAt:  now,
+		LastSeenAt: now,
+	})
+}
+
+// ValidateToken verifies a user's access token and the session it belongs to.
+// Tokens of users that are not active are invalid.
+func (a *Auth) ValidateToken(ctx context.Context, token string) (jwt.Claims, error) {
+	const op = "auth.ValidateToken"
+
+	log := a.log.With(slog.String("op", op))
+
+	appID, err := jwt.AppID(token)
+	if err != nil {
+		return jwt.Claims{}, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+	app, err := a.authSrv.App(ctx, appID)
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			return jwt.Claims{}, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+		}
+		return jwt.Claims{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	claims, err := jwt.ParseToken(token, app, a.tokenSecret)
+	if err != nil {
+		log.Debug("invalid token", slog.String("error", err.Error()))
+
+		return jwt.Claims{}, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+	if claims.UserID != 0 {
+		user, err := a.authSrv.UserByID(ctx, claims.UserID)
+		if err == nil && !user.Active(time.Now()) {
+			log.Info("user is not active", slog.Int64("user_id", user.ID), slog.String("status", string(user.Status)))
+
+			err = storage.ErrUserNotFound
+		}
+		if err == nil && user.OrgID != app.OrgID {
+			log.Warn("app is in another organization than the user", slog.Int64("user_id", user.ID))
+
+			err = storage.ErrUserNotFound
+		}
+		if err != nil {
+			if errors.Is(err, storage.ErrUserNotFound) {
+				return jwt.Claims{}, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+			}
+			return jwt.Claims{}, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+	if claims.SessionID == 0 {
+		// Only tokens of API keys have no session, they are revoked with
+		// their key.
+		if err := a.checkTokenAPIKey(ctx, claims); err != nil {
+			log.Info("api key token is not valid", slog.String("error", err.Error()))
+
+			return jwt.Claims{}, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+		}
+		return claims, nil
+	}
+
+	session, err := a.authSrv.SessionByID(ctx, claims.SessionID)
+	if err != nil {
+		if errors.Is(err, storage.ErrSessionNotFound) {
+			return jwt.Claims{}, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+		}
+		return jwt.Claims{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if session.RevokedAt != nil || session.UserID != claims.UserID || session.AppID != claims.SessionAppID() {
+		log.Info("session is revoked or not the token's", slog.Int64("session_id", session.ID))
+
+		return jwt.Claims{}, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+
+	if now := time.Now(); now.Sub(session.LastSeenAt) > sessionTouchInterval {
+		if err := a.authSrv.TouchSession(ctx, session.ID, now); err != nil {
+			log.Warn("failed to touch session", slog.String("error", err.Error()))
+		}
+	}
+	return claims, nil
+}
+
+// checkTokenAPIKey checks that the token was issued for an API key of its
+// user and app that is neither revoked nor expired.
+func (a *Auth) checkTokenAPIKey(ctx context.Context, claims jwt.Claims) error {
+	if claims.APIKeyID == 0 {
+		return errors.New("token has no session")
+	}
+	key, err := a.authSrv.APIKeyByID(ctx, claims.APIKeyID)
+	if err != nil {
+		return err
+	}
+	if key.UserID != claims.UserID || key.AppID != claims.AppID {
+		return errors.New("api key belongs to another user or app")
+	}
+	if key.RevokedAt != nil || (key.ExpiresAt != nil && !time.Now().Before(*key.ExpiresAt)) {
+		return errors.New("api key is revoked or expired")
+	}
+	return nil
+}
+
+func (a *Auth) ListSessions(ctx context.Context, actorID int64, userID int64) ([]models.Session, error) {
+	const op = "auth.ListSessions"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int64("actor_id", actorID),
+		slog.Int64("user_id", userID),
+	)
+
+	if err := a.authorizeUser(ctx, actorID, userID); err != nil {
+		log.Warn("not allowed to list sessions")
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	sessions, err := a.authSrv.UserSessions(ctx, userID)
+	if err != nil {
+		log.Error("failed to get sessions")
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return sessions, nil
+}
+
+func (a *Auth) RevokeSession(ctx context.Context, actorID int64, sessionID int64) error {
+	const op = "auth.RevokeSession"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int64("actor_id", actorID),
+		slog.Int64("session_id", sessionID),
+	)
+
+	session, err := a.authSrv.SessionByID(ctx, sessionID)
+	if err != nil {
+		if errors.Is(err, storage.ErrSessionNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrSessionNotFound)
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := a.authorizeUser(ctx, actorID, session.UserID); err != nil {
+		log.Warn("not allowed to revoke session")
+
+		// Do not reveal that somebody else's session exists.
+		if errors.Is(err, ErrPermissionDenied) {
+			return fmt.Errorf("%s: %w", op, ErrSessionNotFound)
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.authSrv.RevokeSession(ctx, sessionID); err != nil {
+		log.Error("failed to revoke session")
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("session revoked")
+	return nil
+}
+
+// RevokeAllSessions revokes every session of the user except keepSessionID,
+// which may be zero.
+func (a *Auth) RevokeAllSessions(ctx context.Context, actorID int64, userID int64, keepSessionID int64) (int64, error) {
+	const op = "auth.RevokeAllSessions"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int64("actor_id", actorID),
+		slog.Int64("user_id", userID),
+	)
+
+	if err := a.authorizeUser(ctx, actorID, userID); err != nil {
+		log.Warn("not allowed to revoke sessions")
+
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	revoked, err := a.authSrv.RevokeSessions(ctx, userID, keepSessionID)
+	if err != nil {
+		log.Error("failed to revoke sessions")
+
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("sessions revoked", slog.Int64("revoked", revoked))
+	return revoked, nil
+}
+
+// authorizeUser allows users to act on themselves, org admins on the users
+// of their organization other than global admins, and global admins on
+// anybody.
+func (a *Auth) authorizeUser(ctx context.Context, actorID int64, userID int64) error {
+	if actorID == userID {
+		return nil
+	}
+	scope, err := a.adminScope(ctx, actorID)
+	if err != nil {
+		return err
+	}
+	if scope == allOrgs {
+		return nil
+	}
+	user, err := a.authSrv.UserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return ErrPermissionDenied
+		}
+		return err
+	}
+	if !administers(scope, user.OrgID) || user.IsAdmin {
+		return ErrPermissionDenied
+	}
+	return nil
+}
@@ -0,0 +1,217 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/mail"
+	"strings"
+
+	"github.com/DavidG9999/my_grpc_app/internal/domain/models"
+	"github.com/DavidG9999/my_grpc_app/internal/lib/jwt"
+	"github.com/DavidG9999/my_grpc_app/internal/lib/password"
+	"github.com/DavidG9999/my_grpc_app/internal/storage"
+)
+
+// GetUser returns the user with the id or, if it is zero, the email within
+// the actor's organization. Users may only get themselves, org admins the
+// users of their organization and global admins anybody.
+func (a *Auth) GetUser(ctx context.Context, actorID int64, userID int64, email string) (models.User, error) {
+	const op = "auth.GetUser"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int64("actor_id", actorID),
+	)
+
+	actor, err := a.authSrv.UserByID(ctx, actorID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return models.User{}, fmt.Errorf("%s: %w", op, ErrPermissionDenied)
+		}
+		return models.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+	scope, scopeErr := scopeOf(actor)
+	isAdmin := scopeErr == nil
+
+	var user models.User
+	if userID != 0 {
+		user, err = a.authSrv.UserByID(ctx, userID)
+	} else {
+		user, err = a.authSrv.User(ctx, actor.OrgID, email)
+	}
+	if err == nil && isAdmin && !administers(scope, user.OrgID) {
+		// Users of other organizations do not exist for org admins.
+		err = storage.ErrUserNotFound
+	}
+	// Whether somebody else exists is none of a user's business.
+	if !isAdmin && (err != nil || user.ID != actorID) {
+		log.Warn("not allowed to get user")
+
+		return models.User{}, fmt.Errorf("%s: %w", op, ErrPermissionDenied)
+	}
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return models.User{}, fmt.Errorf("%s: %w", op, ErrUserNotFound)
+		}
+		return models.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return user, nil
+}
+
+// UpdateUser changes the user's name and email, keeping the current ones
+// where they are empty. A new email has to be verified again, a verification
+// token is mailed to it.
+func (a *Auth) UpdateUser(ctx context.Context, actorID int64, userID int64, name string, email string) (models.User, error) {
+	const op = "auth.UpdateUser"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int64("actor_id", actorID),
+		slog.Int64("user_id", userID),
+	)
+
+	if email != "" && !validEmail(email) {
+		log.Warn("invalid email")
+
+		return models.User{}, fmt.Errorf("%s: %w", op, ErrInvalidEmail)
+	}
+	if err := a.authorizeUser(ctx, actorID, userID); err != nil {
+		log.Warn("not allowed to update user")
+
+		return models.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	user, err := a.authSrv.UserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return models.User{}, fmt.Errorf("%s: %w", op, ErrUserNotFound)
+		}
+		return models.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if name != "" {
+		user.Name = name
+	}
+	emailChanged := email != "" && email != user.Email
+	if emailChanged {
+		user.Email = email
+		user.EmailVerified = false
+	}
+
+	if err := a.authSrv.UpdateUser(ctx, userID, user.Name, user.Email); err != nil {
+		if errors.Is(err, storage.ErrUserExists) {
+			log.Warn("email already taken")
+
+			return models.User{}, fmt.Errorf("%s: %w", op, ErrUserExist)
+		}
+		log.Error("failed to update user")
+
+		return models.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if emailChanged {
+		if err := a.sendEmailVerification(ctx, user); err != nil {
+			log.Error("failed to send email verification", slog.String("error", err.Error()))
+		}
+	}
+
+	log.Info("user updated")
+	return user, nil
+}
+
+// ChangePassword sets a new password that satisfies the policies of all apps
+// of the user's organization.
+// Users have to confirm their current password, admins changing somebody
+// else's do not, unless that is a global admin. All other sessions of the user
+// are revoked.
+func (a *Auth) ChangePassword(
+	ctx context.Context,
+	actor jwt.Claims,
+	userID int64,
+	currentPassword string,
+	newPassword string,
+) error {
+	const op = "auth.ChangePassword"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int64("actor_id", actor.UserID),
+		slog.Int64("user_id", userID),
+	)
+
+	if err := a.authorizeUser(ctx, actor.UserID, userID); err != nil {
+		log.Warn("not allowed to change password")
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	user, err := a.authSrv.UserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrUserNotFound)
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Taking over a global admin takes their current password, whoever asks.
+	if actor.UserID == userID || user.IsAdmin {
+		if _, err := a.hasher.Verify(user.PasswordHash, currentPassword); err != nil {
+			if errors.Is(err, password.ErrMismatch) {
+				log.Info("invalid current password")
+
+				return fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+			}
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	// The password signs the user in to every app of their organization, not
+	// just the one the actor's token is for.
+	appIDs, err := a.authSrv.OrgAppIDs(ctx, user.OrgID)
+	if err != nil {
+		log.Error("failed to get apps of organization")
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := a.validatePassword(a.passwords.Strictest(appIDs...), newPassword, user.Name, user.Email); err != nil {
+		log.Info("password does not satisfy policy")
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	passHash, err := a.hasher.Hash(newPassword)
+	if err != nil {
+		log.Error("failed to generate password hash")
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := a.authSrv.UpdatePasswordHash(ctx, userID, passHash); err != nil {
+		log.Error("failed to update password hash")
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	var keep int64
+	if actor.UserID == userID {
+		keep = actor.SessionID
+	}
+	if _, err := a.authSrv.RevokeSessions(ctx, userID, keep); err != nil {
+		log.Error("failed to revoke sessions")
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("password changed")
+	return nil
+}
+
+// validEmail reports whether email is a bare address, without a display name
+// or line breaks, that can be put in mail headers as it is.
+func validEmail(email string) bool {
+	if strings.ContainsAny(email, "\r\n") {
+		return false
+	}
+	addr, err := mail.ParseAddress(email)
+	return err == nil && addr.Address == email
+}
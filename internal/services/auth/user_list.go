@@ -0,0 +1,142 @@
+package auth
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/DavidG9999/my_grpc_app/internal/domain/models"
+)
+
+const (
+	defaultUserPageSize = 50
+	maxUserPageSize     = 500
+)
+
+var ErrInvalidPageToken = errors.New("invalid page token")
+
+// ListUsersParams selects and orders the users to list. PageToken is the
+// NextPageToken of the previous page, which has to be listed with the same
+// sorting.
+type ListUsersParams struct {
+	Filter     models.UserFilter
+	SortBy     models.UserSortField
+	Descending bool
+	PageSize   int
+	PageToken  string
+}
+
+type UserPage struct {
+	Users []models.User
+	// NextPageToken is empty on the last page.
+	NextPageToken string
+}
+
+// pageToken is the opaque cursor handed to clients. It carries the sorting
+// it was issued for so that it cannot be used with another one.
+type pageToken struct {
+	SortBy     models.UserSortField `json:"s"`
+	Descending bool                 `json:"d,omitempty"`
+	ID         int64                `json:"i"`
+	Email      string               `json:"e,omitempty"`
+	CreatedAt  time.Time            `json:"c,omitempty"`
+}
+
+// ListUsers returns a page of users. Global admins may list the users of
+// every organization, org admins those of their own one.
+func (a *Auth) ListUsers(ctx context.Context, actorID int64, params ListUsersParams) (UserPage, error) {
+	const op = "auth.ListUsers"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int64("actor_id", actorID),
+	)
+
+	scope, err := a.adminScope(ctx, actorID)
+	if err == nil && params.Filter.OrgID != allOrgs && !administers(scope, params.Filter.OrgID) {
+		err = ErrPermissionDenied
+	}
+	if err != nil {
+		log.Warn("not allowed to list users")
+
+		return UserPage{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if scope != allOrgs {
+		params.Filter.OrgID = scope
+	}
+
+	sortBy := params.SortBy
+	if sortBy == "" {
+		sortBy = models.UserSortCreatedAt
+	}
+	limit := params.PageSize
+	if limit <= 0 {
+		limit = defaultUserPageSize
+	}
+	limit = min(limit, maxUserPageSize)
+
+	query := models.UserListQuery{
+		Filter:     params.Filter,
+		SortBy:     sortBy,
+		Descending: params.Descending,
+		// One more to know whether there is a next page.
+		Limit: limit + 1,
+	}
+	if params.PageToken != "" {
+		token, err := decodePageToken(params.PageToken)
+		if err != nil || token.SortBy != sortBy || token.Descending != params.Descending {
+			log.Info("invalid page token")
+
+			return UserPage{}, fmt.Errorf("%s: %w", op, ErrInvalidPageToken)
+		}
+		query.After = &models.UserCursor{ID: token.ID, Email: token.Email, CreatedAt: token.CreatedAt}
+	}
+
+	users, err := a.authSrv.ListUsers(ctx, query)
+	if err != nil {
+		log.Error("failed to list users")
+
+		return UserPage{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	page := UserPage{Users: users}
+	if len(users) > limit {
+		page.Users = users[:limit]
+		last := page.Users[limit-1]
+		token := pageToken{SortBy: sortBy, Descending: params.Descending, ID: last.ID}
+		if sortBy == models.UserSortEmail {
+			token.Email = last.Email
+		} else {
+			token.CreatedAt = last.CreatedAt
+		}
+		page.NextPageToken, err = encodePageToken(token)
+		if err != nil {
+			return UserPage{}, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+	return page, nil
+}
+
+func encodePageToken(token pageToken) (string, error) {
+	data, err := json.Marshal(token)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodePageToken(s string) (pageToken, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return pageToken{}, err
+	}
+	var token pageToken
+	if err := json.Unmarshal(data, &token); err != nil {
+		return pageToken{}, err
+	}
+	return token, nil
+}
@@ -0,0 +1,45 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/DavidG9999/my_grpc_app/internal/domain/models"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+// SearchUsers finds users by parts of their names and emails, best matches
+// first. Org admins only find the users of their organization.
+func (a *Auth) SearchUsers(ctx context.Context, actorID int64, query string, limit int) ([]models.UserMatch, error) {
+	const op = "auth.SearchUsers"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int64("actor_id", actorID),
+	)
+
+	scope, err := a.adminScope(ctx, actorID)
+	if err != nil {
+		log.Warn("not allowed to search users")
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	limit = min(limit, maxSearchLimit)
+
+	matches, err := a.authSrv.SearchUsers(ctx, scope, query, limit)
+	if err != nil {
+		log.Error("failed to search users")
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return matches, nil
+}
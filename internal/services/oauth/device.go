@@ -0,0 +1,307 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/DavidG9999/my_grpc_app/internal/domain/models"
+	"github.com/DavidG9999/my_grpc_app/internal/storage"
+)
+
+const (
+	GrantTypeDeviceCode = "urn:ietf:params:oauth:grant-type:device_code"
+
+	// userCodeAlphabet has no vowels and no look-alike characters, so that
+	// codes are easy to type and never spell words.
+	userCodeAlphabet = "BCDFGHJKLMNPQRSTVWXZ"
+	userCodeLen      = 8
+
+	slowDownStep = 5 * time.Second
+)
+
+type DeviceStorage interface {
+	SaveDeviceAuthorization(ctx context.Context, device models.DeviceAuthorization) (int64, error)
+	DeviceAuthorization(ctx context.Context, deviceCodeHash []byte) (models.DeviceAuthorization, error)
+	DeviceAuthorizationByUserCode(ctx context.Context, userCode string) (models.DeviceAuthorization, error)
+	UpdateDeviceAuthorizationStatus(ctx context.Context, deviceID int64, from models.DeviceAuthorizationStatus, to models.DeviceAuthorizationStatus, userID *int64) error
+	UpdateDeviceAuthorizationPoll(ctx context.Context, deviceID int64, polledAt time.Time, interval time.Duration) error
+}
+
+var (
+	ErrAuthorizationPending = errors.New("authorization pending")
+	ErrSlowDown             = errors.New("slow down")
+	ErrExpiredToken         = errors.New("expired token")
+	ErrAccessDenied         = errors.New("access denied")
+	ErrInvalidUserCode      = errors.New("invalid user code")
+)
+
+type DeviceAuthorizationResponse struct {
+	DeviceCode              string
+	UserCode                string
+	VerificationURI         string
+	VerificationURIComplete string
+	ExpiresIn               time.Duration
+	Interval                time.Duration
+}
+
+// StartDeviceAuthorization begins the device flow of RFC 8628 for the app.
+func (o *OAuth) StartDeviceAuthorization(ctx context.Context, clientID string, scope string) (DeviceAuthorizationResponse, error) {
+	const op = "oauth.StartDeviceAuthorization"
+
+	log := o.log.With(
+		slog.String("op", op),
+		slog.String("client_id", clientID),
+	)
+
+	app, err := o.client(ctx, clientID)
+	if err != nil {
+		log.Warn("invalid client")
+
+		return DeviceAuthorizationResponse{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	deviceCode, err := randomSecret()
+	if err != nil {
+		return DeviceAuthorizationResponse{}, fmt.Errorf("%s: %w", op, err)
+	}
+	userCode, err := randomUserCode()
+	if err != nil {
+		return DeviceAuthorizationResponse{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	now := time.Now()
+	_, err = o.oauthSrv.SaveDeviceAuthorization(ctx, models.DeviceAuthorization{
+		DeviceCodeHash: hashSecret(deviceCode),
+		UserCode:       userCode,
+		AppID:          app.ID,
+		Scope:          scope,
+		Status:         models.DeviceAuthorizationPending,
+		PollInterval:   o.devicePollInterval,
+		CreatedAt:      now,
+		ExpiresAt:      now.Add(o.deviceCodeTTL),
+	})
+	if err != nil {
+		log.Error("failed to save device authorization")
+
+		return DeviceAuthorizationResponse{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	verificationURI := strings.TrimSuffix(o.issuer, "/") + "/device"
+
+	log.Info("device authorization started")
+	return DeviceAuthorizationResponse{
+		DeviceCode:              deviceCode,
+		UserCode:                formatUserCode(userCode),
+		VerificationURI:         verificationURI,
+		VerificationURIComplete: verificationURI + "?user_code=" + formatUserCode(userCode),
+		ExpiresIn:               o.deviceCodeTTL,
+		Interval:                o.devicePollInterval,
+	}, nil
+}
+
+// DeviceApp returns the app a pending device authorization is for and the
+// scope it asks for, so that the user approving it can be signed in to the
+// app's organization and sees what they grant.
+func (o *OAuth) DeviceApp(ctx context.Context, userCode string) (models.App, string, error) {
+	const op = "oauth.DeviceApp"
+
+	device, err := o.oauthSrv.DeviceAuthorizationByUserCode(ctx, normalizeUserCode(userCode))
+	if err != nil {
+		if errors.Is(err, storage.ErrDeviceAuthorizationNotFound) {
+			return models.App{}, "", fmt.Errorf("%s: %w", op, ErrInvalidUserCode)
+		}
+		return models.App{}, "", fmt.Errorf("%s: %w", op, err)
+	}
+	if device.Status != models.DeviceAuthorizationPending || time.Now().After(device.ExpiresAt) {
+		return models.App{}, "", fmt.Errorf("%s: %w", op, ErrInvalidUserCode)
+	}
+
+	app, err := o.oauthSrv.App(ctx, device.AppID)
+	if err != nil {
+		return models.App{}, "", fmt.Errorf("%s: %w", op, err)
+	}
+	return app, device.Scope, nil
+}
+
+// ApproveDevice records the decision of a signed-in user about the device
+// identified by the user code.
+func (o *OAuth) ApproveDevice(ctx context.Context, userCode string, userID int64, approve bool) (models.App, error) {
+	const op = "oauth.ApproveDevice"
+
+	log := o.log.With(
+		slog.String("op", op),
+		slog.Int64("user_id", userID),
+	)
+
+	device, err := o.oauthSrv.DeviceAuthorizationByUserCode(ctx, normalizeUserCode(userCode))
+	if err != nil {
+		if errors.Is(err, storage.ErrDeviceAuthorizationNotFound) {
+			return models.App{}, fmt.Errorf("%s: %w", op, ErrInvalidUserCode)
+		}
+		return models.App{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if device.Status != models.DeviceAuthorizationPending || time.Now().After(device.ExpiresAt) {
+		return models.App{}, fmt.Errorf("%s: %w", op, ErrInvalidUserCode)
+	}
+
+	app, err := o.oauthSrv.App(ctx, device.AppID)
+	if err != nil {
+		return models.App{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// A user without access to the app denies the device by approving it.
+	var accessErr error
+	if approve {
+		accessErr = o.checkAppAccess(ctx, app, userID)
+		if accessErr != nil && !errors.Is(accessErr, ErrAccessDenied) {
+			return models.App{}, fmt.Errorf("%s: %w", op, accessErr)
+		}
+	}
+
+	to := models.DeviceAuthorizationDenied
+	if approve && accessErr == nil {
+		to = models.DeviceAuthorizationApproved
+	}
+	err = o.oauthSrv.UpdateDeviceAuthorizationStatus(ctx, device.ID, models.DeviceAuthorizationPending, to, &userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrDeviceAuthorizationHandled) {
+			return models.App{}, fmt.Errorf("%s: %w", op, ErrInvalidUserCode)
+		}
+		return models.App{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("device authorization handled", slog.String("status", string(to)))
+	if accessErr != nil {
+		return models.App{}, fmt.Errorf("%s: %w", op, accessErr)
+	}
+	return app, nil
+}
+
+// PollDeviceToken exchanges an approved device code for tokens. Until the
+// user acts, it reports ErrAuthorizationPending, or ErrSlowDown if the client
+// polls faster than the interval, which is then increased.
+func (o *OAuth) PollDeviceToken(ctx context.Context, clientID string, deviceCode string, client models.ClientInfo) (TokenResponse, error) {
+	const op = "oauth.PollDeviceToken"
+
+	log := o.log.With(
+		slog.String("op", op),
+		slog.String("client_id", clientID),
+	)
+
+	app, err := o.client(ctx, clientID)
+	if err != nil {
+		return TokenResponse{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if deviceCode == "" {
+		return TokenResponse{}, fmt.Errorf("%s: %w: device_code is required", op, ErrInvalidRequest)
+	}
+
+	device, err := o.oauthSrv.DeviceAuthorization(ctx, hashSecret(deviceCode))
+	if err != nil {
+		if errors.Is(err, storage.ErrDeviceAuthorizationNotFound) {
+			return TokenResponse{}, fmt.Errorf("%s: %w", op, ErrInvalidGrant)
+		}
+		return TokenResponse{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if device.AppID != app.ID {
+		return TokenResponse{}, fmt.Errorf("%s: %w", op, ErrInvalidGrant)
+	}
+
+	now := time.Now()
+	if now.After(device.ExpiresAt) {
+		return TokenResponse{}, fmt.Errorf("%s: %w", op, ErrExpiredToken)
+	}
+
+	interval := device.PollInterval
+	tooFast := device.LastPolledAt != nil && now.Before(device.LastPolledAt.Add(interval))
+	if tooFast {
+		interval += slowDownStep
+	}
+	if err := o.oauthSrv.UpdateDeviceAuthorizationPoll(ctx, device.ID, now, interval); err != nil {
+		return TokenResponse{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if tooFast {
+		return TokenResponse{}, fmt.Errorf("%s: %w", op, ErrSlowDown)
+	}
+
+	switch device.Status {
+	case models.DeviceAuthorizationPending:
+		return TokenResponse{}, fmt.Errorf("%s: %w", op, ErrAuthorizationPending)
+	case models.DeviceAuthorizationDenied:
+		return TokenResponse{}, fmt.Errorf("%s: %w", op, ErrAccessDenied)
+	case models.DeviceAuthorizationConsumed:
+		return TokenResponse{}, fmt.Errorf("%s: %w", op, ErrInvalidGrant)
+	}
+
+	err = o.oauthSrv.UpdateDeviceAuthorizationStatus(ctx, device.ID, models.DeviceAuthorizationApproved, models.DeviceAuthorizationConsumed, nil)
+	if err != nil {
+		if errors.Is(err, storage.ErrDeviceAuthorizationHandled) {
+			return TokenResponse{}, fmt.Errorf("%s: %w", op, ErrInvalidGrant)
+		}
+		return TokenResponse{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	user, err := o.oauthSrv.UserByID(ctx, *device.UserID)
+	if err == nil && !user.Active(time.Now()) {
+		err = storage.ErrUserNotFound
+	}
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return TokenResponse{}, fmt.Errorf("%s: %w", op, ErrInvalidGrant)
+		}
+		return TokenResponse{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	authTime := device.CreatedAt
+	if device.AuthTime != nil {
+		authTime = *device.AuthTime
+	}
+	sessionID, err := o.openSession(ctx, user, app, client)
+	if err != nil {
+		log.Error("failed to open session")
+
+		return TokenResponse{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	resp, err := o.issueTokens(ctx, user, app, device.Scope, nil, sessionID, "", authTime)
+	if err != nil {
+		log.Error("failed to issue tokens")
+
+		return TokenResponse{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("device token issued", slog.Int64("user_id", user.ID))
+	return resp, nil
+}
+
+func randomUserCode() (string, error) {
+	code := make([]byte, userCodeLen)
+	max := big.NewInt(int64(len(userCodeAlphabet)))
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		code[i] = userCodeAlphabet[n.Int64()]
+	}
+	return string(code), nil
+}
+
+func formatUserCode(code string) string {
+	return code[:userCodeLen/2] + "-" + code[userCodeLen/2:]
+}
+
+func normalizeUserCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToUpper(code))
+}
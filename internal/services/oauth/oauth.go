@@ -0,0 +1,670 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DavidG9999/my_grpc_app/internal/domain/models"
+	"github.com/DavidG9999/my_grpc_app/internal/lib/jwt"
+	"github.com/DavidG9999/my_grpc_app/internal/storage"
+)
+
+const (
+	ResponseTypeCode = "code"
+
+	GrantTypeAuthorizationCode = "authorization_code"
+	GrantTypeRefreshToken      = "refresh_token"
+	GrantTypeClientCredentials = "client_credentials"
+
+	CodeChallengeMethodS256 = "S256"
+
+	TokenTypeBearer = "Bearer"
+
+	ScopeOpenID = "openid"
+
+	secretLen = 32
+
+	minVerifierLen = 43
+	maxVerifierLen = 128
+)
+
+type OAuth struct {
+	log             *slog.Logger
+	oauthSrv        OAuthService
+	tokenSecret     []byte
+	signingKey      jwt.SigningKey
+	issuer          string
+	tokenTTL        time.Duration
+	codeTTL         time.Duration
+	refreshTokenTTL time.Duration
+
+	deviceCodeTTL      time.Duration
+	devicePollInterval time.Duration
+}
+
+type AppProvider interface {
+	App(ctx context.Context, appID int) (models.App, error)
+	RedirectURIs(ctx context.Context, appID int) ([]string, error)
+	IsAppMember(ctx context.Context, appID int, userID int64) (bool, error)
+}
+
+type UserProvider interface {
+	UserByID(ctx context.Context, userID int64) (models.User, error)
+	UserGroups(ctx context.Context, userID int64, effective bool) ([]models.Group, error)
+}
+
+type CodeStorage interface {
+	SaveAuthorizationCode(ctx context.Context, code models.AuthorizationCode) (int64, error)
+	UseAuthorizationCode(ctx context.Context, codeHash []byte) (models.AuthorizationCode, error)
+}
+
+type RefreshTokenStorage interface {
+	SaveRefreshToken(ctx context.Context, token models.RefreshToken) (int64, error)
+	UseRefreshToken(ctx context.Context, appID int, tokenHash []byte) (models.RefreshToken, error)
+	RevokeRefreshTokensByCode(ctx context.Context, codeID int64) error
+}
+
+type SessionStorage interface {
+	SaveSession(ctx context.Context, session models.Session) (int64, error)
+	SessionByID(ctx context.Context, sessionID int64) (models.Session, error)
+	RevokeSession(ctx context.Context, sessionID int64) error
+}
+
+type OAuthService interface {
+	AppProvider
+	UserProvider
+	CodeStorage
+	RefreshTokenStorage
+	DeviceStorage
+	SessionStorage
+}
+
+var (
+	ErrInvalidRequest          = errors.New("invalid request")
+	ErrInvalidClient           = errors.New("invalid client")
+	ErrInvalidRedirectURI      = errors.New("invalid redirect uri")
+	ErrInvalidGrant            = errors.New("invalid grant")
+	ErrUnsupportedResponseType = errors.New("unsupported response type")
+	ErrUnsupportedGrantType    = errors.New("unsupported grant type")
+	ErrInvalidToken            = errors.New("invalid token")
+)
+
+// AuthorizeRequest holds the parameters of an authorization request.
+type AuthorizeRequest struct {
+	ResponseType        string
+	ClientID            string
+	RedirectURI         string
+	Scope               string
+	State               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+	Nonce               string
+}
+
+// TokenRequest holds the parameters of a token request.
+type TokenRequest struct {
+	GrantType    string
+	ClientID     string
+	ClientSecret string
+	Code         string
+	RedirectURI  string
+	CodeVerifier string
+	RefreshToken string
+	DeviceCode   string
+	// Client describes the device the request came from. A new session is
+	// opened for it unless the grant continues an existing one.
+	Client models.ClientInfo
+}
+
+type TokenResponse struct {
+	AccessToken  string
+	TokenType    string
+	ExpiresIn    int64
+	RefreshToken string
+	IDToken      string
+	Scope        string
+}
+
+func NewOAuth(
+	log *slog.Logger,
+	oauthSrv OAuthService,
+	tokenSecret string,
+	signingKey jwt.SigningKey,
+	issuer string,
+	tokenTTL time.Duration,
+	codeTTL time.Duration,
+	refreshTokenTTL time.Duration,
+	deviceCodeTTL time.Duration,
+	devicePollInterval time.Duration,
+) *OAuth {
+	return &OAuth{
+		log:                log,
+		oauthSrv:           oauthSrv,
+		tokenSecret:        []byte(tokenSecret),
+		signingKey:         signingKey,
+		issuer:             issuer,
+		tokenTTL:           tokenTTL,
+		codeTTL:            codeTTL,
+		refreshTokenTTL:    refreshTokenTTL,
+		deviceCodeTTL:      deviceCodeTTL,
+		devicePollInterval: devicePollInterval,
+	}
+}
+
+// ValidateAuthorizeRequest checks the client and redirect URI first: when
+// either is invalid the caller must not redirect back to the client.
+func (o *OAuth) ValidateAuthorizeRequest(ctx context.Context, req AuthorizeRequest) (models.App, error) {
+	const op = "oauth.ValidateAuthorizeRequest"
+
+	log := o.log.With(
+		slog.String("op", op),
+		slog.String("client_id", req.ClientID),
+	)
+
+	app, err := o.client(ctx, req.ClientID)
+	if err != nil {
+		log.Warn("invalid client")
+
+		return models.App{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	uris, err := o.oauthSrv.RedirectURIs(ctx, app.ID)
+	if err != nil {
+		log.Error("failed to get redirect uris")
+
+		return models.App{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if !slices.Contains(uris, req.RedirectURI) {
+		log.Warn("redirect uri is not registered", slog.String("redirect_uri", req.RedirectURI))
+
+		return models.App{}, fmt.Errorf("%s: %w", op, ErrInvalidRedirectURI)
+	}
+
+	if req.ResponseType != ResponseTypeCode {
+		return app, fmt.Errorf("%s: %w", op, ErrUnsupportedResponseType)
+	}
+	if req.CodeChallenge == "" || req.CodeChallengeMethod != CodeChallengeMethodS256 {
+		return app, fmt.Errorf("%s: %w: code_challenge with S256 method is required", op, ErrInvalidRequest)
+	}
+
+	return app, nil
+}
+
+// IssueCode creates a single-use authorization code for an authenticated user.
+func (o *OAuth) IssueCode(ctx context.Context, req AuthorizeRequest, userID int64) (string, error) {
+	const op = "oauth.IssueCode"
+
+	log := o.log.With(
+		slog.String("op", op),
+		slog.String("client_id", req.ClientID),
+		slog.Int64("user_id", userID),
+	)
+
+	app, err := o.ValidateAuthorizeRequest(ctx, req)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	if err := o.checkAppAccess(ctx, app, userID); err != nil {
+		log.Warn("user is not a member of the app")
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	code, err := randomSecret()
+	if err != nil {
+		log.Error("failed to generate code")
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	now := time.Now()
+	_, err = o.oauthSrv.SaveAuthorizationCode(ctx, models.AuthorizationCode{
+		CodeHash:            hashSecret(code),
+		AppID:               app.ID,
+		UserID:              userID,
+		RedirectURI:         req.RedirectURI,
+		Scope:               req.Scope,
+		CodeChallenge:       req.CodeChallenge,
+		CodeChallengeMethod: req.CodeChallengeMethod,
+		Nonce:               req.Nonce,
+		AuthTime:            now,
+		CreatedAt:           now,
+		ExpiresAt:           now.Add(o.codeTTL),
+	})
+	if err != nil {
+		log.Error("failed to save code")
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("authorization code issued")
+	return code, nil
+}
+
+func (o *OAuth) Token(ctx context.Context, req TokenRequest) (TokenResponse, error) {
+	const op = "oauth.Token"
+
+	var (
+		resp TokenResponse
+		err  error
+	)
+	switch req.GrantType {
+	case GrantTypeAuthorizationCode:
+		resp, err = o.exchangeCode(ctx, req)
+	case GrantTypeRefreshToken:
+		resp, err = o.refresh(ctx, req)
+	case GrantTypeDeviceCode:
+		resp, err = o.PollDeviceToken(ctx, req.ClientID, req.DeviceCode, req.Client)
+	default:
+		err = ErrUnsupportedGrantType
+	}
+	if err != nil {
+		return TokenResponse{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return resp, nil
+}
+
+func (o *OAuth) exchangeCode(ctx context.Context, req TokenRequest) (TokenResponse, error) {
+	const op = "oauth.exchangeCode"
+
+	log := o.log.With(
+		slog.String("op", op),
+		slog.String("client_id", req.ClientID),
+	)
+
+	app, err := o.authenticateClient(ctx, req.ClientID, req.ClientSecret)
+	if err != nil {
+		log.Warn("invalid client")
+
+		return TokenResponse{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if req.Code == "" || req.CodeVerifier == "" {
+		return TokenResponse{}, fmt.Errorf("%s: %w: code and code_verifier are required", op, ErrInvalidRequest)
+	}
+
+	code, err := o.oauthSrv.UseAuthorizationCode(ctx, hashSecret(req.Code))
+	if err != nil {
+		if errors.Is(err, storage.ErrAuthorizationCodeUsed) {
+			log.Warn("authorization code reused, revoking issued tokens", slog.Int64("code_id", code.ID))
+
+			if err := o.oauthSrv.RevokeRefreshTokensByCode(ctx, code.ID); err != nil {
+				log.Error("failed to revoke tokens issued from reused code")
+			}
+			return TokenResponse{}, fmt.Errorf("%s: %w", op, ErrInvalidGrant)
+		}
+		if errors.Is(err, storage.ErrAuthorizationCodeNotFound) {
+			return TokenResponse{}, fmt.Errorf("%s: %w", op, ErrInvalidGrant)
+		}
+		log.Error("failed to use authorization code")
+
+		return TokenResponse{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if code.AppID != app.ID || code.RedirectURI != req.RedirectURI || time.Now().After(code.ExpiresAt) {
+		log.Warn("authorization code does not match the request")
+
+		return TokenResponse{}, fmt.Errorf("%s: %w", op, ErrInvalidGrant)
+	}
+	if !verifyCodeChallenge(code.CodeChallenge, req.CodeVerifier) {
+		log.Warn("code verifier does not match")
+
+		return TokenResponse{}, fmt.Errorf("%s: %w", op, ErrInvalidGrant)
+	}
+
+	user, err := o.oauthSrv.UserByID(ctx, code.UserID)
+	if err == nil && !user.Active(time.Now()) {
+		err = storage.ErrUserNotFound
+	}
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return TokenResponse{}, fmt.Errorf("%s: %w", op, ErrInvalidGrant)
+		}
+		return TokenResponse{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	sessionID, err := o.openSession(ctx, user, app, req.Client)
+	if err != nil {
+		log.Error("failed to open session")
+
+		return TokenResponse{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	resp, err := o.issueTokens(ctx, user, app, code.Scope, &code.ID, sessionID, code.Nonce, code.AuthTime)
+	if err != nil {
+		log.Error("failed to issue tokens")
+
+		return TokenResponse{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("authorization code exchanged", slog.Int64("user_id", user.ID))
+	return resp, nil
+}
+
+func (o *OAuth) refresh(ctx context.Context, req TokenRequest) (TokenResponse, error) {
+	const op = "oauth.refresh"
+
+	log := o.log.With(
+		slog.String("op", op),
+		slog.String("client_id", req.ClientID),
+	)
+
+	app, err := o.authenticateClient(ctx, req.ClientID, req.ClientSecret)
+	if err != nil {
+		log.Warn("invalid client")
+
+		return TokenResponse{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if req.RefreshToken == "" {
+		return TokenResponse{}, fmt.Errorf("%s: %w: refresh_token is required", op, ErrInvalidRequest)
+	}
+
+	token, err := o.oauthSrv.UseRefreshToken(ctx, app.ID, hashSecret(req.RefreshToken))
+	if err != nil {
+		if errors.Is(err, storage.ErrRefreshTokenUsed) {
+			log.Warn("refresh token reused, revoking its session", slog.Int64("token_id", token.ID))
+
+			o.revokeRefreshTokenFamily(ctx, log, token)
+			return TokenResponse{}, fmt.Errorf("%s: %w", op, ErrInvalidGrant)
+		}
+		if errors.Is(err, storage.ErrRefreshTokenNotFound) {
+			return TokenResponse{}, fmt.Errorf("%s: %w", op, ErrInvalidGrant)
+		}
+		log.Error("failed to use refresh token")
+
+		return TokenResponse{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if time.Now().After(token.ExpiresAt) {
+		log.Warn("refresh token expired", slog.Int64("token_id", token.ID))
+
+		return TokenResponse{}, fmt.Errorf("%s: %w", op, ErrInvalidGrant)
+	}
+
+	user, err := o.oauthSrv.UserByID(ctx, token.UserID)
+	if err == nil && (!user.Active(time.Now()) || user.OrgID != app.OrgID) {
+		err = storage.ErrUserNotFound
+	}
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return TokenResponse{}, fmt.Errorf("%s: %w", op, ErrInvalidGrant)
+		}
+		return TokenResponse{}, fmt.Errorf("%s: %w", op, err)
+	}
+	// Users removed from the app since lose it here, the token is used up
+	// already.
+	if err := o.checkAppAccess(ctx, app, user.ID); err != nil {
+		if errors.Is(err, ErrAccessDenied) {
+			log.Warn("user is not a member of the app", slog.Int64("user_id", user.ID))
+
+			return TokenResponse{}, fmt.Errorf("%s: %w", op, ErrInvalidGrant)
+		}
+		return TokenResponse{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Refresh tokens issued before sessions were tracked get a session now.
+	// Those of revoked sessions are not valid: a reuse of the token they were
+	// rotated from may have revoked the session meanwhile.
+	var sessionID int64
+	if token.SessionID != nil {
+		session, err := o.oauthSrv.SessionByID(ctx, *token.SessionID)
+		if err == nil && session.RevokedAt != nil {
+			err = storage.ErrSessionNotFound
+		}
+		if err != nil {
+			if errors.Is(err, storage.ErrSessionNotFound) {
+				log.Warn("session of refresh token revoked", slog.Int64("token_id", token.ID))
+
+				return TokenResponse{}, fmt.Errorf("%s: %w", op, ErrInvalidGrant)
+			}
+			return TokenResponse{}, fmt.Errorf("%s: %w", op, err)
+		}
+		sessionID = session.ID
+	} else {
+		sessionID, err = o.openSession(ctx, user, app, req.Client)
+		if err != nil {
+			log.Error("failed to open session")
+
+			return TokenResponse{}, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	resp, err := o.issueTokens(ctx, user, app, token.Scope, token.AuthorizationCodeID, sessionID, "", token.AuthTime)
+	if err != nil {
+		log.Error("failed to issue tokens")
+
+		return TokenResponse{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("refresh token rotated", slog.Int64("user_id", user.ID))
+	return resp, nil
+}
+
+// revokeRefreshTokenFamily revokes what a reused refresh token was rotated
+// into: its session, or the tokens issued from its authorization code where
+// it has no session.
+func (o *OAuth) revokeRefreshTokenFamily(ctx context.Context, log *slog.Logger, token models.RefreshToken) {
+	switch {
+	case token.SessionID != nil:
+		if err := o.oauthSrv.RevokeSession(ctx, *token.SessionID); err != nil {
+			log.Error("failed to revoke session of reused refresh token")
+		}
+	case token.AuthorizationCodeID != nil:
+		if err := o.oauthSrv.RevokeRefreshTokensByCode(ctx, *token.AuthorizationCodeID); err != nil {
+			log.Error("failed to revoke tokens issued from code of reused refresh token")
+		}
+	}
+}
+
+func (o *OAuth) openSession(ctx context.Context, user models.User, app models.App, client models.ClientInfo) (int64, error) {
+	now := time.Now()
+	return o.oauthSrv.SaveSession(ctx, models.Session{
+		UserID:     user.ID,
+		AppID:      app.ID,
+		Device:     client.Device,
+		IP:         client.IP,
+		UserAgent:  client.UserAgent,
+		CreatedAt:  now,
+		LastSeenAt: now,
+	})
+}
+
+func (o *OAuth) issueTokens(ctx context.Context, user models.User, app models.App, scope string, codeID *int64, sessionID int64, nonce string, authTime time.Time) (TokenResponse, error) {
+	var groups []string
+	if app.GroupsClaim {
+		userGroups, err := o.oauthSrv.UserGroups(ctx, user.ID, true)
+		if err != nil {
+			return TokenResponse{}, err
+		}
+		groups = make([]string, 0, len(userGroups))
+		for _, group := range userGroups {
+			groups = append(groups, group.Name)
+		}
+	}
+
+	accessToken, err := jwt.NewToken(user, app, o.tokenSecret, sessionID, groups, o.tokenTTL)
+	if err != nil {
+		return TokenResponse{}, err
+	}
+
+	var idToken string
+	if hasScope(scope, ScopeOpenID) {
+		idToken, err = jwt.NewIDToken(user, app, o.signingKey, o.issuer, nonce, authTime, groups, o.tokenTTL)
+		if err != nil {
+			return TokenResponse{}, err
+		}
+	}
+
+	refreshToken, err := randomSecret()
+	if err != nil {
+		return TokenResponse{}, err
+	}
+
+	now := time.Now()
+	_, err = o.oauthSrv.SaveRefreshToken(ctx, models.RefreshToken{
+		TokenHash:           hashSecret(refreshToken),
+		AppID:               app.ID,
+		UserID:              user.ID,
+		AuthorizationCodeID: codeID,
+		SessionID:           &sessionID,
+		Scope:               scope,
+		AuthTime:            authTime,
+		CreatedAt:           now,
+		ExpiresAt:           now.Add(o.refreshTokenTTL),
+	})
+	if err != nil {
+		return TokenResponse{}, err
+	}
+
+	return TokenResponse{
+		AccessToken:  accessToken,
+		TokenType:    TokenTypeBearer,
+		ExpiresIn:    int64(o.tokenTTL.Seconds()),
+		RefreshToken: refreshToken,
+		IDToken:      idToken,
+		Scope:        scope,
+	}, nil
+}
+
+func (o *OAuth) Issuer() string {
+	return o.issuer
+}
+
+// SigningKeys returns the public keys ID tokens are signed with.
+func (o *OAuth) SigningKeys() []jwt.JWK {
+	return []jwt.JWK{o.signingKey.JWK()}
+}
+
+// UserInfo returns the owner of an access token.
+func (o *OAuth) UserInfo(ctx context.Context, accessToken string) (models.User, error) {
+	const op = "oauth.UserInfo"
+
+	log := o.log.With(slog.String("op", op))
+
+	appID, err := jwt.AppID(accessToken)
+	if err != nil {
+		return models.User{}, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+
+	app, err := o.oauthSrv.App(ctx, appID)
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			return models.User{}, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+		}
+		return models.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	claims, err := jwt.ParseToken(accessToken, app, o.tokenSecret)
+	if err != nil {
+		log.Warn("invalid access token")
+
+		return models.User{}, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+	// Tokens without a session are API key tokens, which are not for OIDC.
+	session, err := o.oauthSrv.SessionByID(ctx, claims.SessionID)
+	if err != nil {
+		if errors.Is(err, storage.ErrSessionNotFound) {
+			return models.User{}, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+		}
+		return models.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if session.RevokedAt != nil || session.UserID != claims.UserID || session.AppID != claims.SessionAppID() {
+		log.Warn("session is revoked or not the token's", slog.Int64("session_id", session.ID))
+
+		return models.User{}, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+
+	user, err := o.oauthSrv.UserByID(ctx, claims.UserID)
+	if err == nil && (!user.Active(time.Now()) || user.OrgID != app.OrgID) {
+		err = storage.ErrUserNotFound
+	}
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return models.User{}, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+		}
+		return models.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return user, nil
+}
+
+// checkAppAccess returns ErrAccessDenied if the app is invite-only and the
+// user is not one of its members.
+func (o *OAuth) checkAppAccess(ctx context.Context, app models.App, userID int64) error {
+	if !app.InviteOnly {
+		return nil
+	}
+	member, err := o.oauthSrv.IsAppMember(ctx, app.ID, userID)
+	if err != nil {
+		return err
+	}
+	if !member {
+		return ErrAccessDenied
+	}
+	return nil
+}
+
+func (o *OAuth) client(ctx context.Context, clientID string) (models.App, error) {
+	appID, err := strconv.Atoi(clientID)
+	if err != nil {
+		return models.App{}, ErrInvalidClient
+	}
+
+	app, err := o.oauthSrv.App(ctx, appID)
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			return models.App{}, ErrInvalidClient
+		}
+		return models.App{}, err
+	}
+	return app, nil
+}
+
+// authenticateClient looks up the client and verifies its secret. Only public
+// clients may leave it out, they rely on PKCE alone.
+func (o *OAuth) authenticateClient(ctx context.Context, clientID string, clientSecret string) (models.App, error) {
+	app, err := o.client(ctx, clientID)
+	if err != nil {
+		return models.App{}, err
+	}
+	if clientSecret == "" && app.Public {
+		return app, nil
+	}
+	if subtle.ConstantTimeCompare([]byte(clientSecret), []byte(app.Secret)) != 1 {
+		return models.App{}, ErrInvalidClient
+	}
+	return app, nil
+}
+
+func verifyCodeChallenge(challenge string, verifier string) bool {
+	if len(verifier) < minVerifierLen || len(verifier) > maxVerifierLen {
+		return false
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	expected := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(challenge)) == 1
+}
+
+func hasScope(scope string, want string) bool {
+	return slices.Contains(strings.Fields(scope), want)
+}
+
+func randomSecret() (string, error) {
+	b := make([]byte, secretLen)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashSecret(secret string) []byte {
+	sum := sha256.Sum256([]byte(secret))
+	return sum[:]
+}
@@ -0,0 +1,153 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/DavidG9999/my_grpc_app/internal/domain/models"
+)
+
+const apiKeyColumns = "id, user_id, app_id, name, key_hash, scope, created_at, expires_at, last_used_at, revoked_at"
+
+type APIKeyStorage struct {
+	db *sql.DB
+}
+
+func NewAPIKeyStorage(db *sql.DB) *APIKeyStorage {
+	return &APIKeyStorage{db: db}
+}
+
+func (s *APIKeyStorage) SaveAPIKey(ctx context.Context, key models.APIKey) (int64, error) {
+	const op = "storage.sqlite.SaveAPIKey"
+
+	stmp, err := s.db.Prepare(`INSERT INTO api_keys(user_id, app_id, name, key_hash, scope, created_at, expires_at)
+		VALUES(?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	res, err := stmp.ExecContext(ctx, key.UserID, key.AppID, key.Name, key.KeyHash, key.Scope, key.CreatedAt, key.ExpiresAt)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return id, nil
+}
+
+func (s *APIKeyStorage) APIKeyByID(ctx context.Context, keyID int64) (models.APIKey, error) {
+	const op = "storage.sqlite.APIKeyByID"
+
+	stmp, err := s.db.Prepare("SELECT " + apiKeyColumns + " FROM api_keys WHERE id=?")
+	if err != nil {
+		return models.APIKey{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	key, err := scanAPIKey(stmp.QueryRowContext(ctx, keyID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.APIKey{}, fmt.Errorf("%s: %w", op, ErrAPIKeyNotFound)
+		}
+		return models.APIKey{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return key, nil
+}
+
+func (s *APIKeyStorage) APIKeyByHash(ctx context.Context, keyHash []byte) (models.APIKey, error) {
+	const op = "storage.sqlite.APIKeyByHash"
+
+	stmp, err := s.db.Prepare("SELECT " + apiKeyColumns + " FROM api_keys WHERE key_hash=?")
+	if err != nil {
+		return models.APIKey{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	key, err := scanAPIKey(stmp.QueryRowContext(ctx, keyHash))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.APIKey{}, fmt.Errorf("%s: %w", op, ErrAPIKeyNotFound)
+		}
+		return models.APIKey{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return key, nil
+}
+
+// UserAPIKeys returns the keys of the user that are not revoked, newest first.
+// Expired keys are included so that their owners can see why they stopped
+// working.
+func (s *APIKeyStorage) UserAPIKeys(ctx context.Context, userID int64) ([]models.APIKey, error) {
+	const op = "storage.sqlite.UserAPIKeys"
+
+	stmp, err := s.db.Prepare("SELECT " + apiKeyColumns + ` FROM api_keys
+		WHERE user_id=? AND revoked_at IS NULL ORDER BY created_at DESC, id DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err := stmp.QueryContext(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var keys []models.APIKey
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return keys, nil
+}
+
+func (s *APIKeyStorage) TouchAPIKey(ctx context.Context, keyID int64, lastUsedAt time.Time) error {
+	const op = "storage.sqlite.TouchAPIKey"
+
+	stmp, err := s.db.Prepare("UPDATE api_keys SET last_used_at=? WHERE id=?")
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := stmp.ExecContext(ctx, lastUsedAt, keyID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// RevokeAPIKey revokes the key. Revoking it again is not an error.
+func (s *APIKeyStorage) RevokeAPIKey(ctx context.Context, keyID int64) error {
+	const op = "storage.sqlite.RevokeAPIKey"
+
+	stmp, err := s.db.Prepare("UPDATE api_keys SET revoked_at=COALESCE(revoked_at, ?) WHERE id=?")
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	res, err := stmp.ExecContext(ctx, time.Now().UTC(), keyID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, ErrAPIKeyNotFound)
+	}
+	return nil
+}
+
+func scanAPIKey(row rowScanner) (models.APIKey, error) {
+	var key models.APIKey
+	err := row.Scan(&key.ID, &key.UserID, &key.AppID, &key.Name, &key.KeyHash, &key.Scope,
+		&key.CreatedAt, &key.ExpiresAt, &key.LastUsedAt, &key.RevokedAt)
+	return key, err
+}
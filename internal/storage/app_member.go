@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// AddAppMember makes the user a member of the app. Adding a member again
+// is not an error.
+func (s *AuthStorage) AddAppMember(ctx context.Context, appID int, userID int64) error {
+	const op = "storage.sqlite.AddAppMember"
+
+	stmp, err := s.db.Prepare("INSERT INTO app_members(app_id, user_id, created_at) VALUES(?, ?, ?) ON CONFLICT DO NOTHING")
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = stmp.ExecContext(ctx, appID, userID, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (s *AuthStorage) RemoveAppMember(ctx context.Context, appID int, userID int64) error {
+	const op = "storage.sqlite.RemoveAppMember"
+
+	stmp, err := s.db.Prepare("DELETE FROM app_members WHERE app_id=? AND user_id=?")
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	res, err := stmp.ExecContext(ctx, appID, userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, ErrAppMemberNotFound)
+	}
+	return nil
+}
+
+func (s *AuthStorage) IsAppMember(ctx context.Context, appID int, userID int64) (bool, error) {
+	const op = "storage.sqlite.IsAppMember"
+
+	stmp, err := s.db.Prepare("SELECT 1 FROM app_members WHERE app_id=? AND user_id=?")
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	row := stmp.QueryRowContext(ctx, appID, userID)
+
+	var member int
+	err = row.Scan(&member)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	return true, nil
+}
@@ -0,0 +1,38 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/DavidG9999/my_grpc_app/internal/domain/models"
+)
+
+type AuditStorage struct {
+	db *sql.DB
+}
+
+func NewAuditStorage(db *sql.DB) *AuditStorage {
+	return &AuditStorage{db: db}
+}
+
+func (s *AuditStorage) SaveAuditEvent(ctx context.Context, event models.AuditEvent) (int64, error) {
+	const op = "storage.sqlite.SaveAuditEvent"
+
+	stmp, err := s.db.Prepare(`INSERT INTO audit_events(actor_id, action, target_user_id, app_id, session_id, change_id, reason, ip, created_at)
+		VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	res, err := stmp.ExecContext(ctx, event.ActorID, event.Action, event.TargetUserID, event.AppID, event.SessionID,
+		event.ChangeID, event.Reason, event.IP, event.CreatedAt)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return id, nil
+}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/DavidG9999/my_grpc_app/internal/domain/models"
 	"github.com/mattn/go-sqlite3"
@@ -18,15 +19,15 @@ func NewAuthStorage(db *sql.DB) *AuthStorage {
 	return &AuthStorage{db: db}
 }
 
-func (s *AuthStorage) SaveUser(ctx context.Context, name string, email string, passwordHash []byte, isAdmin bool) (int64, error) {
+func (s *AuthStorage) SaveUser(ctx context.Context, orgID int64, name string, email string, passwordHash []byte, isAdmin bool) (int64, error) {
 	const op = "storage.sqlite.SaveUser"
 
-	stmp, err := s.db.Prepare("INSERT INTO users(name, email, password_hash, is_admin) VALUES(?, ?, ?, ?)")
+	stmp, err := s.db.Prepare("INSERT INTO users(org_id, name, email, password_hash, is_admin, created_at) VALUES(?, ?, ?, ?, ?, ?)")
 	if err != nil {
 		return 0, fmt.Errorf("%s: %w", op, err)
 	}
 
-	res, err := stmp.ExecContext(ctx, name, email, passwordHash, isAdmin)
+	res, err := stmp.ExecContext(ctx, orgID, name, email, passwordHash, isAdmin, time.Now().UTC())
 	if err != nil {
 		var sqliteErr sqlite3.Error
 
@@ -43,17 +44,165 @@ func (s *AuthStorage) SaveUser(ctx context.Context, name string, email string, p
 	return id, nil
 }
 
-func (s *AuthStorage) User(ctx context.Context, email string) (models.User, error) {
+func (s *AuthStorage) UpdatePasswordHash(ctx context.Context, userID int64, passwordHash []byte) error {
+	const op = "storage.sqlite.UpdatePasswordHash"
+
+	stmp, err := s.db.Prepare("UPDATE users SET password_hash=? WHERE id=?")
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	res, err := stmp.ExecContext(ctx, passwordHash, userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, ErrUserNotFound)
+	}
+	return nil
+}
+
+// UpdateUser sets the user's name and email. A changed email is no longer
+// verified.
+func (s *AuthStorage) UpdateUser(ctx context.Context, userID int64, name string, email string) error {
+	const op = "storage.sqlite.UpdateUser"
+
+	stmp, err := s.db.Prepare(`UPDATE users SET name=?, email_verified=(email_verified AND email=?), email=?
+		WHERE id=?`)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	res, err := stmp.ExecContext(ctx, name, email, email, userID)
+	if err != nil {
+		var sqliteErr sqlite3.Error
+
+		if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+			return fmt.Errorf("%s: %w", op, ErrUserExists)
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, ErrUserNotFound)
+	}
+	return nil
+}
+
+func (s *AuthStorage) SetOrgAdmin(ctx context.Context, userID int64, isOrgAdmin bool) error {
+	const op = "storage.sqlite.SetOrgAdmin"
+
+	stmp, err := s.db.Prepare("UPDATE users SET is_org_admin=? WHERE id=?")
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	res, err := stmp.ExecContext(ctx, isOrgAdmin, userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, ErrUserNotFound)
+	}
+	return nil
+}
+
+// SetAdmin grants or takes away global administration. The last admin is
+// never demoted: the check and the update are one statement, so concurrent
+// demotions cannot leave the service without admins.
+func (s *AuthStorage) SetAdmin(ctx context.Context, userID int64, isAdmin bool) error {
+	const op = "storage.sqlite.SetAdmin"
+
+	stmp, err := s.db.Prepare(`UPDATE users SET is_admin=? WHERE id=?
+		AND (? OR NOT is_admin OR (SELECT COUNT(*) FROM users WHERE is_admin) > 1)`)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	res, err := stmp.ExecContext(ctx, isAdmin, userID, isAdmin)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		if _, err := s.UserByID(ctx, userID); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		return fmt.Errorf("%s: %w", op, ErrLastAdmin)
+	}
+	return nil
+}
+
+// SetUserStatus sets the status of the user. suspendedUntil is only kept for
+// suspended users.
+func (s *AuthStorage) SetUserStatus(ctx context.Context, userID int64, status models.UserStatus, reason string, suspendedUntil *time.Time) error {
+	const op = "storage.sqlite.SetUserStatus"
+
+	stmp, err := s.db.Prepare("UPDATE users SET status=?, status_reason=?, suspended_until=? WHERE id=?")
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if status != models.UserStatusSuspended {
+		suspendedUntil = nil
+	}
+	res, err := stmp.ExecContext(ctx, status, reason, suspendedUntil, userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, ErrUserNotFound)
+	}
+	return nil
+}
+
+func (s *AuthStorage) User(ctx context.Context, orgID int64, email string) (models.User, error) {
 	const op = "storage.sqlite.User"
 
-	stmp, err := s.db.Prepare("SELECT id, name, email, password_hash FROM users WHERE email=?")
+	stmp, err := s.db.Prepare("SELECT " + userColumns + " FROM users WHERE org_id=? AND email=?")
 	if err != nil {
 		return models.User{}, fmt.Errorf("%s: %w", op, err)
 	}
-	row := stmp.QueryRowContext(ctx, email)
+	row := stmp.QueryRowContext(ctx, orgID, email)
 
-	var user models.User
-	err = row.Scan(&user.ID, &user.Name, &user.Email, &user.PasswordHash)
+	user, err := scanUser(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.User{}, fmt.Errorf("%s: %w", op, ErrUserNotFound)
+		}
+		return models.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return user, nil
+}
+
+func (s *AuthStorage) UserByID(ctx context.Context, userID int64) (models.User, error) {
+	const op = "storage.sqlite.UserByID"
+
+	stmp, err := s.db.Prepare("SELECT " + userColumns + " FROM users WHERE id=?")
+	if err != nil {
+		return models.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+	row := stmp.QueryRowContext(ctx, userID)
+
+	user, err := scanUser(row)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return models.User{}, fmt.Errorf("%s: %w", op, ErrUserNotFound)
@@ -83,10 +232,44 @@ func (s *AuthStorage) IsAdmin(ctx context.Context, userID int64) (bool, error) {
 	return isAdmin, nil
 }
 
+// SaveFirstAdmin saves a global admin unless there already is one, checking
+// and inserting in a single statement so concurrent calls create one admin.
+func (s *AuthStorage) SaveFirstAdmin(ctx context.Context, orgID int64, name string, email string, passwordHash []byte) (int64, error) {
+	const op = "storage.sqlite.SaveFirstAdmin"
+
+	stmp, err := s.db.Prepare(`INSERT INTO users(org_id, name, email, password_hash, is_admin, created_at)
+		SELECT ?, ?, ?, ?, TRUE, ? WHERE NOT EXISTS (SELECT 1 FROM users WHERE is_admin)`)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	res, err := stmp.ExecContext(ctx, orgID, name, email, passwordHash, time.Now().UTC())
+	if err != nil {
+		var sqliteErr sqlite3.Error
+
+		if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+			return 0, fmt.Errorf("%s: %w", op, ErrUserExists)
+		}
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return 0, fmt.Errorf("%s: %w", op, ErrAdminExists)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return id, nil
+}
+
 func (s *AuthStorage) App(ctx context.Context, appID int) (models.App, error) {
 	const op = "storage.sqlite.App"
 
-	stmp, err := s.db.Prepare("SELECT * FROM apps WHERE id=?")
+	stmp, err := s.db.Prepare("SELECT id, org_id, name, secret, invite_only, groups_claim, public FROM apps WHERE id=?")
 	if err != nil {
 		return models.App{}, fmt.Errorf("%s: %w", op, err)
 	}
@@ -94,7 +277,7 @@ func (s *AuthStorage) App(ctx context.Context, appID int) (models.App, error) {
 	row := stmp.QueryRowContext(ctx, appID)
 
 	var app models.App
-	err = row.Scan(&app.ID, &app.Name, &app.Secret)
+	err = row.Scan(&app.ID, &app.OrgID, &app.Name, &app.Secret, &app.InviteOnly, &app.GroupsClaim, &app.Public)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return models.App{}, fmt.Errorf("%s: %w", op, ErrAppNotFound)
@@ -103,3 +286,161 @@ func (s *AuthStorage) App(ctx context.Context, appID int) (models.App, error) {
 	}
 	return app, nil
 }
+
+// UpdateAppSecret replaces the secret of the app. Tokens signed with the old
+// secret stop validating.
+func (s *AuthStorage) UpdateAppSecret(ctx context.Context, appID int, secret string) error {
+	const op = "storage.sqlite.UpdateAppSecret"
+
+	stmp, err := s.db.Prepare("UPDATE apps SET secret=? WHERE id=?")
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	res, err := stmp.ExecContext(ctx, secret, appID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, ErrAppNotFound)
+	}
+	return nil
+}
+
+// appTables are the tables whose rows belong to an app and go with it.
+var appTables = []string{
+	"app_redirect_uris",
+	"app_scopes",
+	"app_members",
+	"authorization_codes",
+	"refresh_tokens",
+	"device_authorizations",
+	"sessions",
+	"api_keys",
+}
+
+// DeleteApp deletes the app with everything issued for it. Foreign keys are
+// not enforced on every connection, so the dependent rows are deleted here.
+func (s *AuthStorage) DeleteApp(ctx context.Context, appID int) error {
+	const op = "storage.sqlite.DeleteApp"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	for _, table := range appTables {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM "+table+" WHERE app_id=?", appID); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+	_, err = tx.ExecContext(ctx, "DELETE FROM token_exchange_policies WHERE source_app_id=? OR target_app_id=?", appID, appID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	res, err := tx.ExecContext(ctx, "DELETE FROM apps WHERE id=?", appID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, ErrAppNotFound)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// OrgAppIDs returns the IDs of the apps of the organization.
+func (s *AuthStorage) OrgAppIDs(ctx context.Context, orgID int64) ([]int, error) {
+	const op = "storage.sqlite.OrgAppIDs"
+
+	stmp, err := s.db.Prepare("SELECT id FROM apps WHERE org_id=? ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err := stmp.QueryContext(ctx, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var appIDs []int
+	for rows.Next() {
+		var appID int
+		if err := rows.Scan(&appID); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		appIDs = append(appIDs, appID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return appIDs, nil
+}
+
+func (s *AuthStorage) AppScopes(ctx context.Context, appID int) ([]string, error) {
+	const op = "storage.sqlite.AppScopes"
+
+	stmp, err := s.db.Prepare("SELECT scope FROM app_scopes WHERE app_id=? ORDER BY scope")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err := stmp.QueryContext(ctx, appID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var scopes []string
+	for rows.Next() {
+		var scope string
+		if err := rows.Scan(&scope); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		scopes = append(scopes, scope)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return scopes, nil
+}
+
+func (s *AuthStorage) ExchangeScopes(ctx context.Context, sourceAppID int, targetAppID int) ([]string, error) {
+	const op = "storage.sqlite.ExchangeScopes"
+
+	stmp, err := s.db.Prepare("SELECT scope FROM token_exchange_policies WHERE source_app_id=? AND target_app_id=? ORDER BY scope")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err := stmp.QueryContext(ctx, sourceAppID, targetAppID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var scopes []string
+	for rows.Next() {
+		var scope string
+		if err := rows.Scan(&scope); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		scopes = append(scopes, scope)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return scopes, nil
+}
@@ -0,0 +1,129 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/DavidG9999/my_grpc_app/internal/domain/models"
+)
+
+const changeColumns = "id, kind, proposer_id, target_user_id, app_id, reason, status, decided_by, decided_at, created_at, expires_at"
+
+type ChangeStorage struct {
+	db *sql.DB
+}
+
+func NewChangeStorage(db *sql.DB) *ChangeStorage {
+	return &ChangeStorage{db: db}
+}
+
+func (s *ChangeStorage) SaveChange(ctx context.Context, change models.PendingChange) (int64, error) {
+	const op = "storage.sqlite.SaveChange"
+
+	stmp, err := s.db.Prepare(`INSERT INTO pending_changes(kind, proposer_id, target_user_id, app_id, reason, status, created_at, expires_at)
+		VALUES(?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	res, err := stmp.ExecContext(ctx, change.Kind, change.ProposerID, change.TargetUserID, change.AppID, change.Reason,
+		models.ChangePending, change.CreatedAt, change.ExpiresAt)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return id, nil
+}
+
+func (s *ChangeStorage) ChangeByID(ctx context.Context, changeID int64) (models.PendingChange, error) {
+	const op = "storage.sqlite.ChangeByID"
+
+	stmp, err := s.db.Prepare("SELECT " + changeColumns + " FROM pending_changes WHERE id=?")
+	if err != nil {
+		return models.PendingChange{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	change, err := scanChange(stmp.QueryRowContext(ctx, changeID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.PendingChange{}, fmt.Errorf("%s: %w", op, ErrChangeNotFound)
+		}
+		return models.PendingChange{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return change, nil
+}
+
+// DecideChange approves or rejects a pending change that has not expired.
+// Only one decision is ever taken on a change, so concurrent approvals cannot
+// carry it out twice.
+func (s *ChangeStorage) DecideChange(ctx context.Context, changeID int64, status models.ChangeStatus, deciderID int64, decidedAt time.Time) error {
+	const op = "storage.sqlite.DecideChange"
+
+	stmp, err := s.db.Prepare(`UPDATE pending_changes SET status=?, decided_by=?, decided_at=?
+		WHERE id=? AND status=? AND expires_at>?`)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	res, err := stmp.ExecContext(ctx, status, deciderID, decidedAt, changeID, models.ChangePending, decidedAt)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, ErrChangeDecided)
+	}
+	return nil
+}
+
+// ReopenChange puts an approved change back in the queue when it could not
+// be carried out.
+func (s *ChangeStorage) ReopenChange(ctx context.Context, changeID int64) error {
+	const op = "storage.sqlite.ReopenChange"
+
+	stmp, err := s.db.Prepare("UPDATE pending_changes SET status=?, decided_by=NULL, decided_at=NULL WHERE id=? AND status=?")
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if _, err := stmp.ExecContext(ctx, models.ChangePending, changeID, models.ChangeApproved); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// ExpireChanges marks the pending changes that were not decided in time as
+// expired and returns how many there were.
+func (s *ChangeStorage) ExpireChanges(ctx context.Context, now time.Time) (int64, error) {
+	const op = "storage.sqlite.ExpireChanges"
+
+	stmp, err := s.db.Prepare("UPDATE pending_changes SET status=? WHERE status=? AND expires_at<=?")
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	res, err := stmp.ExecContext(ctx, models.ChangeExpired, models.ChangePending, now)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return affected, nil
+}
+
+func scanChange(row rowScanner) (models.PendingChange, error) {
+	var change models.PendingChange
+	err := row.Scan(&change.ID, &change.Kind, &change.ProposerID, &change.TargetUserID, &change.AppID, &change.Reason,
+		&change.Status, &change.DecidedBy, &change.DecidedAt, &change.CreatedAt, &change.ExpiresAt)
+	return change, err
+}
@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/DavidG9999/my_grpc_app/internal/domain/models"
+)
+
+type EmailVerificationStorage struct {
+	db *sql.DB
+}
+
+func NewEmailVerificationStorage(db *sql.DB) *EmailVerificationStorage {
+	return &EmailVerificationStorage{db: db}
+}
+
+func (s *EmailVerificationStorage) SaveEmailVerification(ctx context.Context, verification models.EmailVerification) (int64, error) {
+	const op = "storage.sqlite.SaveEmailVerification"
+
+	stmp, err := s.db.Prepare("INSERT INTO email_verifications(user_id, email, token_hash, created_at, expires_at) VALUES(?, ?, ?, ?, ?)")
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	res, err := stmp.ExecContext(ctx, verification.UserID, verification.Email, verification.TokenHash, verification.CreatedAt, verification.ExpiresAt)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return id, nil
+}
+
+// UseEmailVerification marks the verification with the token hash as used
+// and returns it. A token is only ever used once.
+func (s *EmailVerificationStorage) UseEmailVerification(ctx context.Context, tokenHash []byte) (models.EmailVerification, error) {
+	const op = "storage.sqlite.UseEmailVerification"
+
+	stmp, err := s.db.Prepare("UPDATE email_verifications SET used_at=? WHERE token_hash=? AND used_at IS NULL")
+	if err != nil {
+		return models.EmailVerification{}, fmt.Errorf("%s: %w", op, err)
+	}
+	res, err := stmp.ExecContext(ctx, time.Now(), tokenHash)
+	if err != nil {
+		return models.EmailVerification{}, fmt.Errorf("%s: %w", op, err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return models.EmailVerification{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return models.EmailVerification{}, fmt.Errorf("%s: %w", op, ErrEmailVerificationNotFound)
+	}
+
+	stmp, err = s.db.Prepare("SELECT id, user_id, email, token_hash, created_at, expires_at, used_at FROM email_verifications WHERE token_hash=?")
+	if err != nil {
+		return models.EmailVerification{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var verification models.EmailVerification
+	err = stmp.QueryRowContext(ctx, tokenHash).Scan(&verification.ID, &verification.UserID, &verification.Email, &verification.TokenHash,
+		&verification.CreatedAt, &verification.ExpiresAt, &verification.UsedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.EmailVerification{}, fmt.Errorf("%s: %w", op, ErrEmailVerificationNotFound)
+		}
+		return models.EmailVerification{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return verification, nil
+}
+
+// VerifyEmail marks the email of the user as verified if it still is the
+// given one.
+func (s *EmailVerificationStorage) VerifyEmail(ctx context.Context, userID int64, email string) error {
+	const op = "storage.sqlite.VerifyEmail"
+
+	stmp, err := s.db.Prepare("UPDATE users SET email_verified=TRUE WHERE id=? AND email=?")
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	res, err := stmp.ExecContext(ctx, userID, email)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, ErrEmailVerificationNotFound)
+	}
+	return nil
+}
@@ -0,0 +1,214 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/DavidG9999/my_grpc_app/internal/domain/models"
+	"github.com/mattn/go-sqlite3"
+)
+
+// subgroups selects the group and every group nested in it, however deep.
+// UNION instead of UNION ALL stops the recursion at groups already seen.
+const subgroups = `WITH RECURSIVE subgroups(id) AS (
+		SELECT ?
+		UNION
+		SELECT group_children.child_id FROM group_children JOIN subgroups ON group_children.group_id = subgroups.id
+	)`
+
+type GroupStorage struct {
+	db *sql.DB
+}
+
+func NewGroupStorage(db *sql.DB) *GroupStorage {
+	return &GroupStorage{db: db}
+}
+
+func (s *GroupStorage) SaveGroup(ctx context.Context, orgID int64, name string) (int64, error) {
+	const op = "storage.sqlite.SaveGroup"
+
+	stmp, err := s.db.Prepare("INSERT INTO groups(org_id, name, created_at) VALUES(?, ?, ?)")
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	res, err := stmp.ExecContext(ctx, orgID, name, time.Now().UTC())
+	if err != nil {
+		var sqliteErr sqlite3.Error
+
+		if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+			return 0, fmt.Errorf("%s: %w", op, ErrGroupExists)
+		}
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return id, nil
+}
+
+func (s *GroupStorage) GroupByID(ctx context.Context, groupID int64) (models.Group, error) {
+	const op = "storage.sqlite.GroupByID"
+
+	stmp, err := s.db.Prepare("SELECT id, org_id, name, created_at FROM groups WHERE id=?")
+	if err != nil {
+		return models.Group{}, fmt.Errorf("%s: %w", op, err)
+	}
+	row := stmp.QueryRowContext(ctx, groupID)
+
+	var group models.Group
+	err = row.Scan(&group.ID, &group.OrgID, &group.Name, &group.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Group{}, fmt.Errorf("%s: %w", op, ErrGroupNotFound)
+		}
+		return models.Group{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return group, nil
+}
+
+// AddGroupUser makes the user a direct member of the group. Adding a member
+// again is not an error.
+func (s *GroupStorage) AddGroupUser(ctx context.Context, groupID int64, userID int64) error {
+	const op = "storage.sqlite.AddGroupUser"
+
+	stmp, err := s.db.Prepare("INSERT INTO group_users(group_id, user_id, created_at) VALUES(?, ?, ?) ON CONFLICT DO NOTHING")
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := stmp.ExecContext(ctx, groupID, userID, time.Now().UTC()); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// AddGroupChild nests the child group in the group. It returns ErrGroupCycle
+// if the group is the child or already nested in it.
+func (s *GroupStorage) AddGroupChild(ctx context.Context, groupID int64, childID int64) error {
+	const op = "storage.sqlite.AddGroupChild"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	var cycle bool
+	err = tx.QueryRowContext(ctx, subgroups+` SELECT EXISTS (SELECT 1 FROM subgroups WHERE id = ?)`,
+		childID, groupID).Scan(&cycle)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if cycle {
+		return fmt.Errorf("%s: %w", op, ErrGroupCycle)
+	}
+
+	_, err = tx.ExecContext(ctx, "INSERT INTO group_children(group_id, child_id, created_at) VALUES(?, ?, ?) ON CONFLICT DO NOTHING",
+		groupID, childID, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// GroupUsers returns the direct members of the group or, if effective is
+// set, the members of the group and of every group nested in it.
+func (s *GroupStorage) GroupUsers(ctx context.Context, groupID int64, effective bool) ([]models.User, error) {
+	const op = "storage.sqlite.GroupUsers"
+
+	query := "SELECT " + userColumns + " FROM users WHERE id IN (SELECT user_id FROM group_users WHERE group_id = ?) ORDER BY id"
+	if effective {
+		query = subgroups + " SELECT " + userColumns + ` FROM users WHERE id IN (
+			SELECT user_id FROM group_users WHERE group_id IN (SELECT id FROM subgroups)
+		) ORDER BY id`
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var users []models.User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return users, nil
+}
+
+// GroupChildren returns the groups nested directly in the group or, if
+// effective is set, at any depth.
+func (s *GroupStorage) GroupChildren(ctx context.Context, groupID int64, effective bool) ([]models.Group, error) {
+	const op = "storage.sqlite.GroupChildren"
+
+	query := "SELECT id, org_id, name, created_at FROM groups WHERE id IN (SELECT child_id FROM group_children WHERE group_id = ?) ORDER BY name"
+	args := []any{groupID}
+	if effective {
+		query = subgroups + " SELECT id, org_id, name, created_at FROM groups WHERE id IN (SELECT id FROM subgroups) AND id <> ? ORDER BY name"
+		args = append(args, groupID)
+	}
+
+	groups, err := s.groups(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return groups, nil
+}
+
+// UserGroups returns the groups the user is a direct member of or, if
+// effective is set, also the groups those are nested in, however deep.
+func (s *GroupStorage) UserGroups(ctx context.Context, userID int64, effective bool) ([]models.Group, error) {
+	const op = "storage.sqlite.UserGroups"
+
+	query := "SELECT id, org_id, name, created_at FROM groups WHERE id IN (SELECT group_id FROM group_users WHERE user_id = ?) ORDER BY name"
+	if effective {
+		query = `WITH RECURSIVE supergroups(id) AS (
+				SELECT group_id FROM group_users WHERE user_id = ?
+				UNION
+				SELECT group_children.group_id FROM group_children JOIN supergroups ON group_children.child_id = supergroups.id
+			)
+			SELECT id, org_id, name, created_at FROM groups WHERE id IN (SELECT id FROM supergroups) ORDER BY name`
+	}
+
+	groups, err := s.groups(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return groups, nil
+}
+
+func (s *GroupStorage) groups(ctx context.Context, query string, args ...any) ([]models.Group, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var groups []models.Group
+	for rows.Next() {
+		var group models.Group
+		if err := rows.Scan(&group.ID, &group.OrgID, &group.Name, &group.CreatedAt); err != nil {
+			return nil, err
+		}
+		groups = append(groups, group)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
@@ -0,0 +1,293 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/DavidG9999/my_grpc_app/internal/domain/models"
+)
+
+type OAuthStorage struct {
+	db *sql.DB
+}
+
+func NewOAuthStorage(db *sql.DB) *OAuthStorage {
+	return &OAuthStorage{db: db}
+}
+
+func (s *OAuthStorage) RedirectURIs(ctx context.Context, appID int) ([]string, error) {
+	const op = "storage.sqlite.RedirectURIs"
+
+	stmp, err := s.db.Prepare("SELECT uri FROM app_redirect_uris WHERE app_id=?")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err := stmp.QueryContext(ctx, appID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var uris []string
+	for rows.Next() {
+		var uri string
+		if err := rows.Scan(&uri); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		uris = append(uris, uri)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return uris, nil
+}
+
+func (s *OAuthStorage) SaveAuthorizationCode(ctx context.Context, code models.AuthorizationCode) (int64, error) {
+	const op = "storage.sqlite.SaveAuthorizationCode"
+
+	stmp, err := s.db.Prepare(`INSERT INTO authorization_codes(code_hash, app_id, user_id, redirect_uri, scope, code_challenge, code_challenge_method, nonce, auth_time, created_at, expires_at)
+		VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	res, err := stmp.ExecContext(ctx, code.CodeHash, code.AppID, code.UserID, code.RedirectURI, code.Scope,
+		code.CodeChallenge, code.CodeChallengeMethod, code.Nonce, code.AuthTime, code.CreatedAt, code.ExpiresAt)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return id, nil
+}
+
+// UseAuthorizationCode marks the code as used and returns it. If the code was
+// already used, the code is returned together with ErrAuthorizationCodeUsed so
+// that the caller can revoke whatever was issued from it.
+func (s *OAuthStorage) UseAuthorizationCode(ctx context.Context, codeHash []byte) (models.AuthorizationCode, error) {
+	const op = "storage.sqlite.UseAuthorizationCode"
+
+	stmp, err := s.db.Prepare("UPDATE authorization_codes SET used_at=? WHERE code_hash=? AND used_at IS NULL")
+	if err != nil {
+		return models.AuthorizationCode{}, fmt.Errorf("%s: %w", op, err)
+	}
+	res, err := stmp.ExecContext(ctx, time.Now(), codeHash)
+	if err != nil {
+		return models.AuthorizationCode{}, fmt.Errorf("%s: %w", op, err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return models.AuthorizationCode{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	stmp, err = s.db.Prepare(`SELECT id, code_hash, app_id, user_id, redirect_uri, scope, code_challenge, code_challenge_method, nonce, auth_time, created_at, expires_at, used_at
+		FROM authorization_codes WHERE code_hash=?`)
+	if err != nil {
+		return models.AuthorizationCode{}, fmt.Errorf("%s: %w", op, err)
+	}
+	row := stmp.QueryRowContext(ctx, codeHash)
+
+	var code models.AuthorizationCode
+	err = row.Scan(&code.ID, &code.CodeHash, &code.AppID, &code.UserID, &code.RedirectURI, &code.Scope,
+		&code.CodeChallenge, &code.CodeChallengeMethod, &code.Nonce, &code.AuthTime, &code.CreatedAt, &code.ExpiresAt, &code.UsedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.AuthorizationCode{}, fmt.Errorf("%s: %w", op, ErrAuthorizationCodeNotFound)
+		}
+		return models.AuthorizationCode{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return code, fmt.Errorf("%s: %w", op, ErrAuthorizationCodeUsed)
+	}
+	return code, nil
+}
+
+func (s *OAuthStorage) SaveRefreshToken(ctx context.Context, token models.RefreshToken) (int64, error) {
+	const op = "storage.sqlite.SaveRefreshToken"
+
+	stmp, err := s.db.Prepare(`INSERT INTO refresh_tokens(token_hash, app_id, user_id, authorization_code_id, session_id, scope, auth_time, created_at, expires_at)
+		VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	res, err := stmp.ExecContext(ctx, token.TokenHash, token.AppID, token.UserID, token.AuthorizationCodeID, token.SessionID,
+		token.Scope, token.AuthTime, token.CreatedAt, token.ExpiresAt)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return id, nil
+}
+
+// UseRefreshToken revokes the refresh token of the app and returns it, so
+// that it is rotated once. Tokens revoked before are returned with
+// ErrRefreshTokenUsed.
+func (s *OAuthStorage) UseRefreshToken(ctx context.Context, appID int, tokenHash []byte) (models.RefreshToken, error) {
+	const op = "storage.sqlite.UseRefreshToken"
+
+	stmp, err := s.db.Prepare("UPDATE refresh_tokens SET revoked_at=? WHERE token_hash=? AND app_id=? AND revoked_at IS NULL")
+	if err != nil {
+		return models.RefreshToken{}, fmt.Errorf("%s: %w", op, err)
+	}
+	res, err := stmp.ExecContext(ctx, time.Now(), tokenHash, appID)
+	if err != nil {
+		return models.RefreshToken{}, fmt.Errorf("%s: %w", op, err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return models.RefreshToken{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	stmp, err = s.db.Prepare(`SELECT id, token_hash, app_id, user_id, authorization_code_id, session_id, scope, auth_time, created_at, expires_at, revoked_at
+		FROM refresh_tokens WHERE token_hash=? AND app_id=?`)
+	if err != nil {
+		return models.RefreshToken{}, fmt.Errorf("%s: %w", op, err)
+	}
+	row := stmp.QueryRowContext(ctx, tokenHash, appID)
+
+	var token models.RefreshToken
+	err = row.Scan(&token.ID, &token.TokenHash, &token.AppID, &token.UserID, &token.AuthorizationCodeID, &token.SessionID,
+		&token.Scope, &token.AuthTime, &token.CreatedAt, &token.ExpiresAt, &token.RevokedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.RefreshToken{}, fmt.Errorf("%s: %w", op, ErrRefreshTokenNotFound)
+		}
+		return models.RefreshToken{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return token, fmt.Errorf("%s: %w", op, ErrRefreshTokenUsed)
+	}
+	return token, nil
+}
+
+func (s *OAuthStorage) RevokeRefreshTokensByCode(ctx context.Context, codeID int64) error {
+	const op = "storage.sqlite.RevokeRefreshTokensByCode"
+
+	stmp, err := s.db.Prepare("UPDATE refresh_tokens SET revoked_at=? WHERE authorization_code_id=? AND revoked_at IS NULL")
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if _, err := stmp.ExecContext(ctx, time.Now(), codeID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (s *OAuthStorage) SaveDeviceAuthorization(ctx context.Context, device models.DeviceAuthorization) (int64, error) {
+	const op = "storage.sqlite.SaveDeviceAuthorization"
+
+	stmp, err := s.db.Prepare(`INSERT INTO device_authorizations(device_code_hash, user_code, app_id, scope, status, poll_interval, created_at, expires_at)
+		VALUES(?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	res, err := stmp.ExecContext(ctx, device.DeviceCodeHash, device.UserCode, device.AppID, device.Scope, device.Status,
+		int64(device.PollInterval.Seconds()), device.CreatedAt, device.ExpiresAt)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return id, nil
+}
+
+func (s *OAuthStorage) DeviceAuthorization(ctx context.Context, deviceCodeHash []byte) (models.DeviceAuthorization, error) {
+	const op = "storage.sqlite.DeviceAuthorization"
+
+	device, err := s.deviceAuthorization(ctx, "device_code_hash", deviceCodeHash)
+	if err != nil {
+		return models.DeviceAuthorization{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return device, nil
+}
+
+func (s *OAuthStorage) DeviceAuthorizationByUserCode(ctx context.Context, userCode string) (models.DeviceAuthorization, error) {
+	const op = "storage.sqlite.DeviceAuthorizationByUserCode"
+
+	device, err := s.deviceAuthorization(ctx, "user_code", userCode)
+	if err != nil {
+		return models.DeviceAuthorization{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return device, nil
+}
+
+func (s *OAuthStorage) deviceAuthorization(ctx context.Context, column string, value any) (models.DeviceAuthorization, error) {
+	stmp, err := s.db.Prepare(`SELECT id, device_code_hash, user_code, app_id, scope, status, user_id, poll_interval, last_polled_at, auth_time, created_at, expires_at
+		FROM device_authorizations WHERE ` + column + `=?`)
+	if err != nil {
+		return models.DeviceAuthorization{}, err
+	}
+	row := stmp.QueryRowContext(ctx, value)
+
+	var (
+		device   models.DeviceAuthorization
+		interval int64
+	)
+	err = row.Scan(&device.ID, &device.DeviceCodeHash, &device.UserCode, &device.AppID, &device.Scope, &device.Status,
+		&device.UserID, &interval, &device.LastPolledAt, &device.AuthTime, &device.CreatedAt, &device.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.DeviceAuthorization{}, ErrDeviceAuthorizationNotFound
+		}
+		return models.DeviceAuthorization{}, err
+	}
+	device.PollInterval = time.Duration(interval) * time.Second
+	return device, nil
+}
+
+// UpdateDeviceAuthorizationStatus moves the authorization from one status to
+// another. ErrDeviceAuthorizationHandled is returned if it is no longer in the
+// expected status.
+func (s *OAuthStorage) UpdateDeviceAuthorizationStatus(ctx context.Context, deviceID int64, from models.DeviceAuthorizationStatus, to models.DeviceAuthorizationStatus, userID *int64) error {
+	const op = "storage.sqlite.UpdateDeviceAuthorizationStatus"
+
+	stmp, err := s.db.Prepare(`UPDATE device_authorizations SET status=?, user_id=COALESCE(?, user_id), auth_time=COALESCE(auth_time, ?)
+		WHERE id=? AND status=?`)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	var authTime *time.Time
+	if userID != nil {
+		now := time.Now()
+		authTime = &now
+	}
+	res, err := stmp.ExecContext(ctx, to, userID, authTime, deviceID, from)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, ErrDeviceAuthorizationHandled)
+	}
+	return nil
+}
+
+func (s *OAuthStorage) UpdateDeviceAuthorizationPoll(ctx context.Context, deviceID int64, polledAt time.Time, interval time.Duration) error {
+	const op = "storage.sqlite.UpdateDeviceAuthorizationPoll"
+
+	stmp, err := s.db.Prepare("UPDATE device_authorizations SET last_polled_at=?, poll_interval=? WHERE id=?")
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if _, err := stmp.ExecContext(ctx, polledAt, int64(interval.Seconds()), deviceID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
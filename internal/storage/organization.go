@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/DavidG9999/my_grpc_app/internal/domain/models"
+	"github.com/mattn/go-sqlite3"
+)
+
+type OrganizationStorage struct {
+	db *sql.DB
+}
+
+func NewOrganizationStorage(db *sql.DB) *OrganizationStorage {
+	return &OrganizationStorage{db: db}
+}
+
+func (s *OrganizationStorage) SaveOrganization(ctx context.Context, name string) (int64, error) {
+	const op = "storage.sqlite.SaveOrganization"
+
+	stmp, err := s.db.Prepare("INSERT INTO organizations(name, created_at) VALUES(?, ?)")
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	res, err := stmp.ExecContext(ctx, name, time.Now().UTC())
+	if err != nil {
+		var sqliteErr sqlite3.Error
+
+		if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+			return 0, fmt.Errorf("%s: %w", op, ErrOrganizationExists)
+		}
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return id, nil
+}
+
+func (s *OrganizationStorage) OrganizationByID(ctx context.Context, orgID int64) (models.Organization, error) {
+	const op = "storage.sqlite.OrganizationByID"
+
+	stmp, err := s.db.Prepare("SELECT id, name, created_at FROM organizations WHERE id=?")
+	if err != nil {
+		return models.Organization{}, fmt.Errorf("%s: %w", op, err)
+	}
+	row := stmp.QueryRowContext(ctx, orgID)
+
+	var org models.Organization
+	err = row.Scan(&org.ID, &org.Name, &org.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Organization{}, fmt.Errorf("%s: %w", op, ErrOrganizationNotFound)
+		}
+		return models.Organization{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return org, nil
+}
@@ -0,0 +1,162 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/DavidG9999/my_grpc_app/internal/domain/models"
+)
+
+type SessionStorage struct {
+	db *sql.DB
+}
+
+func NewSessionStorage(db *sql.DB) *SessionStorage {
+	return &SessionStorage{db: db}
+}
+
+func (s *SessionStorage) SaveSession(ctx context.Context, session models.Session) (int64, error) {
+	const op = "storage.sqlite.SaveSession"
+
+	stmp, err := s.db.Prepare(`INSERT INTO sessions(user_id, app_id, device, ip, user_agent, created_at, last_seen_at, impersonator_id)
+		VALUES(?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	res, err := stmp.ExecContext(ctx, session.UserID, session.AppID, session.Device, session.IP, session.UserAgent,
+		session.CreatedAt, session.LastSeenAt, session.ImpersonatorID)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return id, nil
+}
+
+func (s *SessionStorage) SessionByID(ctx context.Context, sessionID int64) (models.Session, error) {
+	const op = "storage.sqlite.SessionByID"
+
+	stmp, err := s.db.Prepare(`SELECT id, user_id, app_id, device, ip, user_agent, created_at, last_seen_at, revoked_at, impersonator_id
+		FROM sessions WHERE id=?`)
+	if err != nil {
+		return models.Session{}, fmt.Errorf("%s: %w", op, err)
+	}
+	row := stmp.QueryRowContext(ctx, sessionID)
+
+	var session models.Session
+	err = row.Scan(&session.ID, &session.UserID, &session.AppID, &session.Device, &session.IP, &session.UserAgent,
+		&session.CreatedAt, &session.LastSeenAt, &session.RevokedAt, &session.ImpersonatorID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Session{}, fmt.Errorf("%s: %w", op, ErrSessionNotFound)
+		}
+		return models.Session{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return session, nil
+}
+
+// UserSessions returns the active sessions of the user, most recently used first.
+func (s *SessionStorage) UserSessions(ctx context.Context, userID int64) ([]models.Session, error) {
+	const op = "storage.sqlite.UserSessions"
+
+	stmp, err := s.db.Prepare(`SELECT id, user_id, app_id, device, ip, user_agent, created_at, last_seen_at, revoked_at, impersonator_id
+		FROM sessions WHERE user_id=? AND revoked_at IS NULL ORDER BY last_seen_at DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err := stmp.QueryContext(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var sessions []models.Session
+	for rows.Next() {
+		var session models.Session
+		err := rows.Scan(&session.ID, &session.UserID, &session.AppID, &session.Device, &session.IP, &session.UserAgent,
+			&session.CreatedAt, &session.LastSeenAt, &session.RevokedAt, &session.ImpersonatorID)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		sessions = append(sessions, session)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return sessions, nil
+}
+
+func (s *SessionStorage) TouchSession(ctx context.Context, sessionID int64, lastSeenAt time.Time) error {
+	const op = "storage.sqlite.TouchSession"
+
+	stmp, err := s.db.Prepare("UPDATE sessions SET last_seen_at=? WHERE id=?")
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if _, err := stmp.ExecContext(ctx, lastSeenAt, sessionID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// RevokeSession revokes the session together with the refresh tokens issued
+// within it.
+func (s *SessionStorage) RevokeSession(ctx context.Context, sessionID int64) error {
+	const op = "storage.sqlite.RevokeSession"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	if _, err := tx.ExecContext(ctx, "UPDATE sessions SET revoked_at=? WHERE id=? AND revoked_at IS NULL", now, sessionID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE refresh_tokens SET revoked_at=? WHERE session_id=? AND revoked_at IS NULL", now, sessionID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// RevokeSessions revokes all sessions of the user except keepSessionID,
+// together with their refresh tokens.
+func (s *SessionStorage) RevokeSessions(ctx context.Context, userID int64, keepSessionID int64) (int64, error) {
+	const op = "storage.sqlite.RevokeSessions"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	res, err := tx.ExecContext(ctx, "UPDATE sessions SET revoked_at=? WHERE user_id=? AND id<>? AND revoked_at IS NULL", now, userID, keepSessionID)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	revoked, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE refresh_tokens SET revoked_at=?
+		WHERE user_id=? AND revoked_at IS NULL AND (session_id IS NULL OR session_id<>?)`, now, userID, keepSessionID)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return revoked, nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"time"
 
 	"github.com/DavidG9999/my_grpc_app/internal/domain/models"
 )
@@ -11,22 +12,148 @@ import (
 var (
 	ErrUserExists   = errors.New("user already exist")
 	ErrUserNotFound = errors.New("user not found")
+	ErrLastAdmin    = errors.New("cannot demote the last admin")
+	ErrAdminExists  = errors.New("admin already exists")
 	ErrAppNotFound  = errors.New("app nor found")
+
+	ErrAuthorizationCodeNotFound = errors.New("authorization code not found")
+	ErrAuthorizationCodeUsed     = errors.New("authorization code already used")
+	ErrRefreshTokenNotFound      = errors.New("refresh token not found")
+	ErrRefreshTokenUsed          = errors.New("refresh token already used")
+
+	ErrDeviceAuthorizationNotFound = errors.New("device authorization not found")
+	ErrDeviceAuthorizationHandled  = errors.New("device authorization already handled")
+
+	ErrSessionNotFound = errors.New("session not found")
+
+	ErrAppMemberNotFound = errors.New("app member not found")
+
+	ErrOrganizationExists   = errors.New("organization already exists")
+	ErrOrganizationNotFound = errors.New("organization not found")
+
+	ErrGroupExists   = errors.New("group already exists")
+	ErrGroupNotFound = errors.New("group not found")
+	ErrGroupCycle    = errors.New("group would contain itself")
+
+	ErrAPIKeyNotFound = errors.New("api key not found")
+
+	ErrChangeNotFound = errors.New("change not found")
+	ErrChangeDecided  = errors.New("change already decided or expired")
+
+	ErrEmailVerificationNotFound = errors.New("email verification not found")
 )
 
 type Auth interface {
-	SaveUser(ctx context.Context, name string, email string, passwordHash []byte, isAdmin bool) (int64, error)
-	User(ctx context.Context, email string) (models.User, error)
+	SaveUser(ctx context.Context, orgID int64, name string, email string, passwordHash []byte, isAdmin bool) (int64, error)
+	SaveFirstAdmin(ctx context.Context, orgID int64, name string, email string, passwordHash []byte) (int64, error)
+	UpdatePasswordHash(ctx context.Context, userID int64, passwordHash []byte) error
+	UpdateUser(ctx context.Context, userID int64, name string, email string) error
+	SetOrgAdmin(ctx context.Context, userID int64, isOrgAdmin bool) error
+	SetAdmin(ctx context.Context, userID int64, isAdmin bool) error
+	SetUserStatus(ctx context.Context, userID int64, status models.UserStatus, reason string, suspendedUntil *time.Time) error
+	User(ctx context.Context, orgID int64, email string) (models.User, error)
+	UserByID(ctx context.Context, userID int64) (models.User, error)
+	ListUsers(ctx context.Context, query models.UserListQuery) ([]models.User, error)
+	SearchUsers(ctx context.Context, orgID int64, query string, limit int) ([]models.UserMatch, error)
 	IsAdmin(ctx context.Context, userID int64) (bool, error)
 	App(ctx context.Context, appID int) (models.App, error)
+	UpdateAppSecret(ctx context.Context, appID int, secret string) error
+	DeleteApp(ctx context.Context, appID int) error
+	OrgAppIDs(ctx context.Context, orgID int64) ([]int, error)
+	AppScopes(ctx context.Context, appID int) ([]string, error)
+	ExchangeScopes(ctx context.Context, sourceAppID int, targetAppID int) ([]string, error)
+	AddAppMember(ctx context.Context, appID int, userID int64) error
+	RemoveAppMember(ctx context.Context, appID int, userID int64) error
+	IsAppMember(ctx context.Context, appID int, userID int64) (bool, error)
+}
+
+type OAuth interface {
+	RedirectURIs(ctx context.Context, appID int) ([]string, error)
+	SaveAuthorizationCode(ctx context.Context, code models.AuthorizationCode) (int64, error)
+	UseAuthorizationCode(ctx context.Context, codeHash []byte) (models.AuthorizationCode, error)
+	SaveRefreshToken(ctx context.Context, token models.RefreshToken) (int64, error)
+	UseRefreshToken(ctx context.Context, appID int, tokenHash []byte) (models.RefreshToken, error)
+	RevokeRefreshTokensByCode(ctx context.Context, codeID int64) error
+	SaveDeviceAuthorization(ctx context.Context, device models.DeviceAuthorization) (int64, error)
+	DeviceAuthorization(ctx context.Context, deviceCodeHash []byte) (models.DeviceAuthorization, error)
+	DeviceAuthorizationByUserCode(ctx context.Context, userCode string) (models.DeviceAuthorization, error)
+	UpdateDeviceAuthorizationStatus(ctx context.Context, deviceID int64, from models.DeviceAuthorizationStatus, to models.DeviceAuthorizationStatus, userID *int64) error
+	UpdateDeviceAuthorizationPoll(ctx context.Context, deviceID int64, polledAt time.Time, interval time.Duration) error
+}
+
+type Session interface {
+	SaveSession(ctx context.Context, session models.Session) (int64, error)
+	SessionByID(ctx context.Context, sessionID int64) (models.Session, error)
+	UserSessions(ctx context.Context, userID int64) ([]models.Session, error)
+	TouchSession(ctx context.Context, sessionID int64, lastSeenAt time.Time) error
+	RevokeSession(ctx context.Context, sessionID int64) error
+	RevokeSessions(ctx context.Context, userID int64, keepSessionID int64) (int64, error)
+}
+
+type Organization interface {
+	SaveOrganization(ctx context.Context, name string) (int64, error)
+	OrganizationByID(ctx context.Context, orgID int64) (models.Organization, error)
+}
+
+type Group interface {
+	SaveGroup(ctx context.Context, orgID int64, name string) (int64, error)
+	GroupByID(ctx context.Context, groupID int64) (models.Group, error)
+	AddGroupUser(ctx context.Context, groupID int64, userID int64) error
+	AddGroupChild(ctx context.Context, groupID int64, childID int64) error
+	GroupUsers(ctx context.Context, groupID int64, effective bool) ([]models.User, error)
+	GroupChildren(ctx context.Context, groupID int64, effective bool) ([]models.Group, error)
+	UserGroups(ctx context.Context, userID int64, effective bool) ([]models.Group, error)
+}
+
+type APIKey interface {
+	SaveAPIKey(ctx context.Context, key models.APIKey) (int64, error)
+	APIKeyByID(ctx context.Context, keyID int64) (models.APIKey, error)
+	APIKeyByHash(ctx context.Context, keyHash []byte) (models.APIKey, error)
+	UserAPIKeys(ctx context.Context, userID int64) ([]models.APIKey, error)
+	TouchAPIKey(ctx context.Context, keyID int64, lastUsedAt time.Time) error
+	RevokeAPIKey(ctx context.Context, keyID int64) error
+}
+
+type Audit interface {
+	SaveAuditEvent(ctx context.Context, event models.AuditEvent) (int64, error)
+}
+
+type Change interface {
+	SaveChange(ctx context.Context, change models.PendingChange) (int64, error)
+	ChangeByID(ctx context.Context, changeID int64) (models.PendingChange, error)
+	DecideChange(ctx context.Context, changeID int64, status models.ChangeStatus, deciderID int64, decidedAt time.Time) error
+	ReopenChange(ctx context.Context, changeID int64) error
+	ExpireChanges(ctx context.Context, now time.Time) (int64, error)
+}
+
+type EmailVerification interface {
+	SaveEmailVerification(ctx context.Context, verification models.EmailVerification) (int64, error)
+	UseEmailVerification(ctx context.Context, tokenHash []byte) (models.EmailVerification, error)
+	VerifyEmail(ctx context.Context, userID int64, email string) error
 }
 
 type Storage struct {
 	Auth
+	OAuth
+	Session
+	Organization
+	Group
+	APIKey
+	Audit
+	Change
+	EmailVerification
 }
 
 func NewStorage(db *sql.DB) *Storage {
 	return &Storage{
-		Auth: NewAuthStorage(db),
+		Auth:              NewAuthStorage(db),
+		OAuth:             NewOAuthStorage(db),
+		Session:           NewSessionStorage(db),
+		Organization:      NewOrganizationStorage(db),
+		Group:             NewGroupStorage(db),
+		APIKey:            NewAPIKeyStorage(db),
+		Audit:             NewAuditStorage(db),
+		Change:            NewChangeStorage(db),
+		EmailVerification: NewEmailVerificationStorage(db),
 	}
 }
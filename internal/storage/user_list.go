@@ -0,0 +1,114 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/DavidG9999/my_grpc_app/internal/domain/models"
+)
+
+const userColumns = "id, org_id, name, email, password_hash, email_verified, is_admin, is_org_admin, status, status_reason, suspended_until, created_at"
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanUser scans the userColumns of the row, followed by the extra columns
+// of the query.
+func scanUser(row rowScanner, extra ...any) (models.User, error) {
+	var (
+		user      models.User
+		createdAt sql.NullTime
+	)
+	dest := []any{&user.ID, &user.OrgID, &user.Name, &user.Email, &user.PasswordHash, &user.EmailVerified,
+		&user.IsAdmin, &user.IsOrgAdmin, &user.Status, &user.StatusReason, &user.SuspendedUntil, &createdAt}
+	err := row.Scan(append(dest, extra...)...)
+	if err != nil {
+		return models.User{}, err
+	}
+	user.CreatedAt = createdAt.Time
+	return user, nil
+}
+
+// ListUsers returns a page of users matching the filter. Pages are read by
+// keyset on the sort column and id, so that every page is an index range
+// scan no matter how deep it is.
+func (s *AuthStorage) ListUsers(ctx context.Context, query models.UserListQuery) ([]models.User, error) {
+	const op = "storage.sqlite.ListUsers"
+
+	var (
+		where []string
+		args  []any
+	)
+	filter := query.Filter
+	if filter.OrgID != 0 {
+		where = append(where, "org_id = ?")
+		args = append(args, filter.OrgID)
+	}
+	if filter.EmailPrefix != "" {
+		// A range instead of LIKE, which cannot use the index on email.
+		where = append(where, "email >= ? AND email < ?")
+		args = append(args, filter.EmailPrefix, filter.EmailPrefix+"\U0010FFFF")
+	}
+	if filter.IsAdmin != nil {
+		where = append(where, "is_admin = ?")
+		args = append(args, *filter.IsAdmin)
+	}
+	if filter.Status != "" {
+		where = append(where, "status = ?")
+		args = append(args, filter.Status)
+	}
+	if !filter.CreatedAfter.IsZero() {
+		where = append(where, "created_at >= ?")
+		args = append(args, filter.CreatedAfter.UTC())
+	}
+	if !filter.CreatedBefore.IsZero() {
+		where = append(where, "created_at < ?")
+		args = append(args, filter.CreatedBefore.UTC())
+	}
+
+	sortColumn := "created_at"
+	if query.SortBy == models.UserSortEmail {
+		sortColumn = "email"
+	}
+	order, cmp := "ASC", ">"
+	if query.Descending {
+		order, cmp = "DESC", "<"
+	}
+	if after := query.After; after != nil {
+		where = append(where, fmt.Sprintf("(%s, id) %s (?, ?)", sortColumn, cmp))
+		if sortColumn == "email" {
+			args = append(args, after.Email, after.ID)
+		} else {
+			args = append(args, after.CreatedAt.UTC(), after.ID)
+		}
+	}
+
+	stmt := "SELECT " + userColumns + " FROM users"
+	if len(where) > 0 {
+		stmt += " WHERE " + strings.Join(where, " AND ")
+	}
+	stmt += fmt.Sprintf(" ORDER BY %s %s, id %s LIMIT ?", sortColumn, order, order)
+	args = append(args, query.Limit)
+
+	rows, err := s.db.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	users := make([]models.User, 0, query.Limit)
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return users, nil
+}
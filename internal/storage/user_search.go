@@ -0,0 +1,200 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"html"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/DavidG9999/my_grpc_app/internal/domain/models"
+)
+
+// Matched parts of search results are enclosed in these, the rest of the
+// highlights is HTML-escaped.
+const (
+	HighlightStart = "<mark>"
+	HighlightEnd   = "</mark>"
+)
+
+// The highlight function of FTS5 encloses matches in these, they are replaced
+// with HighlightStart and HighlightEnd once the text is escaped.
+const (
+	ftsHighlightStart = "\x02"
+	ftsHighlightEnd   = "\x03"
+)
+
+// trigramLength is the shortest term the trigram tokenizer of users_fts
+// can find.
+const trigramLength = 3
+
+// SearchUsers finds users of the organization, or of all of them if orgID
+// is zero, whose name or email contain every term of the query, best matches
+// first. Queries with terms too short for the full-text index are answered
+// by searchUsersLike.
+func (s *AuthStorage) SearchUsers(ctx context.Context, orgID int64, query string, limit int) ([]models.UserMatch, error) {
+	const op = "storage.sqlite.SearchUsers"
+
+	terms := strings.Fields(query)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+	for _, term := range terms {
+		if utf8.RuneCountInString(term) < trigramLength {
+			matches, err := searchUsersLike(ctx, s.db, orgID, terms, limit)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", op, err)
+			}
+			return matches, nil
+		}
+	}
+
+	// Every term is quoted, so that the query cannot use the FTS5 syntax.
+	quoted := make([]string, 0, len(terms))
+	for _, term := range terms {
+		quoted = append(quoted, `"`+strings.ReplaceAll(term, `"`, `""`)+`"`)
+	}
+
+	stmp, err := s.db.Prepare(`SELECT ` + userColumns + `, m.name_highlight, m.email_highlight, m.score
+		FROM users JOIN (
+			SELECT rowid,
+				highlight(users_fts, 0, ?, ?) AS name_highlight,
+				highlight(users_fts, 1, ?, ?) AS email_highlight,
+				-bm25(users_fts) AS score
+			FROM users_fts WHERE users_fts MATCH ?
+		) AS m ON users.id = m.rowid
+		WHERE ? = 0 OR users.org_id = ?
+		ORDER BY m.score DESC, users.id LIMIT ?`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err := stmp.QueryContext(ctx,
+		ftsHighlightStart, ftsHighlightEnd, ftsHighlightStart, ftsHighlightEnd,
+		strings.Join(quoted, " "), orgID, orgID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var matches []models.UserMatch
+	for rows.Next() {
+		var match models.UserMatch
+		match.User, err = scanUser(rows, &match.NameHighlight, &match.EmailHighlight, &match.Score)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		match.NameHighlight = escapeHighlight(match.NameHighlight, match.User.Name, terms)
+		match.EmailHighlight = escapeHighlight(match.EmailHighlight, match.User.Email, terms)
+		matches = append(matches, match)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return matches, nil
+}
+
+// searchUsersLike is SearchUsers with plain LIKE patterns, for storages
+// without a full-text index. It scans the users table and ranks exact
+// matches over prefix matches over the rest.
+func searchUsersLike(ctx context.Context, db *sql.DB, orgID int64, terms []string, limit int) ([]models.UserMatch, error) {
+	// The first term ranks the matches.
+	first := escapeLike(terms[0])
+	args := []any{terms[0], terms[0], first + "%", first + "%"}
+
+	var where []string
+	if orgID != 0 {
+		where = append(where, "org_id = ?")
+		args = append(args, orgID)
+	}
+	for _, term := range terms {
+		where = append(where, `(name LIKE ? ESCAPE '\' OR email LIKE ? ESCAPE '\')`)
+		pattern := "%" + escapeLike(term) + "%"
+		args = append(args, pattern, pattern)
+	}
+	args = append(args, limit)
+
+	rows, err := db.QueryContext(ctx, `SELECT `+userColumns+`,
+			CASE
+				WHEN email = ? COLLATE NOCASE OR name = ? COLLATE NOCASE THEN 3
+				WHEN email LIKE ? ESCAPE '\' OR name LIKE ? ESCAPE '\' THEN 2
+				ELSE 1
+			END AS score
+		FROM users WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY score DESC, id LIMIT ?`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var matches []models.UserMatch
+	for rows.Next() {
+		var match models.UserMatch
+		match.User, err = scanUser(rows, &match.Score)
+		if err != nil {
+			return nil, err
+		}
+		match.NameHighlight = highlight(match.User.Name, terms)
+		match.EmailHighlight = highlight(match.User.Email, terms)
+		matches = append(matches, match)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return matches, nil
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// escapeHighlight escapes the output of the highlight function of FTS5 for s
+// and marks its matches. Where s holds the FTS5 markers itself, they cannot be
+// told apart, and s is highlighted again.
+func escapeHighlight(highlighted string, s string, terms []string) string {
+	if strings.ContainsAny(s, ftsHighlightStart+ftsHighlightEnd) {
+		return highlight(s, terms)
+	}
+	return strings.NewReplacer(ftsHighlightStart, HighlightStart, ftsHighlightEnd, HighlightEnd).
+		Replace(html.EscapeString(highlighted))
+}
+
+// highlight escapes s and marks the case-insensitive occurrences of the terms
+// in it the way the highlight function of FTS5 does.
+func highlight(s string, terms []string) string {
+	lower := strings.ToLower(s)
+	if len(lower) != len(s) {
+		// Offsets of the lowered string do not fit the original one.
+		return html.EscapeString(s)
+	}
+	marked := make([]bool, len(s))
+	for _, term := range terms {
+		term = strings.ToLower(term)
+		for i := 0; term != "" && i+len(term) <= len(lower); {
+			j := strings.Index(lower[i:], term)
+			if j < 0 {
+				break
+			}
+			for k := i + j; k < i+j+len(term); k++ {
+				marked[k] = true
+			}
+			i += j + 1
+		}
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		j := i + 1
+		for j < len(s) && marked[j] == marked[i] {
+			j++
+		}
+		if marked[i] {
+			b.WriteString(HighlightStart + html.EscapeString(s[i:j]) + HighlightEnd)
+		} else {
+			b.WriteString(html.EscapeString(s[i:j]))
+		}
+		i = j
+	}
+	return b.String()
+}
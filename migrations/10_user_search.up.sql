@@ -0,0 +1,22 @@
+CREATE VIRTUAL TABLE IF NOT EXISTS users_fts USING fts5(
+    name,
+    email,
+    content = 'users',
+    content_rowid = 'id',
+    tokenize = 'trigram'
+);
+
+INSERT INTO users_fts(users_fts) VALUES ('rebuild');
+
+CREATE TRIGGER IF NOT EXISTS users_fts_insert AFTER INSERT ON users BEGIN
+    INSERT INTO users_fts(rowid, name, email) VALUES (new.id, new.name, new.email);
+END;
+
+CREATE TRIGGER IF NOT EXISTS users_fts_delete AFTER DELETE ON users BEGIN
+    INSERT INTO users_fts(users_fts, rowid, name, email) VALUES ('delete', old.id, old.name, old.email);
+END;
+
+CREATE TRIGGER IF NOT EXISTS users_fts_update AFTER UPDATE OF name, email ON users BEGIN
+    INSERT INTO users_fts(users_fts, rowid, name, email) VALUES ('delete', old.id, old.name, old.email);
+    INSERT INTO users_fts(rowid, name, email) VALUES (new.id, new.name, new.email);
+END;
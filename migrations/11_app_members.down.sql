@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_app_members_user_id;
+
+DROP TABLE IF EXISTS app_members;
+
+ALTER TABLE apps DROP COLUMN invite_only;
@@ -0,0 +1,11 @@
+ALTER TABLE apps ADD COLUMN invite_only BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE
+    IF NOT EXISTS app_members (
+        app_id INTEGER NOT NULL REFERENCES apps (id) ON DELETE CASCADE,
+        user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+        created_at DATETIME NOT NULL,
+        PRIMARY KEY (app_id, user_id)
+    );
+
+CREATE INDEX IF NOT EXISTS idx_app_members_user_id ON app_members (user_id);
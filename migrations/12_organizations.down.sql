@@ -0,0 +1,45 @@
+CREATE TABLE
+    users_old (
+        id INTEGER PRIMARY KEY,
+        name VARCHAR(100) NOT NULL,
+        email TEXT NOT NULL UNIQUE,
+        password_hash BLOB NOT NULL,
+        is_admin BOOLEAN NOT NULL DEFAULT FALSE,
+        email_verified BOOLEAN NOT NULL DEFAULT FALSE,
+        created_at DATETIME,
+        status TEXT NOT NULL DEFAULT 'active'
+    );
+
+INSERT INTO users_old (id, name, email, password_hash, is_admin, email_verified, created_at, status)
+SELECT id, name, email, password_hash, is_admin, email_verified, created_at, status FROM users;
+
+DROP TABLE users;
+
+ALTER TABLE users_old RENAME TO users;
+
+CREATE INDEX IF NOT EXISTS idx_email ON users (email);
+
+CREATE INDEX IF NOT EXISTS idx_users_created_at ON users (created_at, id);
+
+CREATE INDEX IF NOT EXISTS idx_users_is_admin_created_at ON users (is_admin, created_at, id);
+
+CREATE INDEX IF NOT EXISTS idx_users_status_created_at ON users (status, created_at, id);
+
+CREATE TRIGGER IF NOT EXISTS users_fts_insert AFTER INSERT ON users BEGIN
+    INSERT INTO users_fts(rowid, name, email) VALUES (new.id, new.name, new.email);
+END;
+
+CREATE TRIGGER IF NOT EXISTS users_fts_delete AFTER DELETE ON users BEGIN
+    INSERT INTO users_fts(users_fts, rowid, name, email) VALUES ('delete', old.id, old.name, old.email);
+END;
+
+CREATE TRIGGER IF NOT EXISTS users_fts_update AFTER UPDATE OF name, email ON users BEGIN
+    INSERT INTO users_fts(users_fts, rowid, name, email) VALUES ('delete', old.id, old.name, old.email);
+    INSERT INTO users_fts(rowid, name, email) VALUES (new.id, new.name, new.email);
+END;
+
+DROP INDEX IF EXISTS idx_apps_org_id;
+
+ALTER TABLE apps DROP COLUMN org_id;
+
+DROP TABLE IF EXISTS organizations;
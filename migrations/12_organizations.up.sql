@@ -0,0 +1,60 @@
+CREATE TABLE
+    IF NOT EXISTS organizations (
+        id INTEGER PRIMARY KEY,
+        name TEXT NOT NULL UNIQUE,
+        created_at DATETIME NOT NULL
+    );
+
+-- Everything there was before organizations belongs to the default one.
+INSERT INTO organizations (id, name, created_at) VALUES (1, 'default', strftime('%Y-%m-%d %H:%M:%S+00:00', 'now')) ON CONFLICT DO NOTHING;
+
+ALTER TABLE apps ADD COLUMN org_id INTEGER NOT NULL DEFAULT 1;
+
+CREATE INDEX IF NOT EXISTS idx_apps_org_id ON apps (org_id);
+
+-- Emails are unique within an organization only, which takes rebuilding the
+-- table to drop the constraint on the email column.
+CREATE TABLE
+    users_new (
+        id INTEGER PRIMARY KEY,
+        org_id INTEGER NOT NULL DEFAULT 1 REFERENCES organizations (id),
+        name VARCHAR(100) NOT NULL,
+        email TEXT NOT NULL,
+        password_hash BLOB NOT NULL,
+        is_admin BOOLEAN NOT NULL DEFAULT FALSE,
+        is_org_admin BOOLEAN NOT NULL DEFAULT FALSE,
+        email_verified BOOLEAN NOT NULL DEFAULT FALSE,
+        created_at DATETIME,
+        status TEXT NOT NULL DEFAULT 'active',
+        UNIQUE (org_id, email)
+    );
+
+INSERT INTO users_new (id, name, email, password_hash, is_admin, email_verified, created_at, status)
+SELECT id, name, email, password_hash, is_admin, email_verified, created_at, status FROM users;
+
+DROP TABLE users;
+
+ALTER TABLE users_new RENAME TO users;
+
+CREATE INDEX IF NOT EXISTS idx_email ON users (email);
+
+CREATE INDEX IF NOT EXISTS idx_users_created_at ON users (created_at, id);
+
+CREATE INDEX IF NOT EXISTS idx_users_is_admin_created_at ON users (is_admin, created_at, id);
+
+CREATE INDEX IF NOT EXISTS idx_users_status_created_at ON users (status, created_at, id);
+
+CREATE INDEX IF NOT EXISTS idx_users_org_id_created_at ON users (org_id, created_at, id);
+
+CREATE TRIGGER IF NOT EXISTS users_fts_insert AFTER INSERT ON users BEGIN
+    INSERT INTO users_fts(rowid, name, email) VALUES (new.id, new.name, new.email);
+END;
+
+CREATE TRIGGER IF NOT EXISTS users_fts_delete AFTER DELETE ON users BEGIN
+    INSERT INTO users_fts(users_fts, rowid, name, email) VALUES ('delete', old.id, old.name, old.email);
+END;
+
+CREATE TRIGGER IF NOT EXISTS users_fts_update AFTER UPDATE OF name, email ON users BEGIN
+    INSERT INTO users_fts(users_fts, rowid, name, email) VALUES ('delete', old.id, old.name, old.email);
+    INSERT INTO users_fts(rowid, name, email) VALUES (new.id, new.name, new.email);
+END;
@@ -0,0 +1,11 @@
+ALTER TABLE apps DROP COLUMN groups_claim;
+
+DROP INDEX IF EXISTS idx_group_children_child_id;
+
+DROP TABLE IF EXISTS group_children;
+
+DROP INDEX IF EXISTS idx_group_users_user_id;
+
+DROP TABLE IF EXISTS group_users;
+
+DROP TABLE IF EXISTS groups;
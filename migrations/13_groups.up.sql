@@ -0,0 +1,31 @@
+CREATE TABLE
+    IF NOT EXISTS groups (
+        id INTEGER PRIMARY KEY,
+        org_id INTEGER NOT NULL REFERENCES organizations (id) ON DELETE CASCADE,
+        name TEXT NOT NULL,
+        created_at DATETIME NOT NULL,
+        UNIQUE (org_id, name)
+    );
+
+CREATE TABLE
+    IF NOT EXISTS group_users (
+        group_id INTEGER NOT NULL REFERENCES groups (id) ON DELETE CASCADE,
+        user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+        created_at DATETIME NOT NULL,
+        PRIMARY KEY (group_id, user_id)
+    );
+
+CREATE INDEX IF NOT EXISTS idx_group_users_user_id ON group_users (user_id);
+
+-- Members of a child group are members of its parent too.
+CREATE TABLE
+    IF NOT EXISTS group_children (
+        group_id INTEGER NOT NULL REFERENCES groups (id) ON DELETE CASCADE,
+        child_id INTEGER NOT NULL REFERENCES groups (id) ON DELETE CASCADE,
+        created_at DATETIME NOT NULL,
+        PRIMARY KEY (group_id, child_id)
+    );
+
+CREATE INDEX IF NOT EXISTS idx_group_children_child_id ON group_children (child_id);
+
+ALTER TABLE apps ADD COLUMN groups_claim BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,15 @@
+CREATE TABLE
+    IF NOT EXISTS api_keys (
+        id INTEGER PRIMARY KEY,
+        user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+        app_id INTEGER NOT NULL REFERENCES apps (id) ON DELETE CASCADE,
+        name TEXT NOT NULL,
+        key_hash BLOB NOT NULL UNIQUE,
+        scope TEXT NOT NULL DEFAULT '',
+        created_at DATETIME NOT NULL,
+        expires_at DATETIME,
+        last_used_at DATETIME,
+        revoked_at DATETIME
+    );
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON api_keys (user_id, revoked_at);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_audit_events_target_user_id;
+
+DROP INDEX IF EXISTS idx_audit_events_actor_id;
+
+DROP TABLE IF EXISTS audit_events;
+
+ALTER TABLE sessions DROP COLUMN impersonator_id;
@@ -0,0 +1,20 @@
+ALTER TABLE sessions ADD COLUMN impersonator_id INTEGER REFERENCES users (id) ON DELETE SET NULL;
+
+-- Audit events outlive the users and apps they refer to, so there are no
+-- foreign keys.
+CREATE TABLE
+    IF NOT EXISTS audit_events (
+        id INTEGER PRIMARY KEY,
+        actor_id INTEGER NOT NULL,
+        action TEXT NOT NULL,
+        target_user_id INTEGER NOT NULL DEFAULT 0,
+        app_id INTEGER NOT NULL DEFAULT 0,
+        session_id INTEGER NOT NULL DEFAULT 0,
+        reason TEXT NOT NULL DEFAULT '',
+        ip TEXT NOT NULL DEFAULT '',
+        created_at DATETIME NOT NULL
+    );
+
+CREATE INDEX IF NOT EXISTS idx_audit_events_actor_id ON audit_events (actor_id, created_at);
+
+CREATE INDEX IF NOT EXISTS idx_audit_events_target_user_id ON audit_events (target_user_id, created_at);
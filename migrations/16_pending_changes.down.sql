@@ -0,0 +1,5 @@
+ALTER TABLE audit_events DROP COLUMN change_id;
+
+DROP INDEX IF EXISTS idx_pending_changes_status;
+
+DROP TABLE IF EXISTS pending_changes;
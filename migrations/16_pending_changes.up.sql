@@ -0,0 +1,18 @@
+CREATE TABLE
+    IF NOT EXISTS pending_changes (
+        id INTEGER PRIMARY KEY,
+        kind TEXT NOT NULL,
+        proposer_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+        target_user_id INTEGER NOT NULL DEFAULT 0,
+        app_id INTEGER NOT NULL DEFAULT 0,
+        reason TEXT NOT NULL,
+        status TEXT NOT NULL DEFAULT 'pending',
+        decided_by INTEGER REFERENCES users (id) ON DELETE SET NULL,
+        decided_at DATETIME,
+        created_at DATETIME NOT NULL,
+        expires_at DATETIME NOT NULL
+    );
+
+CREATE INDEX IF NOT EXISTS idx_pending_changes_status ON pending_changes (status, expires_at);
+
+ALTER TABLE audit_events ADD COLUMN change_id INTEGER NOT NULL DEFAULT 0;
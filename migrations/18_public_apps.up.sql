@@ -0,0 +1 @@
+ALTER TABLE apps ADD COLUMN public BOOLEAN NOT NULL DEFAULT FALSE;
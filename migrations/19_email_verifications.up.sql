@@ -0,0 +1,10 @@
+CREATE TABLE
+    IF NOT EXISTS email_verifications (
+        id INTEGER PRIMARY KEY,
+        user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+        email TEXT NOT NULL,
+        token_hash BLOB NOT NULL UNIQUE,
+        created_at DATETIME NOT NULL,
+        expires_at DATETIME NOT NULL,
+        used_at DATETIME
+    );
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS refresh_tokens;
+
+DROP TABLE IF EXISTS authorization_codes;
+
+DROP TABLE IF EXISTS app_redirect_uris;
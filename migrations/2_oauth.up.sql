@@ -0,0 +1,41 @@
+CREATE TABLE
+    IF NOT EXISTS app_redirect_uris (
+        id INTEGER PRIMARY KEY,
+        app_id INTEGER NOT NULL REFERENCES apps (id) ON DELETE CASCADE,
+        uri TEXT NOT NULL,
+        UNIQUE (app_id, uri)
+    );
+
+CREATE TABLE
+    IF NOT EXISTS authorization_codes (
+        id INTEGER PRIMARY KEY,
+        code_hash BLOB NOT NULL UNIQUE,
+        app_id INTEGER NOT NULL REFERENCES apps (id) ON DELETE CASCADE,
+        user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+        redirect_uri TEXT NOT NULL,
+        scope TEXT NOT NULL DEFAULT '',
+        code_challenge TEXT NOT NULL,
+        code_challenge_method TEXT NOT NULL,
+        created_at DATETIME NOT NULL,
+        expires_at DATETIME NOT NULL,
+        used_at DATETIME
+    );
+
+CREATE INDEX IF NOT EXISTS idx_authorization_codes_expires_at ON authorization_codes (expires_at);
+
+CREATE TABLE
+    IF NOT EXISTS refresh_tokens (
+        id INTEGER PRIMARY KEY,
+        token_hash BLOB NOT NULL UNIQUE,
+        app_id INTEGER NOT NULL REFERENCES apps (id) ON DELETE CASCADE,
+        user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+        authorization_code_id INTEGER REFERENCES authorization_codes (id) ON DELETE SET NULL,
+        scope TEXT NOT NULL DEFAULT '',
+        created_at DATETIME NOT NULL,
+        expires_at DATETIME NOT NULL,
+        revoked_at DATETIME
+    );
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens (user_id);
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_authorization_code_id ON refresh_tokens (authorization_code_id);
@@ -0,0 +1,5 @@
+ALTER TABLE refresh_tokens DROP COLUMN auth_time;
+
+ALTER TABLE authorization_codes DROP COLUMN auth_time;
+
+ALTER TABLE authorization_codes DROP COLUMN nonce;
@@ -0,0 +1,9 @@
+ALTER TABLE authorization_codes ADD COLUMN nonce TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE authorization_codes ADD COLUMN auth_time DATETIME;
+
+UPDATE authorization_codes SET auth_time = created_at WHERE auth_time IS NULL;
+
+ALTER TABLE refresh_tokens ADD COLUMN auth_time DATETIME;
+
+UPDATE refresh_tokens SET auth_time = created_at WHERE auth_time IS NULL;
@@ -0,0 +1,7 @@
+CREATE TABLE
+    IF NOT EXISTS app_scopes (
+        id INTEGER PRIMARY KEY,
+        app_id INTEGER NOT NULL REFERENCES apps (id) ON DELETE CASCADE,
+        scope TEXT NOT NULL,
+        UNIQUE (app_id, scope)
+    );
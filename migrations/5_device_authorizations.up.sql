@@ -0,0 +1,17 @@
+CREATE TABLE
+    IF NOT EXISTS device_authorizations (
+        id INTEGER PRIMARY KEY,
+        device_code_hash BLOB NOT NULL UNIQUE,
+        user_code TEXT NOT NULL UNIQUE,
+        app_id INTEGER NOT NULL REFERENCES apps (id) ON DELETE CASCADE,
+        scope TEXT NOT NULL DEFAULT '',
+        status TEXT NOT NULL DEFAULT 'pending',
+        user_id INTEGER REFERENCES users (id) ON DELETE CASCADE,
+        poll_interval INTEGER NOT NULL,
+        last_polled_at DATETIME,
+        auth_time DATETIME,
+        created_at DATETIME NOT NULL,
+        expires_at DATETIME NOT NULL
+    );
+
+CREATE INDEX IF NOT EXISTS idx_device_authorizations_expires_at ON device_authorizations (expires_at);
@@ -0,0 +1,8 @@
+CREATE TABLE
+    IF NOT EXISTS token_exchange_policies (
+        id INTEGER PRIMARY KEY,
+        source_app_id INTEGER NOT NULL REFERENCES apps (id) ON DELETE CASCADE,
+        target_app_id INTEGER NOT NULL REFERENCES apps (id) ON DELETE CASCADE,
+        scope TEXT NOT NULL,
+        UNIQUE (source_app_id, target_app_id, scope)
+    );
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_refresh_tokens_session_id;
+
+ALTER TABLE refresh_tokens DROP COLUMN session_id;
+
+DROP TABLE IF EXISTS sessions;
@@ -0,0 +1,18 @@
+CREATE TABLE
+    IF NOT EXISTS sessions (
+        id INTEGER PRIMARY KEY,
+        user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+        app_id INTEGER NOT NULL REFERENCES apps (id) ON DELETE CASCADE,
+        device TEXT NOT NULL DEFAULT '',
+        ip TEXT NOT NULL DEFAULT '',
+        user_agent TEXT NOT NULL DEFAULT '',
+        created_at DATETIME NOT NULL,
+        last_seen_at DATETIME NOT NULL,
+        revoked_at DATETIME
+    );
+
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions (user_id, revoked_at);
+
+ALTER TABLE refresh_tokens ADD COLUMN session_id INTEGER REFERENCES sessions (id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_session_id ON refresh_tokens (session_id);
@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_users_status_created_at;
+
+DROP INDEX IF EXISTS idx_users_is_admin_created_at;
+
+DROP INDEX IF EXISTS idx_users_created_at;
+
+ALTER TABLE users DROP COLUMN status;
+
+ALTER TABLE users DROP COLUMN created_at;
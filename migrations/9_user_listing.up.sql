@@ -0,0 +1,11 @@
+ALTER TABLE users ADD COLUMN created_at DATETIME;
+
+UPDATE users SET created_at = strftime('%Y-%m-%d %H:%M:%S+00:00', 'now') WHERE created_at IS NULL;
+
+ALTER TABLE users ADD COLUMN status TEXT NOT NULL DEFAULT 'active';
+
+CREATE INDEX IF NOT EXISTS idx_users_created_at ON users (created_at, id);
+
+CREATE INDEX IF NOT EXISTS idx_users_is_admin_created_at ON users (is_admin, created_at, id);
+
+CREATE INDEX IF NOT EXISTS idx_users_status_created_at ON users (status, created_at, id);
@@ -0,0 +1,141 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	ssov1 "github.com/DavidG9999/api/gen/go/sso"
+	"github.com/DavidG9999/my_grpc_app/tests/suite"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func Test_Access_Policies(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	userToken, userID := signUpAndSignIn(ctx, t, st)
+	userCtx := withBearer(ctx, userToken)
+
+	admin, err := st.AuthClient.GetUser(withBearer(ctx, adminToken(ctx, t, st)), &ssov1.GetUserRequest{})
+	require.NoError(t, err)
+	// An app can sign any claims with its secret, but not tokens of the SSO.
+	forged, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"uid":    admin.GetUser().GetId(),
+		"org_id": tenantOrgID,
+		"app_id": tenantAppID,
+		"sid":    0,
+		"exp":    time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte(tenantAppSecret))
+	require.NoError(t, err)
+
+	tests := []struct {
+		call func(ctx context.Context) error
+		ctx  context.Context
+		code codes.Code
+	}{
+		{
+			//Authenticated RPC without token
+			call: func(ctx context.Context) error {
+				_, err := st.AuthClient.GetUser(ctx, &ssov1.GetUserRequest{})
+				return err
+			},
+			ctx:  ctx,
+			code: codes.Unauthenticated,
+		},
+		{
+			//Authenticated RPC with invalid token
+			call: func(ctx context.Context) error {
+				_, err := st.AuthClient.IsAdmin(ctx, &ssov1.IsAdminRequest{UserId: userID})
+				return err
+			},
+			ctx:  withBearer(ctx, "not-a-token"),
+			code: codes.Unauthenticated,
+		},
+		{
+			//Admin RPC without token
+			call: func(ctx context.Context) error {
+				_, err := st.AuthClient.ListUsers(ctx, &ssov1.ListUsersRequest{})
+				return err
+			},
+			ctx:  ctx,
+			code: codes.Unauthenticated,
+		},
+		{
+			//Admin RPC with a user's token
+			call: func(ctx context.Context) error {
+				_, err := st.AuthClient.ImportUsers(ctx, &ssov1.ImportUsersRequest{})
+				return err
+			},
+			ctx:  userCtx,
+			code: codes.PermissionDenied,
+		},
+		{
+			//Admin RPC with a user's token
+			call: func(ctx context.Context) error {
+				_, err := st.AuthClient.Impersonate(ctx, &ssov1.ImpersonateRequest{UserId: userID, Reason: "test"})
+				return err
+			},
+			ctx:  userCtx,
+			code: codes.PermissionDenied,
+		},
+		{
+			//Admin RPC with a token an app signed for an admin
+			call: func(ctx context.Context) error {
+				_, err := st.AuthClient.ListUsers(ctx, &ssov1.ListUsersRequest{})
+				return err
+			},
+			ctx:  withBearer(ctx, forged),
+			code: codes.Unauthenticated,
+		},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("Test_Access_Policies №%d", i), func(t *testing.T) {
+			err := test.call(test.ctx)
+			require.Error(t, err)
+			assert.Equal(t, test.code, status.Code(err))
+		})
+	}
+
+	resp, err := st.AuthClient.IsAdmin(userCtx, &ssov1.IsAdminRequest{UserId: userID})
+	require.NoError(t, err)
+	assert.False(t, resp.GetIsAdmin())
+}
+
+func Test_Access_AdminDelegatedTokens(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	adminCtx := withBearer(ctx, adminToken(ctx, t, st))
+	created, err := st.AuthClient.CreateAPIKey(adminCtx, &ssov1.CreateAPIKeyRequest{
+		Name:  "admin-key",
+		AppId: appID,
+	})
+	require.NoError(t, err)
+	exchanged, err := st.AuthClient.ExchangeAPIKey(ctx, &ssov1.ExchangeAPIKeyRequest{ApiKey: created.GetKey()})
+	require.NoError(t, err)
+	keyCtx := withBearer(ctx, exchanged.GetToken())
+
+	_, err = st.AuthClient.GetUser(keyCtx, &ssov1.GetUserRequest{})
+	require.NoError(t, err)
+
+	// Admin RPCs need the admin's own token.
+	_, err = st.AuthClient.ListUsers(keyCtx, &ssov1.ListUsersRequest{})
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = st.AuthClient.CreateOrganization(keyCtx, &ssov1.CreateOrganizationRequest{Name: "key-org"})
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// Tokens of a revoked key stop working at once.
+	_, err = st.AuthClient.RevokeAPIKey(adminCtx, &ssov1.RevokeAPIKeyRequest{ApiKeyId: created.GetApiKey().GetId()})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.GetUser(keyCtx, &ssov1.GetUserRequest{})
+	require.Error(t, err)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
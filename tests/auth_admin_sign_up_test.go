@@ -0,0 +1,74 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	ssov1 "github.com/DavidG9999/api/gen/go/sso"
+	"github.com/DavidG9999/my_grpc_app/tests/suite"
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const bootstrapToken = "test-bootstrap-token"
+
+func Test_SignUp_AdminDenied(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	userToken, _ := signUpAndSignIn(ctx, t, st)
+	adminCtx := withBearer(ctx, adminToken(ctx, t, st))
+
+	tests := []struct {
+		ctx            context.Context
+		bootstrapToken string
+	}{
+		{
+			//Anonymous caller
+			ctx: ctx,
+		},
+		{
+			//Caller who is not an admin
+			ctx: withBearer(ctx, userToken),
+		},
+		{
+			//Global admin, who has to propose a promotion instead
+			ctx: adminCtx,
+		},
+		{
+			//Wrong bootstrap token
+			ctx:            ctx,
+			bootstrapToken: "wrong-" + bootstrapToken,
+		},
+		{
+			//Bootstrap token after the first admin exists
+			ctx:            ctx,
+			bootstrapToken: bootstrapToken,
+		},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("Test_SignUp_AdminDenied №%d", i), func(t *testing.T) {
+			resp, err := st.AuthClient.SignUp(test.ctx, &ssov1.SignUpRequest{
+				Name:           gofakeit.Username(),
+				Email:          gofakeit.Email(),
+				Password:       randomFakePassword(),
+				IsAdmin:        true,
+				BootstrapToken: test.bootstrapToken,
+			})
+			require.NoError(t, err)
+			assert.NotZero(t, resp.GetUserId())
+			assert.False(t, resp.GetIsAdmin())
+		})
+	}
+
+	_, err := st.AuthClient.SignUp(withBearer(ctx, "not-a-token"), &ssov1.SignUpRequest{
+		Name:     gofakeit.Username(),
+		Email:    gofakeit.Email(),
+		Password: randomFakePassword(),
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
@@ -0,0 +1,119 @@
+package tests
+
+import (
+	"fmt"
+	"testing"
+
+	ssov1 "github.com/DavidG9999/api/gen/go/sso"
+	"github.com/DavidG9999/my_grpc_app/tests/suite"
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func Test_APIKeys_HappyPath(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	token, userID := signUpAndSignIn(ctx, t, st)
+	userCtx := withBearer(ctx, token)
+
+	name := gofakeit.AppName()
+	created, err := st.AuthClient.CreateAPIKey(userCtx, &ssov1.CreateAPIKeyRequest{
+		Name:      name,
+		AppId:     appID,
+		Scopes:    []string{"users:read"},
+		ExpiresIn: 3600,
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, created.GetKey())
+	assert.Equal(t, name, created.GetApiKey().GetName())
+	assert.Equal(t, []string{"users:read"}, created.GetApiKey().GetScopes())
+	assert.NotZero(t, created.GetApiKey().GetExpiresAt())
+	assert.Zero(t, created.GetApiKey().GetLastUsedAt())
+
+	exchanged, err := st.AuthClient.ExchangeAPIKey(ctx, &ssov1.ExchangeAPIKeyRequest{
+		ApiKey: created.GetKey(),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"users:read"}, exchanged.GetScopes())
+	assert.Equal(t, int64(st.Cfg.TokenTTL.Seconds()), exchanged.GetExpiresIn())
+
+	tokenParsed, err := jwt.Parse(exchanged.GetToken(), func(token *jwt.Token) (interface{}, error) {
+		return []byte(appSecret), nil
+	})
+	require.NoError(t, err)
+	claims, ok := tokenParsed.Claims.(jwt.MapClaims)
+	require.True(t, ok)
+	assert.Equal(t, userID, int64(claims["uid"].(float64)))
+	assert.Equal(t, created.GetApiKey().GetId(), int64(claims["api_key_id"].(float64)))
+	assert.Equal(t, "users:read", claims["scope"].(string))
+
+	listed, err := st.AuthClient.ListAPIKeys(userCtx, &ssov1.ListAPIKeysRequest{})
+	require.NoError(t, err)
+	require.Len(t, listed.GetApiKeys(), 1)
+	assert.Equal(t, created.GetApiKey().GetId(), listed.GetApiKeys()[0].GetId())
+	assert.NotZero(t, listed.GetApiKeys()[0].GetLastUsedAt())
+
+	// Tokens of API keys cannot mint further keys.
+	_, err = st.AuthClient.CreateAPIKey(withBearer(ctx, exchanged.GetToken()), &ssov1.CreateAPIKeyRequest{Name: name})
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = st.AuthClient.RevokeAPIKey(userCtx, &ssov1.RevokeAPIKeyRequest{ApiKeyId: created.GetApiKey().GetId()})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.ExchangeAPIKey(ctx, &ssov1.ExchangeAPIKeyRequest{ApiKey: created.GetKey()})
+	require.Error(t, err)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	listed, err = st.AuthClient.ListAPIKeys(userCtx, &ssov1.ListAPIKeysRequest{})
+	require.NoError(t, err)
+	assert.Empty(t, listed.GetApiKeys())
+}
+
+func Test_APIKeys_FailCases(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	token, _ := signUpAndSignIn(ctx, t, st)
+	userCtx := withBearer(ctx, token)
+
+	created, err := st.AuthClient.CreateAPIKey(userCtx, &ssov1.CreateAPIKeyRequest{
+		Name:   gofakeit.AppName(),
+		Scopes: []string{"users:read"},
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		req  *ssov1.ExchangeAPIKeyRequest
+		code codes.Code
+	}{
+		//empty key
+		{req: &ssov1.ExchangeAPIKeyRequest{}, code: codes.InvalidArgument},
+		//unknown key
+		{req: &ssov1.ExchangeAPIKeyRequest{ApiKey: "sso_" + gofakeit.LetterN(43)}, code: codes.Unauthenticated},
+		//scope beyond the key
+		{req: &ssov1.ExchangeAPIKeyRequest{ApiKey: created.GetKey(), Scopes: []string{"users:write"}}, code: codes.PermissionDenied},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("Test_ExchangeAPIKey_FailCases №%d", i), func(t *testing.T) {
+			_, err := st.AuthClient.ExchangeAPIKey(ctx, test.req)
+			require.Error(t, err)
+			assert.Equal(t, test.code, status.Code(err))
+		})
+	}
+
+	_, err = st.AuthClient.CreateAPIKey(userCtx, &ssov1.CreateAPIKeyRequest{
+		Name:   gofakeit.AppName(),
+		Scopes: []string{"admin"},
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	otherToken, _ := signUpAndSignIn(ctx, t, st)
+	_, err = st.AuthClient.RevokeAPIKey(withBearer(ctx, otherToken), &ssov1.RevokeAPIKeyRequest{ApiKeyId: created.GetApiKey().GetId()})
+	require.Error(t, err)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
@@ -0,0 +1,136 @@
+package tests
+
+import (
+	"net/url"
+	"strconv"
+	"testing"
+
+	ssov1 "github.com/DavidG9999/api/gen/go/sso"
+	"github.com/DavidG9999/my_grpc_app/tests/suite"
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	inviteOnlyAppID     = 1001
+	inviteOnlyAppSecret = "test-invite-only-secret"
+)
+
+func Test_AppMembers_HappyPath(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	email := gofakeit.Email()
+	password := randomFakePassword()
+	respSignUp, err := st.AuthClient.SignUp(ctx, &ssov1.SignUpRequest{
+		Name:     gofakeit.Username(),
+		Email:    email,
+		Password: password,
+	})
+	require.NoError(t, err)
+	userID := respSignUp.GetUserId()
+
+	signIn := func() error {
+		_, err := st.AuthClient.SignIn(ctx, &ssov1.SignInRequest{
+			Email:    email,
+			Password: password,
+			AppId:    inviteOnlyAppID,
+		})
+		return err
+	}
+
+	err = signIn()
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	adminCtx := withBearer(ctx, adminToken(ctx, t, st))
+	_, err = st.AuthClient.AddUserToApp(adminCtx, &ssov1.AddUserToAppRequest{AppId: inviteOnlyAppID, UserId: userID})
+	require.NoError(t, err)
+	require.NoError(t, signIn())
+
+	_, err = st.AuthClient.RemoveUserFromApp(adminCtx, &ssov1.RemoveUserFromAppRequest{AppId: inviteOnlyAppID, UserId: userID})
+	require.NoError(t, err)
+
+	err = signIn()
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = st.AuthClient.RemoveUserFromApp(adminCtx, &ssov1.RemoveUserFromAppRequest{AppId: inviteOnlyAppID, UserId: userID})
+	require.Error(t, err)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func Test_AppMembers_RemovedUserCannotRefresh(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	email := gofakeit.Email()
+	password := randomFakePassword()
+	respSignUp, err := st.AuthClient.SignUp(ctx, &ssov1.SignUpRequest{
+		Name:     gofakeit.Username(),
+		Email:    email,
+		Password: password,
+	})
+	require.NoError(t, err)
+
+	adminCtx := withBearer(ctx, adminToken(ctx, t, st))
+	_, err = st.AuthClient.AddUserToApp(adminCtx, &ssov1.AddUserToAppRequest{AppId: inviteOnlyAppID, UserId: respSignUp.GetUserId()})
+	require.NoError(t, err)
+
+	client := url.Values{
+		"client_id":     {strconv.Itoa(inviteOnlyAppID)},
+		"client_secret": {inviteOnlyAppSecret},
+	}
+	verifier := gofakeit.LetterN(64)
+	code := authorize(t, st, email, password, verifier, url.Values{"client_id": client["client_id"]})
+	token := oauthToken(t, st, url.Values{
+		"grant_type":    {"authorization_code"},
+		"client_id":     client["client_id"],
+		"client_secret": client["client_secret"],
+		"code":          {code},
+		"redirect_uri":  {redirectURI},
+		"code_verifier": {verifier},
+	})
+	require.Empty(t, token.Error)
+
+	_, err = st.AuthClient.RemoveUserFromApp(adminCtx, &ssov1.RemoveUserFromAppRequest{AppId: inviteOnlyAppID, UserId: respSignUp.GetUserId()})
+	require.NoError(t, err)
+
+	refreshed := oauthToken(t, st, url.Values{
+		"grant_type":    {"refresh_token"},
+		"client_id":     client["client_id"],
+		"client_secret": client["client_secret"],
+		"refresh_token": {token.RefreshToken},
+	})
+	assert.Equal(t, "invalid_grant", refreshed.Error)
+}
+
+func Test_AppMembers_FailCases(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	token, userID := signUpAndSignIn(ctx, t, st)
+	_, err := st.AuthClient.AddUserToApp(withBearer(ctx, token), &ssov1.AddUserToAppRequest{
+		AppId:  inviteOnlyAppID,
+		UserId: userID,
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	adminCtx := withBearer(ctx, adminToken(ctx, t, st))
+
+	_, err = st.AuthClient.AddUserToApp(adminCtx, &ssov1.AddUserToAppRequest{
+		AppId:  inviteOnlyAppID + 1000,
+		UserId: userID,
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	assert.Contains(t, err.Error(), "app not found")
+
+	_, err = st.AuthClient.AddUserToApp(adminCtx, &ssov1.AddUserToAppRequest{
+		AppId: inviteOnlyAppID,
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Contains(t, err.Error(), "user_id is required")
+}
@@ -0,0 +1,202 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	ssov1 "github.com/DavidG9999/api/gen/go/sso"
+	"github.com/DavidG9999/my_grpc_app/tests/suite"
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	approverEmail    = "test-approver@example.com"
+	approverPassword = "test-admin-pass"
+)
+
+func Test_ApproveChange_PromoteAdmin(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	adminCtx := withBearer(ctx, adminToken(ctx, t, st))
+	approverCtx := withBearer(ctx, approverToken(ctx, t, st))
+	_, userID := signUpAndSignIn(ctx, t, st)
+
+	respPropose, err := st.AuthClient.ProposeChange(adminCtx, &ssov1.ProposeChangeRequest{
+		Kind:   "promote_admin",
+		UserId: userID,
+		Reason: "on-call rotation",
+	})
+	require.NoError(t, err)
+	change := respPropose.GetChange()
+	assert.Equal(t, "pending", change.GetStatus())
+	assert.Equal(t, userID, change.GetUserId())
+	assert.Greater(t, change.GetExpiresAt(), change.GetCreatedAt())
+
+	_, err = st.AuthClient.ApproveChange(adminCtx, &ssov1.ApproveChangeRequest{ChangeId: change.GetId()})
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	respIsAdmin, err := st.AuthClient.IsAdmin(adminCtx, &ssov1.IsAdminRequest{UserId: userID})
+	require.NoError(t, err)
+	assert.False(t, respIsAdmin.GetIsAdmin())
+
+	respApprove, err := st.AuthClient.ApproveChange(approverCtx, &ssov1.ApproveChangeRequest{ChangeId: change.GetId()})
+	require.NoError(t, err)
+	assert.Equal(t, "approved", respApprove.GetChange().GetStatus())
+	assert.Empty(t, respApprove.GetAppSecret())
+
+	respIsAdmin, err = st.AuthClient.IsAdmin(adminCtx, &ssov1.IsAdminRequest{UserId: userID})
+	require.NoError(t, err)
+	assert.True(t, respIsAdmin.GetIsAdmin())
+
+	_, err = st.AuthClient.ApproveChange(approverCtx, &ssov1.ApproveChangeRequest{ChangeId: change.GetId()})
+	require.Error(t, err)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+}
+
+func Test_RejectChange(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	adminCtx := withBearer(ctx, adminToken(ctx, t, st))
+	approverCtx := withBearer(ctx, approverToken(ctx, t, st))
+	userToken, userID := signUpAndSignIn(ctx, t, st)
+
+	respPropose, err := st.AuthClient.ProposeChange(adminCtx, &ssov1.ProposeChangeRequest{
+		Kind:   "promote_admin",
+		UserId: userID,
+		Reason: "on-call rotation",
+	})
+	require.NoError(t, err)
+	changeID := respPropose.GetChange().GetId()
+
+	_, err = st.AuthClient.RejectChange(withBearer(ctx, userToken), &ssov1.RejectChangeRequest{ChangeId: changeID})
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = st.AuthClient.RejectChange(approverCtx, &ssov1.RejectChangeRequest{ChangeId: changeID, Reason: "not approved by the team"})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.ApproveChange(approverCtx, &ssov1.ApproveChangeRequest{ChangeId: changeID})
+	require.Error(t, err)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	respIsAdmin, err := st.AuthClient.IsAdmin(adminCtx, &ssov1.IsAdminRequest{UserId: userID})
+	require.NoError(t, err)
+	assert.False(t, respIsAdmin.GetIsAdmin())
+}
+
+func Test_ProposeChange_FailCases(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	adminCtx := withBearer(ctx, adminToken(ctx, t, st))
+	userToken, userID := signUpAndSignIn(ctx, t, st)
+
+	tests := []struct {
+		ctx          context.Context
+		req          *ssov1.ProposeChangeRequest
+		expectedCode codes.Code
+	}{
+		{
+			//Unknown kind
+			ctx:          adminCtx,
+			req:          &ssov1.ProposeChangeRequest{Kind: "drop_users", Reason: "cleanup"},
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			//Empty reason
+			ctx:          adminCtx,
+			req:          &ssov1.ProposeChangeRequest{Kind: "promote_admin", UserId: userID},
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			//Empty user id
+			ctx:          adminCtx,
+			req:          &ssov1.ProposeChangeRequest{Kind: "promote_admin", Reason: "on-call rotation"},
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			//Empty app id
+			ctx:          adminCtx,
+			req:          &ssov1.ProposeChangeRequest{Kind: "rotate_app_secret", Reason: "secret leaked"},
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			//Unknown user
+			ctx:          adminCtx,
+			req:          &ssov1.ProposeChangeRequest{Kind: "promote_admin", UserId: 1 << 40, Reason: "on-call rotation"},
+			expectedCode: codes.NotFound,
+		},
+		{
+			//Unknown app
+			ctx:          adminCtx,
+			req:          &ssov1.ProposeChangeRequest{Kind: "delete_app", AppId: 1 << 30, Reason: "retired"},
+			expectedCode: codes.NotFound,
+		},
+		{
+			//Caller who is not an admin
+			ctx:          withBearer(ctx, userToken),
+			req:          &ssov1.ProposeChangeRequest{Kind: "promote_admin", UserId: userID, Reason: "promote me"},
+			expectedCode: codes.PermissionDenied,
+		},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("Test_ProposeChange_FailCases №%d", i), func(t *testing.T) {
+			_, err := st.AuthClient.ProposeChange(test.ctx, test.req)
+			require.Error(t, err)
+			assert.Equal(t, test.expectedCode, status.Code(err))
+		})
+	}
+
+	_, err := st.AuthClient.ApproveChange(adminCtx, &ssov1.ApproveChangeRequest{ChangeId: 1 << 40})
+	require.Error(t, err)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func approverToken(ctx context.Context, t *testing.T, st *suite.Suite) string {
+	t.Helper()
+
+	respSignIn, err := st.AuthClient.SignIn(ctx, &ssov1.SignInRequest{
+		Email:    approverEmail,
+		Password: approverPassword,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+
+	return respSignIn.GetToken()
+}
+
+// promoteAdmin makes the user a global admin, proposed by the test admin and
+// approved by the second one.
+func promoteAdmin(ctx context.Context, t *testing.T, st *suite.Suite, userID int64) {
+	t.Helper()
+
+	approveUserChange(ctx, t, st, "promote_admin", userID)
+}
+
+// promoteOrgAdmin makes the user an admin of their organization the same way.
+func promoteOrgAdmin(ctx context.Context, t *testing.T, st *suite.Suite, userID int64) {
+	t.Helper()
+
+	approveUserChange(ctx, t, st, "promote_org_admin", userID)
+}
+
+func approveUserChange(ctx context.Context, t *testing.T, st *suite.Suite, kind string, userID int64) {
+	t.Helper()
+
+	respPropose, err := st.AuthClient.ProposeChange(withBearer(ctx, adminToken(ctx, t, st)), &ssov1.ProposeChangeRequest{
+		Kind:   kind,
+		UserId: userID,
+		Reason: "test " + gofakeit.DigitN(6),
+	})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.ApproveChange(withBearer(ctx, approverToken(ctx, t, st)), &ssov1.ApproveChangeRequest{
+		ChangeId: respPropose.GetChange().GetId(),
+	})
+	require.NoError(t, err)
+}
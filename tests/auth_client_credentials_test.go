@@ -0,0 +1,91 @@
+package tests
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	ssov1 "github.com/DavidG9999/api/gen/go/sso"
+	"github.com/DavidG9999/my_grpc_app/tests/suite"
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ClientCredentials_HappyPath(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	resp, err := st.AuthClient.ClientCredentials(ctx, &ssov1.ClientCredentialsRequest{
+		AppId:     appID,
+		AppSecret: appSecret,
+		Scopes:    []string{"users:read"},
+	})
+	require.NoError(t, err)
+
+	issueTime := time.Now()
+
+	tokenParsed, err := jwt.Parse(resp.GetToken(), func(token *jwt.Token) (interface{}, error) {
+		return []byte(appSecret), nil
+	})
+	require.NoError(t, err)
+
+	claims, ok := tokenParsed.Claims.(jwt.MapClaims)
+	require.True(t, ok)
+
+	const deltaSeconds = 1
+	assert.Equal(t, fmt.Sprint(appID), claims["sub"])
+	assert.Equal(t, "users:read", claims["scope"])
+	assert.NotContains(t, claims, "uid")
+	assert.InDelta(t, issueTime.Add(st.Cfg.TokenTTL).Unix(), claims["exp"].(float64), deltaSeconds)
+	assert.Equal(t, []string{"users:read"}, resp.GetScopes())
+}
+
+func Test_ClientCredentials_FailCases(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	tests := []struct {
+		appID       int32
+		appSecret   string
+		scopes      []string
+		expectedErr string
+	}{
+		{
+			//Request with empty App ID
+			appSecret:   appSecret,
+			expectedErr: "app_id is required",
+		},
+		{
+			//Request with empty App secret
+			appID:       appID,
+			expectedErr: "app_secret is required",
+		},
+		{
+			//Request with wrong App secret
+			appID:       appID,
+			appSecret:   gofakeit.LetterN(16),
+			expectedErr: "invalid app credentials",
+		},
+		{
+			//Request with scope not registered for the app
+			appID:       appID,
+			appSecret:   appSecret,
+			scopes:      []string{"admin"},
+			expectedErr: "scope is not allowed for app",
+		},
+	}
+	i := 1
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("Test_ClientCredentials_FailCases №%d", i), func(t *testing.T) {
+			resp, err := st.AuthClient.ClientCredentials(ctx, &ssov1.ClientCredentialsRequest{
+				AppId:     test.appID,
+				AppSecret: test.appSecret,
+				Scopes:    test.scopes,
+			})
+			require.Error(t, err)
+			assert.Empty(t, resp.GetToken())
+			assert.ErrorContains(t, err, test.expectedErr)
+			i++
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package tests
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	ssov1 "github.com/DavidG9999/api/gen/go/sso"
+	"github.com/DavidG9999/my_grpc_app/tests/suite"
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_DeviceAuthorization_HappyPath(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	email := gofakeit.Email()
+	password := randomFakePassword()
+
+	_, err := st.AuthClient.SignUp(ctx, &ssov1.SignUpRequest{
+		Name:     gofakeit.Username(),
+		Email:    email,
+		Password: password,
+	})
+	require.NoError(t, err)
+
+	respStart, err := st.AuthClient.StartDeviceAuthorization(ctx, &ssov1.StartDeviceAuthorizationRequest{
+		AppId: appID,
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, respStart.GetDeviceCode())
+	require.NotEmpty(t, respStart.GetUserCode())
+	interval := time.Duration(respStart.GetInterval()) * time.Second
+
+	_, err = st.AuthClient.PollDeviceToken(ctx, &ssov1.PollDeviceTokenRequest{
+		AppId:      appID,
+		DeviceCode: respStart.GetDeviceCode(),
+	})
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "authorization_pending")
+
+	_, err = st.AuthClient.PollDeviceToken(ctx, &ssov1.PollDeviceTokenRequest{
+		AppId:      appID,
+		DeviceCode: respStart.GetDeviceCode(),
+	})
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "slow_down")
+
+	resp, err := st.HTTPClient.Post(st.HTTPURL+"/device", "application/x-www-form-urlencoded", strings.NewReader(url.Values{
+		"user_code": {respStart.GetUserCode()},
+		"email":     {email},
+		"password":  {password},
+		"action":    {"approve"},
+	}.Encode()))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// slow_down increases the interval by five seconds.
+	time.Sleep(interval + 5*time.Second)
+
+	respPoll, err := st.AuthClient.PollDeviceToken(ctx, &ssov1.PollDeviceTokenRequest{
+		AppId:      appID,
+		DeviceCode: respStart.GetDeviceCode(),
+	})
+	require.NoError(t, err)
+	assert.NotEmpty(t, respPoll.GetToken())
+	assert.NotEmpty(t, respPoll.GetRefreshToken())
+}
+
+func Test_DeviceAuthorization_Denied(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	email := gofakeit.Email()
+	password := randomFakePassword()
+
+	_, err := st.AuthClient.SignUp(ctx, &ssov1.SignUpRequest{
+		Name:     gofakeit.Username(),
+		Email:    email,
+		Password: password,
+	})
+	require.NoError(t, err)
+
+	respStart, err := st.AuthClient.StartDeviceAuthorization(ctx, &ssov1.StartDeviceAuthorizationRequest{
+		AppId: appID,
+	})
+	require.NoError(t, err)
+
+	resp, err := st.HTTPClient.Post(st.HTTPURL+"/device", "application/x-www-form-urlencoded", strings.NewReader(url.Values{
+		"user_code": {respStart.GetUserCode()},
+		"email":     {email},
+		"password":  {password},
+		"action":    {"deny"},
+	}.Encode()))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	_, err = st.AuthClient.PollDeviceToken(ctx, &ssov1.PollDeviceTokenRequest{
+		AppId:      appID,
+		DeviceCode: respStart.GetDeviceCode(),
+	})
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "access_denied")
+}
+
+func Test_DeviceAuthorization_Confirmation(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	email := gofakeit.Email()
+	password := randomFakePassword()
+
+	_, err := st.AuthClient.SignUp(ctx, &ssov1.SignUpRequest{
+		Name:     gofakeit.Username(),
+		Email:    email,
+		Password: password,
+	})
+	require.NoError(t, err)
+
+	respStart, err := st.AuthClient.StartDeviceAuthorization(ctx, &ssov1.StartDeviceAuthorizationRequest{
+		AppId:  appID,
+		Scopes: []string{"openid", "profile"},
+	})
+	require.NoError(t, err)
+
+	// The app and scope are shown before anything is decided.
+	resp, err := st.HTTPClient.Get(st.HTTPURL + "/device?" + url.Values{"user_code": {respStart.GetUserCode()}}.Encode())
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, string(body), "Connect test")
+	assert.Contains(t, string(body), "<li>profile</li>")
+
+	// Signing in without deciding records nothing.
+	resp, err = st.HTTPClient.Post(st.HTTPURL+"/device", "application/x-www-form-urlencoded", strings.NewReader(url.Values{
+		"user_code": {respStart.GetUserCode()},
+		"email":     {email},
+		"password":  {password},
+	}.Encode()))
+	require.NoError(t, err)
+	body, err = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, string(body), "Connect test")
+
+	_, err = st.AuthClient.PollDeviceToken(ctx, &ssov1.PollDeviceTokenRequest{
+		AppId:      appID,
+		DeviceCode: respStart.GetDeviceCode(),
+	})
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "authorization_pending")
+
+	resp, err = st.HTTPClient.Get(st.HTTPURL + "/device?" + url.Values{"user_code": {"BCDF-GHJK"}}.Encode())
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
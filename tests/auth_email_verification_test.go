@@ -0,0 +1,96 @@
+package tests
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	ssov1 "github.com/DavidG9999/api/gen/go/sso"
+	"github.com/DavidG9999/my_grpc_app/internal/lib/mail"
+	"github.com/DavidG9999/my_grpc_app/tests/suite"
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func Test_EmailVerification(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	token, _ := signUpAndSignIn(ctx, t, st)
+	respGet, err := st.AuthClient.GetUser(withBearer(ctx, token), &ssov1.GetUserRequest{})
+	require.NoError(t, err)
+	email := respGet.GetUser().GetEmail()
+
+	_, err = st.AuthClient.SendEmailVerification(withBearer(ctx, token), &ssov1.SendEmailVerificationRequest{})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.VerifyEmail(ctx, &ssov1.VerifyEmailRequest{Token: "unknown"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	verificationToken := mailedVerificationToken(t, st, email)
+	_, err = st.AuthClient.VerifyEmail(ctx, &ssov1.VerifyEmailRequest{Token: verificationToken})
+	require.NoError(t, err)
+	assert.True(t, emailVerified(ctx, t, st, token))
+
+	// Tokens are used once.
+	_, err = st.AuthClient.VerifyEmail(ctx, &ssov1.VerifyEmailRequest{Token: verificationToken})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = st.AuthClient.SendEmailVerification(withBearer(ctx, token), &ssov1.SendEmailVerificationRequest{})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	// A new email is verified again, with the token mailed to it.
+	newEmail := gofakeit.Email()
+	_, err = st.AuthClient.UpdateUser(withBearer(ctx, token), &ssov1.UpdateUserRequest{Email: newEmail})
+	require.NoError(t, err)
+	assert.False(t, emailVerified(ctx, t, st, token))
+
+	_, err = st.AuthClient.VerifyEmail(ctx, &ssov1.VerifyEmailRequest{Token: mailedVerificationToken(t, st, newEmail)})
+	require.NoError(t, err)
+	assert.True(t, emailVerified(ctx, t, st, token))
+}
+
+func Test_EmailVerification_EmailChanged(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	token, _ := signUpAndSignIn(ctx, t, st)
+	respGet, err := st.AuthClient.GetUser(withBearer(ctx, token), &ssov1.GetUserRequest{})
+	require.NoError(t, err)
+	email := respGet.GetUser().GetEmail()
+
+	_, err = st.AuthClient.SendEmailVerification(withBearer(ctx, token), &ssov1.SendEmailVerificationRequest{})
+	require.NoError(t, err)
+	verificationToken := mailedVerificationToken(t, st, email)
+
+	_, err = st.AuthClient.UpdateUser(withBearer(ctx, token), &ssov1.UpdateUserRequest{Email: gofakeit.Email()})
+	require.NoError(t, err)
+
+	// The token was mailed to the old email, it does not verify the new one.
+	_, err = st.AuthClient.VerifyEmail(ctx, &ssov1.VerifyEmailRequest{Token: verificationToken})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.False(t, emailVerified(ctx, t, st, token))
+}
+
+// mailedVerificationToken reads the token from the last mail the server put
+// in its outbox for the email.
+func mailedVerificationToken(t *testing.T, st *suite.Suite, email string) string {
+	t.Helper()
+
+	outbox := mail.Outbox{Dir: filepath.Join("..", st.Cfg.Mail.OutboxDir)}
+	data, err := os.ReadFile(outbox.Path(email))
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+func emailVerified(ctx context.Context, t *testing.T, st *suite.Suite, token string) bool {
+	t.Helper()
+
+	respGet, err := st.AuthClient.GetUser(withBearer(ctx, token), &ssov1.GetUserRequest{})
+	require.NoError(t, err)
+	return respGet.GetUser().GetEmailVerified()
+}
@@ -0,0 +1,133 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	ssov1 "github.com/DavidG9999/api/gen/go/sso"
+	"github.com/DavidG9999/my_grpc_app/tests/suite"
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	targetAppID     = 1000
+	targetAppSecret = "test-target-secret"
+)
+
+func Test_ExchangeToken_HappyPath(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	subjectToken, userID := signUpAndSignIn(ctx, t, st)
+
+	resp, err := st.AuthClient.ExchangeToken(ctx, &ssov1.ExchangeTokenRequest{
+		SubjectToken:   subjectToken,
+		ActorAppId:     appID,
+		ActorAppSecret: appSecret,
+		TargetAppId:    targetAppID,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"orders:read"}, resp.GetScopes())
+
+	tokenParsed, err := jwt.Parse(resp.GetToken(), func(token *jwt.Token) (interface{}, error) {
+		return []byte(targetAppSecret), nil
+	})
+	require.NoError(t, err)
+
+	claims, ok := tokenParsed.Claims.(jwt.MapClaims)
+	require.True(t, ok)
+
+	assert.Equal(t, userID, int64(claims["uid"].(float64)))
+	assert.Equal(t, targetAppID, int(claims["app_id"].(float64)))
+	assert.Equal(t, "orders:read", claims["scope"])
+
+	act, ok := claims["act"].(map[string]interface{})
+	require.True(t, ok)
+	assert.Equal(t, fmt.Sprint(appID), act["sub"])
+}
+
+func Test_ExchangeToken_FailCases(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	subjectToken, _ := signUpAndSignIn(ctx, t, st)
+
+	tests := []struct {
+		subjectToken   string
+		actorAppSecret string
+		targetAppID    int32
+		scopes         []string
+		expectedErr    string
+	}{
+		{
+			//Exchange with invalid subject token
+			subjectToken:   gofakeit.LetterN(32),
+			actorAppSecret: appSecret,
+			targetAppID:    targetAppID,
+			expectedErr:    "invalid subject token",
+		},
+		{
+			//Exchange with wrong actor secret
+			subjectToken:   subjectToken,
+			actorAppSecret: gofakeit.LetterN(16),
+			targetAppID:    targetAppID,
+			expectedErr:    "invalid app credentials",
+		},
+		{
+			//Exchange to an app without policy
+			subjectToken:   subjectToken,
+			actorAppSecret: appSecret,
+			targetAppID:    appID,
+			expectedErr:    "token exchange is not allowed for target app",
+		},
+		{
+			//Exchange with scope outside of policy
+			subjectToken:   subjectToken,
+			actorAppSecret: appSecret,
+			targetAppID:    targetAppID,
+			scopes:         []string{"orders:write"},
+			expectedErr:    "scope is not allowed for target app",
+		},
+	}
+	i := 1
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("Test_ExchangeToken_FailCases №%d", i), func(t *testing.T) {
+			resp, err := st.AuthClient.ExchangeToken(ctx, &ssov1.ExchangeTokenRequest{
+				SubjectToken:   test.subjectToken,
+				ActorAppId:     appID,
+				ActorAppSecret: test.actorAppSecret,
+				TargetAppId:    test.targetAppID,
+				Scopes:         test.scopes,
+			})
+			require.Error(t, err)
+			assert.Empty(t, resp.GetToken())
+			assert.ErrorContains(t, err, test.expectedErr)
+			i++
+		})
+	}
+}
+
+func signUpAndSignIn(ctx context.Context, t *testing.T, st *suite.Suite) (string, int64) {
+	t.Helper()
+
+	email := gofakeit.Email()
+	password := randomFakePassword()
+
+	respSignUp, err := st.AuthClient.SignUp(ctx, &ssov1.SignUpRequest{
+		Name:     gofakeit.Username(),
+		Email:    email,
+		Password: password,
+	})
+	require.NoError(t, err)
+
+	respSignIn, err := st.AuthClient.SignIn(ctx, &ssov1.SignInRequest{
+		Email:    email,
+		Password: password,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+
+	return respSignIn.GetToken(), respSignUp.GetUserId()
+}
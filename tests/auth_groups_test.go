@@ -0,0 +1,164 @@
+package tests
+
+import (
+	"testing"
+
+	ssov1 "github.com/DavidG9999/api/gen/go/sso"
+	"github.com/DavidG9999/my_grpc_app/tests/suite"
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func Test_Groups_NestedMembership(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	adminCtx := withBearer(ctx, adminToken(ctx, t, st))
+
+	parent, err := st.AuthClient.CreateGroup(adminCtx, &ssov1.CreateGroupRequest{
+		Name:  "parent-" + gofakeit.LetterN(12),
+		OrgId: tenantOrgID,
+	})
+	require.NoError(t, err)
+	child, err := st.AuthClient.CreateGroup(adminCtx, &ssov1.CreateGroupRequest{
+		Name:  "child-" + gofakeit.LetterN(12),
+		OrgId: tenantOrgID,
+	})
+	require.NoError(t, err)
+
+	email := gofakeit.Email()
+	password := randomFakePassword()
+	user, err := st.AuthClient.SignUp(ctx, &ssov1.SignUpRequest{
+		Name:     gofakeit.Username(),
+		Email:    email,
+		Password: password,
+		AppId:    tenantAppID,
+	})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.AddGroupMember(adminCtx, &ssov1.AddGroupMemberRequest{
+		GroupId:       parent.GetGroupId(),
+		MemberGroupId: child.GetGroupId(),
+	})
+	require.NoError(t, err)
+	_, err = st.AuthClient.AddGroupMember(adminCtx, &ssov1.AddGroupMemberRequest{
+		GroupId: child.GetGroupId(),
+		UserId:  user.GetUserId(),
+	})
+	require.NoError(t, err)
+
+	direct, err := st.AuthClient.ListGroupMembers(adminCtx, &ssov1.ListGroupMembersRequest{
+		GroupId: parent.GetGroupId(),
+	})
+	require.NoError(t, err)
+	assert.Empty(t, direct.GetUsers())
+	require.Len(t, direct.GetGroups(), 1)
+	assert.Equal(t, child.GetGroupId(), direct.GetGroups()[0].GetId())
+
+	effective, err := st.AuthClient.ListGroupMembers(adminCtx, &ssov1.ListGroupMembersRequest{
+		GroupId:   parent.GetGroupId(),
+		Effective: true,
+	})
+	require.NoError(t, err)
+	require.Len(t, effective.GetUsers(), 1)
+	assert.Equal(t, user.GetUserId(), effective.GetUsers()[0].GetId())
+
+	userGroups, err := st.AuthClient.ListUserGroups(adminCtx, &ssov1.ListUserGroupsRequest{
+		UserId:    user.GetUserId(),
+		Effective: true,
+	})
+	require.NoError(t, err)
+	assert.Len(t, userGroups.GetGroups(), 2)
+
+	respSignIn, err := st.AuthClient.SignIn(ctx, &ssov1.SignInRequest{
+		Email:    email,
+		Password: password,
+		AppId:    tenantAppID,
+	})
+	require.NoError(t, err)
+
+	tokenParsed, err := jwt.Parse(respSignIn.GetToken(), func(token *jwt.Token) (interface{}, error) {
+		return []byte(tenantAppSecret), nil
+	})
+	require.NoError(t, err)
+	claims, ok := tokenParsed.Claims.(jwt.MapClaims)
+	require.True(t, ok)
+	groups, ok := claims["groups"].([]interface{})
+	require.True(t, ok)
+	assert.Len(t, groups, 2)
+}
+
+func Test_Groups_RejectsCycles(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	adminCtx := withBearer(ctx, adminToken(ctx, t, st))
+
+	var ids []int64
+	for i := 0; i < 3; i++ {
+		resp, err := st.AuthClient.CreateGroup(adminCtx, &ssov1.CreateGroupRequest{
+			Name: "group-" + gofakeit.LetterN(12),
+		})
+		require.NoError(t, err)
+		ids = append(ids, resp.GetGroupId())
+	}
+	for i := 0; i < 2; i++ {
+		_, err := st.AuthClient.AddGroupMember(adminCtx, &ssov1.AddGroupMemberRequest{
+			GroupId:       ids[i],
+			MemberGroupId: ids[i+1],
+		})
+		require.NoError(t, err)
+	}
+
+	_, err := st.AuthClient.AddGroupMember(adminCtx, &ssov1.AddGroupMemberRequest{
+		GroupId:       ids[2],
+		MemberGroupId: ids[0],
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	_, err = st.AuthClient.AddGroupMember(adminCtx, &ssov1.AddGroupMemberRequest{
+		GroupId:       ids[0],
+		MemberGroupId: ids[0],
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+}
+
+func Test_Groups_FailCases(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	token, _ := signUpAndSignIn(ctx, t, st)
+	userCtx := withBearer(ctx, token)
+
+	_, err := st.AuthClient.CreateGroup(userCtx, &ssov1.CreateGroupRequest{Name: "group-" + gofakeit.LetterN(12)})
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	adminCtx := withBearer(ctx, adminToken(ctx, t, st))
+	name := "group-" + gofakeit.LetterN(12)
+	group, err := st.AuthClient.CreateGroup(adminCtx, &ssov1.CreateGroupRequest{Name: name})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.CreateGroup(adminCtx, &ssov1.CreateGroupRequest{Name: name})
+	require.Error(t, err)
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	_, err = st.AuthClient.AddGroupMember(adminCtx, &ssov1.AddGroupMemberRequest{GroupId: group.GetGroupId()})
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	tenantGroup, err := st.AuthClient.CreateGroup(adminCtx, &ssov1.CreateGroupRequest{
+		Name:  "group-" + gofakeit.LetterN(12),
+		OrgId: tenantOrgID,
+	})
+	require.NoError(t, err)
+	_, err = st.AuthClient.AddGroupMember(adminCtx, &ssov1.AddGroupMemberRequest{
+		GroupId:       group.GetGroupId(),
+		MemberGroupId: tenantGroup.GetGroupId(),
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
@@ -0,0 +1,115 @@
+package tests
+
+import (
+	"strconv"
+	"testing"
+
+	ssov1 "github.com/DavidG9999/api/gen/go/sso"
+	"github.com/DavidG9999/my_grpc_app/tests/suite"
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func Test_Impersonate_HappyPath(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	adminCtx := withBearer(ctx, adminToken(ctx, t, st))
+	admin, err := st.AuthClient.GetUser(adminCtx, &ssov1.GetUserRequest{})
+	require.NoError(t, err)
+
+	userToken, userID := signUpAndSignIn(ctx, t, st)
+	userCtx := withBearer(ctx, userToken)
+
+	resp, err := st.AuthClient.Impersonate(adminCtx, &ssov1.ImpersonateRequest{
+		UserId: userID,
+		AppId:  appID,
+		Reason: "ticket " + gofakeit.DigitN(6),
+	})
+	require.NoError(t, err)
+	assert.Positive(t, resp.GetExpiresIn())
+
+	tokenParsed, err := jwt.Parse(resp.GetToken(), func(token *jwt.Token) (interface{}, error) {
+		return []byte(appSecret), nil
+	})
+	require.NoError(t, err)
+	claims, ok := tokenParsed.Claims.(jwt.MapClaims)
+	require.True(t, ok)
+	assert.Equal(t, userID, int64(claims["uid"].(float64)))
+	act, ok := claims["act"].(map[string]interface{})
+	require.True(t, ok)
+	assert.Equal(t, strconv.FormatInt(admin.GetUser().GetId(), 10), act["sub"])
+
+	impersonatedCtx := withBearer(ctx, resp.GetToken())
+	me, err := st.AuthClient.GetUser(impersonatedCtx, &ssov1.GetUserRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, userID, me.GetUser().GetId())
+
+	// The impersonated token cannot be turned into a lasting credential.
+	_, err = st.AuthClient.CreateAPIKey(impersonatedCtx, &ssov1.CreateAPIKeyRequest{Name: gofakeit.AppName()})
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	sessions, err := st.AuthClient.ListSessions(userCtx, &ssov1.ListSessionsRequest{})
+	require.NoError(t, err)
+	var impersonation *ssov1.Session
+	for _, session := range sessions.GetSessions() {
+		if session.GetImpersonatorId() != 0 {
+			impersonation = session
+		}
+	}
+	require.NotNil(t, impersonation)
+	assert.Equal(t, admin.GetUser().GetId(), impersonation.GetImpersonatorId())
+
+	_, err = st.AuthClient.RevokeSession(userCtx, &ssov1.RevokeSessionRequest{SessionId: impersonation.GetId()})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.GetUser(impersonatedCtx, &ssov1.GetUserRequest{})
+	require.Error(t, err)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func Test_Impersonate_FailCases(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	adminCtx := withBearer(ctx, adminToken(ctx, t, st))
+	admin, err := st.AuthClient.GetUser(adminCtx, &ssov1.GetUserRequest{})
+	require.NoError(t, err)
+
+	userToken, userID := signUpAndSignIn(ctx, t, st)
+
+	// Users cannot impersonate.
+	_, otherID := signUpAndSignIn(ctx, t, st)
+	_, err = st.AuthClient.Impersonate(withBearer(ctx, userToken), &ssov1.ImpersonateRequest{
+		UserId: otherID,
+		Reason: "curiosity",
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// Admins cannot be impersonated.
+	_, err = st.AuthClient.Impersonate(adminCtx, &ssov1.ImpersonateRequest{
+		UserId: admin.GetUser().GetId(),
+		Reason: "debugging",
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// Every impersonation needs a reason for the audit trail.
+	_, err = st.AuthClient.Impersonate(adminCtx, &ssov1.ImpersonateRequest{UserId: userID})
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// Impersonations cannot be chained.
+	resp, err := st.AuthClient.Impersonate(adminCtx, &ssov1.ImpersonateRequest{UserId: userID, Reason: "debugging"})
+	require.NoError(t, err)
+	_, err = st.AuthClient.Impersonate(withBearer(ctx, resp.GetToken()), &ssov1.ImpersonateRequest{
+		UserId: otherID,
+		Reason: "debugging",
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
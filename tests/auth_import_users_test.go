@@ -0,0 +1,136 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	ssov1 "github.com/DavidG9999/api/gen/go/sso"
+	"github.com/DavidG9999/my_grpc_app/tests/suite"
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	adminEmail    = "test-admin@example.com"
+	adminPassword = "test-admin-pass"
+
+	pbkdf2Hash     = "$pbkdf2-sha256$i=1000$MDEyMzQ1Njc4OWFiY2RlZg$vVa9NN3XuLI/EX9NpfGfhdAVv+DFp8/IaN7QWRP1UzM"
+	pbkdf2Password = "legacy-pbkdf2-pass"
+	scryptHash     = "$scrypt$ln=10,r=8,p=1$MDEyMzQ1Njc4OWFiY2RlZg$A58WUC+MzlR5lBLy3ZjU/y3jHDgnBQTpAgpkcX1oD9o"
+	scryptPassword = "legacy-scrypt-pass"
+)
+
+func Test_ImportUsers_HappyPath(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	pbkdf2Email := gofakeit.Email()
+	scryptEmail := gofakeit.Email()
+
+	resp, err := st.AuthClient.ImportUsers(withBearer(ctx, adminToken(ctx, t, st)), &ssov1.ImportUsersRequest{
+		Users: []*ssov1.ImportedUser{
+			{Name: gofakeit.Username(), Email: pbkdf2Email, PasswordHash: pbkdf2Hash},
+			{Name: gofakeit.Username(), Email: scryptEmail, PasswordHash: scryptHash},
+			{Name: gofakeit.Username(), Email: gofakeit.Email(), PasswordHash: "md5:" + gofakeit.LetterN(32)},
+			{Name: gofakeit.Username(), Email: adminEmail, PasswordHash: pbkdf2Hash},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), resp.GetImported())
+	require.Len(t, resp.GetFailures(), 2)
+	assert.Equal(t, int32(2), resp.GetFailures()[0].GetIndex())
+	assert.Equal(t, "unknown password hash format", resp.GetFailures()[0].GetReason())
+	assert.Equal(t, int32(3), resp.GetFailures()[1].GetIndex())
+	assert.Equal(t, "user already exists", resp.GetFailures()[1].GetReason())
+
+	// The second sign in verifies the hash the first one upgraded to.
+	for _, user := range []struct{ email, password string }{
+		{pbkdf2Email, pbkdf2Password},
+		{scryptEmail, scryptPassword},
+	} {
+		for range 2 {
+			_, err := st.AuthClient.SignIn(ctx, &ssov1.SignInRequest{
+				Email:    user.email,
+				Password: user.password,
+				AppId:    appID,
+			})
+			require.NoError(t, err)
+		}
+	}
+}
+
+func Test_ImportUsers_HashLimits(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	const saltAndKey = "$MDEyMzQ1Njc4OWFiY2RlZg$A58WUC+MzlR5lBLy3ZjU/y3jHDgnBQTpAgpkcX1oD9o"
+	hashes := []string{
+		//Argon2id without passes and lanes
+		"$argon2id$v=19$m=65536,t=0,p=0" + saltAndKey,
+		//Argon2id with 4 TiB of memory
+		"$argon2id$v=19$m=4294967295,t=1,p=1" + saltAndKey,
+		//PBKDF2 without iterations
+		"$pbkdf2-sha256$i=0" + saltAndKey,
+		//PBKDF2 with two billion iterations
+		"$pbkdf2-sha256$i=2000000000" + saltAndKey,
+		//Scrypt with 128 GiB of memory
+		"$scrypt$ln=30,r=8,p=1" + saltAndKey,
+		//Bcrypt with the highest cost
+		"$2a$31$b6TyOoqi/9pg/ilXDR4x2ORl9j/2mxeNOUX2Ie6vs9rrsaVL0VcEi",
+	}
+	users := make([]*ssov1.ImportedUser, 0, len(hashes))
+	for _, hash := range hashes {
+		users = append(users, &ssov1.ImportedUser{Name: gofakeit.Username(), Email: gofakeit.Email(), PasswordHash: hash})
+	}
+
+	resp, err := st.AuthClient.ImportUsers(withBearer(ctx, adminToken(ctx, t, st)), &ssov1.ImportUsersRequest{Users: users})
+	require.NoError(t, err)
+	assert.Zero(t, resp.GetImported())
+	require.Len(t, resp.GetFailures(), len(hashes))
+	for i, failure := range resp.GetFailures() {
+		assert.Equal(t, int32(i), failure.GetIndex())
+		assert.Equal(t, "password hash parameters are out of limits", failure.GetReason())
+	}
+}
+
+func Test_ImportUsers_Admins(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	resp, err := st.AuthClient.ImportUsers(withBearer(ctx, adminToken(ctx, t, st)), &ssov1.ImportUsersRequest{
+		Users: []*ssov1.ImportedUser{
+			{Name: gofakeit.Username(), Email: gofakeit.Email(), PasswordHash: pbkdf2Hash, IsAdmin: true},
+		},
+	})
+	require.NoError(t, err)
+	assert.Zero(t, resp.GetImported())
+	require.Len(t, resp.GetFailures(), 1)
+	assert.Equal(t, "admins are promoted through approved changes", resp.GetFailures()[0].GetReason())
+}
+
+func Test_ImportUsers_NotAdmin(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	token, _ := signUpAndSignIn(ctx, t, st)
+
+	_, err := st.AuthClient.ImportUsers(withBearer(ctx, token), &ssov1.ImportUsersRequest{
+		Users: []*ssov1.ImportedUser{
+			{Name: gofakeit.Username(), Email: gofakeit.Email(), PasswordHash: pbkdf2Hash},
+		},
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func adminToken(ctx context.Context, t *testing.T, st *suite.Suite) string {
+	t.Helper()
+
+	respSignIn, err := st.AuthClient.SignIn(ctx, &ssov1.SignInRequest{
+		Email:    adminEmail,
+		Password: adminPassword,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+
+	return respSignIn.GetToken()
+}
@@ -0,0 +1,124 @@
+package tests
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	ssov1 "github.com/DavidG9999/api/gen/go/sso"
+	"github.com/DavidG9999/my_grpc_app/tests/suite"
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func Test_ListUsers_HappyPath(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	prefix := fmt.Sprintf("list-%s-", gofakeit.LetterN(10))
+	var emails []string
+	for i := range 5 {
+		email := fmt.Sprintf("%s%d@example.com", prefix, i)
+		_, err := st.AuthClient.SignUp(ctx, &ssov1.SignUpRequest{
+			Name:     gofakeit.Username(),
+			Email:    email,
+			Password: randomFakePassword(),
+		})
+		require.NoError(t, err)
+		emails = append(emails, email)
+	}
+	ctx = withBearer(ctx, adminToken(ctx, t, st))
+
+	for _, descending := range []bool{false, true} {
+		var listed []string
+		var pageToken string
+		for pages := 0; ; pages++ {
+			require.Less(t, pages, 3)
+
+			resp, err := st.AuthClient.ListUsers(ctx, &ssov1.ListUsersRequest{
+				EmailPrefix: prefix,
+				SortBy:      "email",
+				Descending:  descending,
+				PageSize:    2,
+				PageToken:   pageToken,
+			})
+			require.NoError(t, err)
+			for _, user := range resp.GetUsers() {
+				assert.Equal(t, "active", user.GetStatus())
+				assert.NotZero(t, user.GetCreatedAt())
+				listed = append(listed, user.GetEmail())
+			}
+			pageToken = resp.GetNextPageToken()
+			if pageToken == "" {
+				break
+			}
+		}
+
+		want := append([]string(nil), emails...)
+		if descending {
+			sort.Sort(sort.Reverse(sort.StringSlice(want)))
+		}
+		assert.Equal(t, want, listed)
+	}
+
+	isAdmin := true
+	resp, err := st.AuthClient.ListUsers(ctx, &ssov1.ListUsersRequest{
+		EmailPrefix: prefix,
+		IsAdmin:     &isAdmin,
+	})
+	require.NoError(t, err)
+	assert.Empty(t, resp.GetUsers())
+}
+
+func Test_ListUsers_FailCases(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	token, _ := signUpAndSignIn(ctx, t, st)
+	_, err := st.AuthClient.ListUsers(withBearer(ctx, token), &ssov1.ListUsersRequest{})
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	adminCtx := withBearer(ctx, adminToken(ctx, t, st))
+
+	tests := []struct {
+		name        string
+		req         *ssov1.ListUsersRequest
+		expectedErr string
+	}{
+		//unknown sort field
+		{
+			name:        "List users with unknown sort field",
+			req:         &ssov1.ListUsersRequest{SortBy: "name"},
+			expectedErr: "sort_by must be created_at or email",
+		},
+		//negative page size
+		{
+			name:        "List users with negative page size",
+			req:         &ssov1.ListUsersRequest{PageSize: -1},
+			expectedErr: "page_size must not be negative",
+		},
+		//malformed page token
+		{
+			name:        "List users with malformed page token",
+			req:         &ssov1.ListUsersRequest{PageToken: "not-a-token"},
+			expectedErr: "invalid page_token",
+		},
+		//empty created range
+		{
+			name:        "List users with empty created range",
+			req:         &ssov1.ListUsersRequest{CreatedAfter: 2000, CreatedBefore: 1000},
+			expectedErr: "created_after must be before created_before",
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("Test_ListUsers_FailCases №%d", i), func(t *testing.T) {
+			_, err := st.AuthClient.ListUsers(adminCtx, tt.req)
+			require.Error(t, err)
+			assert.Equal(t, codes.InvalidArgument, status.Code(err))
+			assert.Contains(t, err.Error(), tt.expectedErr)
+		})
+	}
+}
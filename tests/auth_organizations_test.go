@@ -0,0 +1,251 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	ssov1 "github.com/DavidG9999/api/gen/go/sso"
+	"github.com/DavidG9999/my_grpc_app/tests/suite"
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	tenantOrgID     = 2
+	tenantAppID     = 1002
+	tenantAppSecret = "test-tenant-secret"
+)
+
+func Test_Organizations_EmailIsUniquePerTenant(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	email := gofakeit.Email()
+	password := randomFakePassword()
+
+	defaultUser, err := st.AuthClient.SignUp(ctx, &ssov1.SignUpRequest{
+		Name:     gofakeit.Username(),
+		Email:    email,
+		Password: password,
+	})
+	require.NoError(t, err)
+	tenantUser, err := st.AuthClient.SignUp(ctx, &ssov1.SignUpRequest{
+		Name:     gofakeit.Username(),
+		Email:    email,
+		Password: password,
+		AppId:    tenantAppID,
+	})
+	require.NoError(t, err)
+	assert.NotEqual(t, defaultUser.GetUserId(), tenantUser.GetUserId())
+
+	_, err = st.AuthClient.SignUp(ctx, &ssov1.SignUpRequest{
+		Name:     gofakeit.Username(),
+		Email:    email,
+		Password: password,
+		AppId:    tenantAppID,
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	respSignIn, err := st.AuthClient.SignIn(ctx, &ssov1.SignInRequest{
+		Email:    email,
+		Password: password,
+		AppId:    tenantAppID,
+	})
+	require.NoError(t, err)
+
+	tokenParsed, err := jwt.Parse(respSignIn.GetToken(), func(token *jwt.Token) (interface{}, error) {
+		return []byte(tenantAppSecret), nil
+	})
+	require.NoError(t, err)
+	claims, ok := tokenParsed.Claims.(jwt.MapClaims)
+	require.True(t, ok)
+	assert.Equal(t, tenantUser.GetUserId(), int64(claims["uid"].(float64)))
+	assert.Equal(t, tenantOrgID, int(claims["org_id"].(float64)))
+}
+
+func Test_Organizations_OrgAdmin(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	email := gofakeit.Email()
+	password := randomFakePassword()
+	orgAdmin, err := st.AuthClient.SignUp(ctx, &ssov1.SignUpRequest{
+		Name:     gofakeit.Username(),
+		Email:    email,
+		Password: password,
+		AppId:    tenantAppID,
+	})
+	require.NoError(t, err)
+
+	promoteOrgAdmin(ctx, t, st, orgAdmin.GetUserId())
+
+	respSignIn, err := st.AuthClient.SignIn(ctx, &ssov1.SignInRequest{
+		Email:    email,
+		Password: password,
+		AppId:    tenantAppID,
+	})
+	require.NoError(t, err)
+	orgAdminCtx := withBearer(ctx, respSignIn.GetToken())
+
+	resp, err := st.AuthClient.ListUsers(orgAdminCtx, &ssov1.ListUsersRequest{PageSize: 500})
+	require.NoError(t, err)
+	require.NotEmpty(t, resp.GetUsers())
+	for _, user := range resp.GetUsers() {
+		assert.Equal(t, int64(tenantOrgID), user.GetOrgId())
+	}
+
+	_, otherUserID := signUpAndSignIn(ctx, t, st)
+	_, err = st.AuthClient.GetUser(orgAdminCtx, &ssov1.GetUserRequest{UserId: otherUserID})
+	require.Error(t, err)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = st.AuthClient.ListUsers(orgAdminCtx, &ssov1.ListUsersRequest{OrgId: 1})
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = st.AuthClient.CreateOrganization(orgAdminCtx, &ssov1.CreateOrganizationRequest{Name: gofakeit.Company()})
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func Test_Organizations_OrgAdminCannotManageAdmins(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	orgAdminToken, _ := tenantOrgAdminToken(ctx, t, st)
+	orgAdminCtx := withBearer(ctx, orgAdminToken)
+
+	newTenantUser := func() int64 {
+		respSignUp, err := st.AuthClient.SignUp(ctx, &ssov1.SignUpRequest{
+			Name:     gofakeit.Username(),
+			Email:    gofakeit.Email(),
+			Password: randomFakePassword(),
+			AppId:    tenantAppID,
+		})
+		require.NoError(t, err)
+		return respSignUp.GetUserId()
+	}
+	userID := newTenantUser()
+	adminID := newTenantUser()
+	promoteAdmin(ctx, t, st, adminID)
+
+	_, err := st.AuthClient.ChangePassword(orgAdminCtx, &ssov1.ChangePasswordRequest{
+		UserId:      userID,
+		NewPassword: randomFakePassword(),
+	})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.ChangePassword(orgAdminCtx, &ssov1.ChangePasswordRequest{
+		UserId:      adminID,
+		NewPassword: randomFakePassword(),
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = st.AuthClient.UpdateUser(orgAdminCtx, &ssov1.UpdateUserRequest{
+		UserId: adminID,
+		Email:  gofakeit.Email(),
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func Test_Organizations_SetOrgAdmin(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	adminCtx := withBearer(ctx, adminToken(ctx, t, st))
+
+	// Grants need a second admin's approval.
+	respSignUp, err := st.AuthClient.SignUp(ctx, &ssov1.SignUpRequest{
+		Name:     gofakeit.Username(),
+		Email:    gofakeit.Email(),
+		Password: randomFakePassword(),
+		AppId:    tenantAppID,
+	})
+	require.NoError(t, err)
+	_, err = st.AuthClient.SetOrgAdmin(adminCtx, &ssov1.SetOrgAdminRequest{
+		UserId:     respSignUp.GetUserId(),
+		IsOrgAdmin: true,
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	// Global admins are not made org admins.
+	respSignUp, err = st.AuthClient.SignUp(ctx, &ssov1.SignUpRequest{
+		Name:     gofakeit.Username(),
+		Email:    gofakeit.Email(),
+		Password: randomFakePassword(),
+		AppId:    tenantAppID,
+	})
+	require.NoError(t, err)
+	promoteAdmin(ctx, t, st, respSignUp.GetUserId())
+	_, err = st.AuthClient.ProposeChange(adminCtx, &ssov1.ProposeChangeRequest{
+		Kind:   "promote_org_admin",
+		UserId: respSignUp.GetUserId(),
+		Reason: "test " + gofakeit.DigitN(6),
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	// Revocations apply at once and are audited.
+	orgAdminToken, orgAdminID := tenantOrgAdminToken(ctx, t, st)
+	orgAdminCtx := withBearer(ctx, orgAdminToken)
+	_, err = st.AuthClient.ListUsers(orgAdminCtx, &ssov1.ListUsersRequest{})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.SetOrgAdmin(adminCtx, &ssov1.SetOrgAdminRequest{UserId: orgAdminID})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.ListUsers(orgAdminCtx, &ssov1.ListUsersRequest{})
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	var audited int
+	err = st.Storage().QueryRowContext(ctx, "SELECT COUNT(*) FROM audit_events WHERE action=? AND target_user_id=?",
+		"revoke_org_admin", orgAdminID).Scan(&audited)
+	require.NoError(t, err)
+	assert.Equal(t, 1, audited)
+}
+
+func Test_Organizations_Create(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	adminCtx := withBearer(ctx, adminToken(ctx, t, st))
+	name := "org-" + gofakeit.LetterN(12)
+
+	resp, err := st.AuthClient.CreateOrganization(adminCtx, &ssov1.CreateOrganizationRequest{Name: name})
+	require.NoError(t, err)
+	assert.NotZero(t, resp.GetOrgId())
+
+	_, err = st.AuthClient.CreateOrganization(adminCtx, &ssov1.CreateOrganizationRequest{Name: name})
+	require.Error(t, err)
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+}
+
+// tenantOrgAdminToken signs up an org admin of the tenant organization and
+// returns their token and ID.
+func tenantOrgAdminToken(ctx context.Context, t *testing.T, st *suite.Suite) (string, int64) {
+	t.Helper()
+
+	email := gofakeit.Email()
+	password := randomFakePassword()
+	respSignUp, err := st.AuthClient.SignUp(ctx, &ssov1.SignUpRequest{
+		Name:     gofakeit.Username(),
+		Email:    email,
+		Password: password,
+		AppId:    tenantAppID,
+	})
+	require.NoError(t, err)
+
+	promoteOrgAdmin(ctx, t, st, respSignUp.GetUserId())
+
+	respSignIn, err := st.AuthClient.SignIn(ctx, &ssov1.SignInRequest{
+		Email:    email,
+		Password: password,
+		AppId:    tenantAppID,
+	})
+	require.NoError(t, err)
+	return respSignIn.GetToken(), respSignUp.GetUserId()
+}
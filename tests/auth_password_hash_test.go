@@ -0,0 +1,49 @@
+package tests
+
+import (
+	"testing"
+
+	ssov1 "github.com/DavidG9999/api/gen/go/sso"
+	"github.com/DavidG9999/my_grpc_app/tests/suite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	legacyBcryptEmail    = "legacy-bcrypt@example.com"
+	legacyBcryptPassword = "legacy-bcrypt-pass"
+	legacyBcryptHash     = "$2a$10$b6TyOoqi/9pg/ilXDR4x2ORl9j/2mxeNOUX2Ie6vs9rrsaVL0VcEi"
+)
+
+func Test_SignIn_RehashesLegacyHash(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	db := st.Storage()
+	// An earlier run upgraded the seeded hash already.
+	_, err := db.ExecContext(ctx, "UPDATE users SET password_hash=? WHERE email=?", legacyBcryptHash, legacyBcryptEmail)
+	require.NoError(t, err)
+
+	// The first sign in verifies the seeded bcrypt hash and replaces it,
+	// the second one verifies the new hash.
+	for range 2 {
+		respSignIn, err := st.AuthClient.SignIn(ctx, &ssov1.SignInRequest{
+			Email:    legacyBcryptEmail,
+			Password: legacyBcryptPassword,
+			AppId:    appID,
+		})
+		require.NoError(t, err)
+		require.NotEmpty(t, respSignIn.GetToken())
+	}
+
+	var hash string
+	err = db.QueryRowContext(ctx, "SELECT password_hash FROM users WHERE email=?", legacyBcryptEmail).Scan(&hash)
+	require.NoError(t, err)
+	assert.Regexp(t, `^\$argon2id\$`, hash)
+
+	_, err = st.AuthClient.SignIn(ctx, &ssov1.SignInRequest{
+		Email:    legacyBcryptEmail,
+		Password: legacyBcryptPassword + "x",
+		AppId:    appID,
+	})
+	require.Error(t, err)
+}
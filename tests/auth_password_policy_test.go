@@ -0,0 +1,157 @@
+package tests
+
+import (
+	"fmt"
+	"testing"
+
+	ssov1 "github.com/DavidG9999/api/gen/go/sso"
+	"github.com/DavidG9999/my_grpc_app/tests/suite"
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func Test_SignUp_PasswordPolicy(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	tests := []struct {
+		name               string
+		email              string
+		password           string
+		appID              int32
+		expectedViolations int
+	}{
+		{
+			//Default policy accepts a long enough password
+			name:     gofakeit.Username(),
+			email:    gofakeit.Email(),
+			password: "correcthorse",
+		},
+		{
+			//Default policy rejects a short password
+			name:               gofakeit.Username(),
+			email:              gofakeit.Email(),
+			password:           "short",
+			expectedViolations: 1,
+		},
+		{
+			//Default policy rejects a password containing the email
+			name:               gofakeit.Username(),
+			email:              "john.smith@example.com",
+			password:           "john.smith1999",
+			expectedViolations: 1,
+		},
+		{
+			//App policy reports all violations at once
+			name:               gofakeit.Username(),
+			email:              gofakeit.Email(),
+			password:           "aaaaaaaa",
+			appID:              targetAppID,
+			expectedViolations: 6,
+		},
+		{
+			//App policy rejects a guessable password that follows the rules
+			name:               gofakeit.Username(),
+			email:              gofakeit.Email(),
+			password:           "Qwerty123456!",
+			appID:              targetAppID,
+			expectedViolations: 1,
+		},
+		{
+			//App policy accepts a strong password
+			name:     gofakeit.Username(),
+			email:    gofakeit.Email(),
+			password: "Tr0ub4dor&3-Horse",
+			appID:    targetAppID,
+		},
+	}
+	i := 1
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("Test_SignUp_PasswordPolicy №%d", i), func(t *testing.T) {
+			respSignUp, err := st.AuthClient.SignUp(ctx, &ssov1.SignUpRequest{
+				Name:     test.name,
+				Email:    test.email,
+				Password: test.password,
+				AppId:    test.appID,
+			})
+			if test.expectedViolations == 0 {
+				require.NoError(t, err)
+				assert.NotEmpty(t, respSignUp.GetUserId())
+				i++
+				return
+			}
+			require.Error(t, err)
+
+			grpcStatus, ok := status.FromError(err)
+			require.True(t, ok)
+			assert.Equal(t, codes.InvalidArgument, grpcStatus.Code())
+
+			var violations []*errdetails.BadRequest_FieldViolation
+			for _, detail := range grpcStatus.Details() {
+				if badRequest, ok := detail.(*errdetails.BadRequest); ok {
+					violations = append(violations, badRequest.GetFieldViolations()...)
+				}
+			}
+			require.Len(t, violations, test.expectedViolations)
+			for _, v := range violations {
+				assert.Equal(t, "password", v.GetField())
+			}
+			i++
+		})
+	}
+}
+
+func Test_SignUp_BreachedPassword(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	_, err := st.AuthClient.SignUp(ctx, &ssov1.SignUpRequest{
+		Name:     gofakeit.Username(),
+		Email:    gofakeit.Email(),
+		Password: "password123",
+	})
+	require.Error(t, err)
+
+	grpcStatus, ok := status.FromError(err)
+	require.True(t, ok)
+	assert.Equal(t, codes.InvalidArgument, grpcStatus.Code())
+
+	var descriptions []string
+	for _, detail := range grpcStatus.Details() {
+		if badRequest, ok := detail.(*errdetails.BadRequest); ok {
+			for _, v := range badRequest.GetFieldViolations() {
+				descriptions = append(descriptions, v.GetDescription())
+			}
+		}
+	}
+	assert.Contains(t, descriptions, "has appeared in a data breach")
+}
+
+func Test_SignUp_WeakPasswordFeedback(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	_, err := st.AuthClient.SignUp(ctx, &ssov1.SignUpRequest{
+		Name:     gofakeit.Username(),
+		Email:    gofakeit.Email(),
+		Password: "Qwerty123456!",
+		AppId:    targetAppID,
+	})
+	require.Error(t, err)
+
+	grpcStatus, ok := status.FromError(err)
+	require.True(t, ok)
+
+	var errorInfo *errdetails.ErrorInfo
+	for _, detail := range grpcStatus.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok {
+			errorInfo = info
+		}
+	}
+	require.NotNil(t, errorInfo)
+	assert.Equal(t, "PASSWORD_TOO_WEAK", errorInfo.GetReason())
+	assert.Equal(t, "2", errorInfo.GetMetadata()["score"])
+	assert.NotEmpty(t, errorInfo.GetMetadata()["warning"])
+	assert.NotEmpty(t, errorInfo.GetMetadata()["suggestions"])
+}
@@ -0,0 +1,102 @@
+package tests
+
+import (
+	"fmt"
+	"testing"
+
+	ssov1 "github.com/DavidG9999/api/gen/go/sso"
+	"github.com/DavidG9999/my_grpc_app/tests/suite"
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func Test_SearchUsers_HappyPath(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	surname := gofakeit.LetterN(12)
+	email := gofakeit.Email()
+	respSignUp, err := st.AuthClient.SignUp(ctx, &ssov1.SignUpRequest{
+		Name:     "Support " + surname,
+		Email:    email,
+		Password: randomFakePassword(),
+	})
+	require.NoError(t, err)
+	ctx = withBearer(ctx, adminToken(ctx, t, st))
+
+	resp, err := st.AuthClient.SearchUsers(ctx, &ssov1.SearchUsersRequest{
+		Query: "support " + surname[2:9],
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.GetResults(), 1)
+	result := resp.GetResults()[0]
+	assert.Equal(t, respSignUp.GetUserId(), result.GetUser().GetId())
+	assert.Equal(t, email, result.GetUser().GetEmail())
+	assert.Contains(t, result.GetNameHighlight(), "<mark>"+surname[2:9]+"</mark>")
+
+	// Too short for the full-text index.
+	resp, err = st.AuthClient.SearchUsers(ctx, &ssov1.SearchUsersRequest{
+		Query: surname[:2] + " " + surname[5:],
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, resp.GetResults())
+	assert.Equal(t, respSignUp.GetUserId(), resp.GetResults()[0].GetUser().GetId())
+}
+
+func Test_SearchUsers_EscapesHighlights(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	surname := gofakeit.LetterN(12)
+	_, err := st.AuthClient.SignUp(ctx, &ssov1.SignUpRequest{
+		Name:     `<script>alert("xss")</script>` + surname,
+		Email:    gofakeit.Email(),
+		Password: randomFakePassword(),
+	})
+	require.NoError(t, err)
+	ctx = withBearer(ctx, adminToken(ctx, t, st))
+
+	surnameHighlight := surname[:2] + "<mark>" + surname[2:9] + "</mark>" + surname[9:]
+	tests := []struct {
+		query     string
+		highlight string
+	}{
+		{
+			//Full-text index
+			query:     surname[2:9],
+			highlight: "&lt;script&gt;alert(&#34;xss&#34;)&lt;/script&gt;" + surnameHighlight,
+		},
+		{
+			//Too short for the full-text index
+			query:     surname[2:9] + " <s",
+			highlight: "<mark>&lt;s</mark>cript&gt;alert(&#34;xss&#34;)&lt;/script&gt;" + surnameHighlight,
+		},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("Test_SearchUsers_EscapesHighlights №%d", i), func(t *testing.T) {
+			resp, err := st.AuthClient.SearchUsers(ctx, &ssov1.SearchUsersRequest{Query: test.query})
+			require.NoError(t, err)
+			require.Len(t, resp.GetResults(), 1)
+			assert.Equal(t, test.highlight, resp.GetResults()[0].GetNameHighlight())
+		})
+	}
+}
+
+func Test_SearchUsers_FailCases(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	token, _ := signUpAndSignIn(ctx, t, st)
+	_, err := st.AuthClient.SearchUsers(withBearer(ctx, token), &ssov1.SearchUsersRequest{
+		Query: gofakeit.LetterN(5),
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = st.AuthClient.SearchUsers(withBearer(ctx, adminToken(ctx, t, st)), &ssov1.SearchUsersRequest{
+		Query: "  ",
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Contains(t, err.Error(), "query is required")
+}
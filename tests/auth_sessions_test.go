@@ -0,0 +1,106 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	ssov1 "github.com/DavidG9999/api/gen/go/sso"
+	"github.com/DavidG9999/my_grpc_app/tests/suite"
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func Test_Sessions_ListAndRevoke(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	email := gofakeit.Email()
+	password := randomFakePassword()
+
+	respSignUp, err := st.AuthClient.SignUp(ctx, &ssov1.SignUpRequest{
+		Name:     gofakeit.Username(),
+		Email:    email,
+		Password: password,
+	})
+	require.NoError(t, err)
+
+	signIn := func(device string) string {
+		respSignIn, err := st.AuthClient.SignIn(metadata.AppendToOutgoingContext(ctx, "x-device-name", device), &ssov1.SignInRequest{
+			Email:    email,
+			Password: password,
+			AppId:    appID,
+		})
+		require.NoError(t, err)
+		return respSignIn.GetToken()
+	}
+	laptopToken := signIn("laptop")
+	phoneToken := signIn("phone")
+
+	laptopCtx := withBearer(ctx, laptopToken)
+
+	respList, err := st.AuthClient.ListSessions(laptopCtx, &ssov1.ListSessionsRequest{})
+	require.NoError(t, err)
+	require.Len(t, respList.GetSessions(), 2)
+
+	var phoneSessionID int64
+	for _, session := range respList.GetSessions() {
+		assert.Equal(t, int32(appID), session.GetAppId())
+		assert.Equal(t, session.GetDevice() == "laptop", session.GetCurrent())
+		if session.GetDevice() == "phone" {
+			phoneSessionID = session.GetId()
+		}
+	}
+	require.NotZero(t, phoneSessionID)
+
+	respValidate, err := st.AuthClient.ValidateToken(ctx, &ssov1.ValidateTokenRequest{Token: phoneToken})
+	require.NoError(t, err)
+	assert.Equal(t, respSignUp.GetUserId(), respValidate.GetUserId())
+	assert.Equal(t, phoneSessionID, respValidate.GetSessionId())
+
+	_, err = st.AuthClient.RevokeSession(laptopCtx, &ssov1.RevokeSessionRequest{SessionId: phoneSessionID})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.ValidateToken(ctx, &ssov1.ValidateTokenRequest{Token: phoneToken})
+	require.Error(t, err)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = st.AuthClient.ListSessions(withBearer(ctx, phoneToken), &ssov1.ListSessionsRequest{})
+	require.Error(t, err)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	respRevokeAll, err := st.AuthClient.RevokeAllSessions(laptopCtx, &ssov1.RevokeAllSessionsRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), respRevokeAll.GetRevoked())
+
+	_, err = st.AuthClient.ValidateToken(ctx, &ssov1.ValidateTokenRequest{Token: laptopToken})
+	require.Error(t, err)
+}
+
+func Test_Sessions_OtherUser(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	token, _ := signUpAndSignIn(ctx, t, st)
+	otherToken, otherUserID := signUpAndSignIn(ctx, t, st)
+
+	respValidate, err := st.AuthClient.ValidateToken(ctx, &ssov1.ValidateTokenRequest{Token: otherToken})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.ListSessions(withBearer(ctx, token), &ssov1.ListSessionsRequest{UserId: otherUserID})
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = st.AuthClient.RevokeSession(withBearer(ctx, token), &ssov1.RevokeSessionRequest{SessionId: respValidate.GetSessionId()})
+	require.Error(t, err)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = st.AuthClient.ListSessions(ctx, &ssov1.ListSessionsRequest{})
+	require.Error(t, err)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func withBearer(ctx context.Context, token string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+}
@@ -0,0 +1,102 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	ssov1 "github.com/DavidG9999/api/gen/go/sso"
+	"github.com/DavidG9999/my_grpc_app/tests/suite"
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func Test_SetAdmin_HappyPath(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	adminCtx := withBearer(ctx, adminToken(ctx, t, st))
+	userToken, userID := signUpAndSignIn(ctx, t, st)
+	userCtx := withBearer(ctx, userToken)
+
+	promoteAdmin(ctx, t, st, userID)
+
+	respIsAdmin, err := st.AuthClient.IsAdmin(userCtx, &ssov1.IsAdminRequest{})
+	require.NoError(t, err)
+	assert.True(t, respIsAdmin.GetIsAdmin())
+
+	// The token issued before the promotion already works for admin RPCs.
+	_, err = st.AuthClient.CreateOrganization(userCtx, &ssov1.CreateOrganizationRequest{Name: gofakeit.Company() + gofakeit.DigitN(6)})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.SetAdmin(adminCtx, &ssov1.SetAdminRequest{UserId: userID, IsAdmin: false})
+	require.NoError(t, err)
+
+	respIsAdmin, err = st.AuthClient.IsAdmin(userCtx, &ssov1.IsAdminRequest{})
+	require.NoError(t, err)
+	assert.False(t, respIsAdmin.GetIsAdmin())
+
+	_, err = st.AuthClient.CreateOrganization(userCtx, &ssov1.CreateOrganizationRequest{Name: gofakeit.Company() + gofakeit.DigitN(6)})
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func Test_SetAdmin_FailCases(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	adminCtx := withBearer(ctx, adminToken(ctx, t, st))
+	userToken, userID := signUpAndSignIn(ctx, t, st)
+
+	tests := []struct {
+		ctx          context.Context
+		userID       int64
+		expectedCode codes.Code
+	}{
+		{
+			//Empty user id
+			ctx:          adminCtx,
+			userID:       0,
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			//Unknown user
+			ctx:          adminCtx,
+			userID:       1 << 40,
+			expectedCode: codes.NotFound,
+		},
+		{
+			//Caller who is not an admin
+			ctx:          withBearer(ctx, userToken),
+			userID:       userID,
+			expectedCode: codes.PermissionDenied,
+		},
+		{
+			//Anonymous caller
+			ctx:          ctx,
+			userID:       userID,
+			expectedCode: codes.Unauthenticated,
+		},
+		{
+			//Promotion without approval
+			ctx:          adminCtx,
+			userID:       userID,
+			expectedCode: codes.FailedPrecondition,
+		},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("Test_SetAdmin_FailCases №%d", i), func(t *testing.T) {
+			_, err := st.AuthClient.SetAdmin(test.ctx, &ssov1.SetAdminRequest{
+				UserId:  test.userID,
+				IsAdmin: true,
+			})
+			require.Error(t, err)
+			assert.Equal(t, test.expectedCode, status.Code(err))
+		})
+	}
+
+	respIsAdmin, err := st.AuthClient.IsAdmin(adminCtx, &ssov1.IsAdminRequest{UserId: userID})
+	require.NoError(t, err)
+	assert.False(t, respIsAdmin.GetIsAdmin())
+}
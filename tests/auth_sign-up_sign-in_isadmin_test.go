@@ -52,6 +52,8 @@ func Test_SignUp_SignIn_IsAdmin_HappyPath(t *testing.T) {
 			})
 			require.NoError(t, err)
 			assert.NotEmpty(t, respSignUp.GetUserId())
+			// Public sign-ups never create admins.
+			assert.False(t, respSignUp.GetIsAdmin())
 
 			respSignIn, err := st.AuthClient.SignIn(ctx, &ssov1.SignInRequest{
 				Email:    test.email,
@@ -82,9 +84,9 @@ func Test_SignUp_SignIn_IsAdmin_HappyPath(t *testing.T) {
 			assert.InDelta(t, loginTime.Add(st.Cfg.TokenTTL).Unix(), claims["exp"].(float64), deltaSeconds)
 
 			userID := respSignUp.GetUserId()
-			respIsAdmin, err := st.AuthClient.IsAdmin(ctx, &ssov1.IsAdminRequest{UserId: userID})
+			respIsAdmin, err := st.AuthClient.IsAdmin(withBearer(ctx, token), &ssov1.IsAdminRequest{UserId: userID})
 			require.NoError(t, err)
-			assert.Equal(t, test.isAdmin, respIsAdmin.GetIsAdmin())
+			assert.False(t, respIsAdmin.GetIsAdmin())
 			i++
 		})
 	}
@@ -267,24 +269,44 @@ func Test_IsAdmin_FailCases(t *testing.T) {
 	require.NoError(t, err)
 	assert.NotEmpty(t, respSignUp.GetUserId())
 
+	userToken, _ := signUpAndSignIn(ctx, t, st)
+
 	tests := []struct {
+		token       string
 		userId      int64
 		expectedErr string
 	}{
 		{
 			//Check if is admin with empty user id
+			token:       adminToken(ctx, t, st),
 			expectedErr: "user id is required",
 		},
 		{
 			//Check if is admin with empty user id
+			token:       adminToken(ctx, t, st),
 			userId:      gofakeit.Int64(),
 			expectedErr: "user not found",
 		},
+		{
+			//Check if is admin without token
+			userId:      respSignUp.GetUserId(),
+			expectedErr: "bearer token is required",
+		},
+		{
+			//Check if another user is admin
+			token:       userToken,
+			userId:      respSignUp.GetUserId(),
+			expectedErr: "not allowed to check user",
+		},
 	}
 	i := 1
 	for _, test := range tests {
 		t.Run(fmt.Sprintf("Test_IsAdmin_FailCases №%d", i), func(t *testing.T) {
-			respIsAdmin, err := st.AuthClient.IsAdmin(ctx, &ssov1.IsAdminRequest{
+			callCtx := ctx
+			if test.token != "" {
+				callCtx = withBearer(ctx, test.token)
+			}
+			respIsAdmin, err := st.AuthClient.IsAdmin(callCtx, &ssov1.IsAdminRequest{
 				UserId: test.userId,
 			})
 			require.Error(t, err)
@@ -0,0 +1,227 @@
+package tests
+
+import (
+	"testing"
+
+	ssov1 "github.com/DavidG9999/api/gen/go/sso"
+	"github.com/DavidG9999/my_grpc_app/tests/suite"
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func Test_GetUser(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	token, userID := signUpAndSignIn(ctx, t, st)
+	_, otherUserID := signUpAndSignIn(ctx, t, st)
+
+	respGet, err := st.AuthClient.GetUser(withBearer(ctx, token), &ssov1.GetUserRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, userID, respGet.GetUser().GetId())
+	assert.False(t, respGet.GetUser().GetEmailVerified())
+
+	_, err = st.AuthClient.GetUser(withBearer(ctx, token), &ssov1.GetUserRequest{UserId: otherUserID})
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = st.AuthClient.GetUser(withBearer(ctx, token), &ssov1.GetUserRequest{Email: gofakeit.Email()})
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	respAdminGet, err := st.AuthClient.GetUser(withBearer(ctx, adminToken(ctx, t, st)), &ssov1.GetUserRequest{
+		Email: respGet.GetUser().GetEmail(),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, userID, respAdminGet.GetUser().GetId())
+}
+
+func Test_UpdateUser(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	token, userID := signUpAndSignIn(ctx, t, st)
+
+	name := gofakeit.Username()
+	email := gofakeit.Email()
+
+	respUpdate, err := st.AuthClient.UpdateUser(withBearer(ctx, token), &ssov1.UpdateUserRequest{
+		Name:  name,
+		Email: email,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, userID, respUpdate.GetUser().GetId())
+	assert.Equal(t, name, respUpdate.GetUser().GetName())
+	assert.Equal(t, email, respUpdate.GetUser().GetEmail())
+	assert.False(t, respUpdate.GetUser().GetEmailVerified())
+
+	_, err = st.AuthClient.UpdateUser(withBearer(ctx, token), &ssov1.UpdateUserRequest{Email: adminEmail})
+	require.Error(t, err)
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	for _, invalid := range []string{
+		gofakeit.Email() + "\r\nBcc: " + gofakeit.Email(),
+		"Name <" + gofakeit.Email() + ">",
+		gofakeit.Username(),
+	} {
+		_, err = st.AuthClient.UpdateUser(withBearer(ctx, token), &ssov1.UpdateUserRequest{Email: invalid})
+		require.Error(t, err)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	}
+
+	_, otherUserID := signUpAndSignIn(ctx, t, st)
+	_, err = st.AuthClient.UpdateUser(withBearer(ctx, token), &ssov1.UpdateUserRequest{UserId: otherUserID, Name: name})
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func Test_ChangePassword(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	email := gofakeit.Email()
+	password := randomFakePassword()
+
+	_, err := st.AuthClient.SignUp(ctx, &ssov1.SignUpRequest{
+		Name:     gofakeit.Username(),
+		Email:    email,
+		Password: password,
+	})
+	require.NoError(t, err)
+
+	signIn := func(password string) (string, error) {
+		respSignIn, err := st.AuthClient.SignIn(ctx, &ssov1.SignInRequest{
+			Email:    email,
+			Password: password,
+			AppId:    appID,
+		})
+		return respSignIn.GetToken(), err
+	}
+	token, err := signIn(password)
+	require.NoError(t, err)
+	otherToken, err := signIn(password)
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.ChangePassword(withBearer(ctx, token), &ssov1.ChangePasswordRequest{
+		CurrentPassword: password + "x",
+		NewPassword:     strongFakePassword(),
+	})
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "invalid current password")
+
+	_, err = st.AuthClient.ChangePassword(withBearer(ctx, token), &ssov1.ChangePasswordRequest{
+		CurrentPassword: password,
+		NewPassword:     "short",
+	})
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "password does not satisfy policy")
+
+	// The password signs the user in to every app of the organization, the
+	// strictest policy among them applies whichever app the token is for.
+	_, err = st.AuthClient.ChangePassword(withBearer(ctx, token), &ssov1.ChangePasswordRequest{
+		CurrentPassword: password,
+		NewPassword:     "correcthorse",
+	})
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "password does not satisfy policy")
+
+	newPassword := strongFakePassword()
+	_, err = st.AuthClient.ChangePassword(withBearer(ctx, token), &ssov1.ChangePasswordRequest{
+		CurrentPassword: password,
+		NewPassword:     newPassword,
+	})
+	require.NoError(t, err)
+
+	_, err = signIn(password)
+	require.Error(t, err)
+	_, err = signIn(newPassword)
+	require.NoError(t, err)
+
+	// The session the password was changed in survives, the others do not.
+	_, err = st.AuthClient.ValidateToken(ctx, &ssov1.ValidateTokenRequest{Token: token})
+	require.NoError(t, err)
+	_, err = st.AuthClient.ValidateToken(ctx, &ssov1.ValidateTokenRequest{Token: otherToken})
+	require.Error(t, err)
+}
+
+func Test_ChangePassword_GlobalAdmin(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	adminCtx := withBearer(ctx, adminToken(ctx, t, st))
+
+	// Admins reset the passwords of users without knowing them.
+	// The policy is the user's organization's, not that of the admin's token.
+	_, userID := signUpAndSignIn(ctx, t, st)
+	_, err := st.AuthClient.ChangePassword(adminCtx, &ssov1.ChangePasswordRequest{
+		UserId:      userID,
+		NewPassword: "correcthorse",
+	})
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "password does not satisfy policy")
+
+	_, err = st.AuthClient.ChangePassword(adminCtx, &ssov1.ChangePasswordRequest{
+		UserId:      userID,
+		NewPassword: strongFakePassword(),
+	})
+	require.NoError(t, err)
+
+	// Not those of global admins.
+	email := gofakeit.Email()
+	password := randomFakePassword()
+	respSignUp, err := st.AuthClient.SignUp(ctx, &ssov1.SignUpRequest{
+		Name:     gofakeit.Username(),
+		Email:    email,
+		Password: password,
+	})
+	require.NoError(t, err)
+	promoteAdmin(ctx, t, st, respSignUp.GetUserId())
+
+	for _, currentPassword := range []string{"", password + "x"} {
+		_, err = st.AuthClient.ChangePassword(adminCtx, &ssov1.ChangePasswordRequest{
+			UserId:          respSignUp.GetUserId(),
+			CurrentPassword: currentPassword,
+			NewPassword:     strongFakePassword(),
+		})
+		require.Error(t, err)
+		assert.ErrorContains(t, err, "invalid current password")
+	}
+
+	newPassword := strongFakePassword()
+	_, err = st.AuthClient.ChangePassword(adminCtx, &ssov1.ChangePasswordRequest{
+		UserId:          respSignUp.GetUserId(),
+		CurrentPassword: password,
+		NewPassword:     newPassword,
+	})
+	require.NoError(t, err)
+
+	_, err = st.AuthClient.SignIn(ctx, &ssov1.SignInRequest{
+		Email:    email,
+		Password: newPassword,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+}
+
+// strongFakePassword returns a password that satisfies the strict policy of
+// the target app, which applies to all users of the default organization.
+func strongFakePassword() string {
+	for {
+		password := gofakeit.Password(true, true, true, false, false, 16) + "-Aa1"
+		if maxRepeated(password) <= 2 {
+			return password
+		}
+	}
+}
+
+func maxRepeated(s string) int {
+	longest, run := 0, 0
+	for i := range s {
+		if i > 0 && s[i] == s[i-1] {
+			run++
+		} else {
+			run = 1
+		}
+		longest = max(longest, run)
+	}
+	return longest
+}
@@ -0,0 +1,180 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	ssov1 "github.com/DavidG9999/api/gen/go/sso"
+	"github.com/DavidG9999/my_grpc_app/tests/suite"
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func Test_SetUserStatus_SignIn(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	adminCtx := withBearer(ctx, adminToken(ctx, t, st))
+
+	tests := []struct {
+		status         string
+		suspendedUntil int64
+		expectedCode   codes.Code
+	}{
+		{
+			//Disabled user
+			status:       "disabled",
+			expectedCode: codes.PermissionDenied,
+		},
+		{
+			//Suspended user
+			status:         "suspended",
+			suspendedUntil: time.Now().Add(time.Hour).Unix(),
+			expectedCode:   codes.FailedPrecondition,
+		},
+		{
+			//Soft-deleted user
+			status:       "deleted",
+			expectedCode: codes.NotFound,
+		},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("Test_SetUserStatus_SignIn №%d", i), func(t *testing.T) {
+			email := gofakeit.Email()
+			password := randomFakePassword()
+
+			respSignUp, err := st.AuthClient.SignUp(ctx, &ssov1.SignUpRequest{
+				Name:     gofakeit.Username(),
+				Email:    email,
+				Password: password,
+			})
+			require.NoError(t, err)
+			userID := respSignUp.GetUserId()
+
+			respSignIn, err := st.AuthClient.SignIn(ctx, &ssov1.SignInRequest{Email: email, Password: password, AppId: appID})
+			require.NoError(t, err)
+			userCtx := withBearer(ctx, respSignIn.GetToken())
+
+			_, err = st.AuthClient.SetUserStatus(adminCtx, &ssov1.SetUserStatusRequest{
+				UserId:         userID,
+				Status:         test.status,
+				Reason:         "ticket " + gofakeit.DigitN(6),
+				SuspendedUntil: test.suspendedUntil,
+			})
+			require.NoError(t, err)
+
+			respUser, err := st.AuthClient.GetUser(adminCtx, &ssov1.GetUserRequest{UserId: userID})
+			require.NoError(t, err)
+			assert.Equal(t, test.status, respUser.GetUser().GetStatus())
+			assert.NotEmpty(t, respUser.GetUser().GetStatusReason())
+			assert.Equal(t, test.suspendedUntil, respUser.GetUser().GetSuspendedUntil())
+
+			// Existing tokens stop working at once.
+			_, err = st.AuthClient.GetUser(userCtx, &ssov1.GetUserRequest{})
+			require.Error(t, err)
+			assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+			_, err = st.AuthClient.SignIn(ctx, &ssov1.SignInRequest{Email: email, Password: password, AppId: appID})
+			require.Error(t, err)
+			assert.Equal(t, test.expectedCode, status.Code(err))
+
+			// The status is not told to those who do not know the password.
+			_, err = st.AuthClient.SignIn(ctx, &ssov1.SignInRequest{Email: email, Password: randomFakePassword(), AppId: appID})
+			require.Error(t, err)
+			assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+			_, err = st.AuthClient.SetUserStatus(adminCtx, &ssov1.SetUserStatusRequest{UserId: userID, Status: "active"})
+			require.NoError(t, err)
+
+			respSignIn, err = st.AuthClient.SignIn(ctx, &ssov1.SignInRequest{Email: email, Password: password, AppId: appID})
+			require.NoError(t, err)
+			assert.NotEmpty(t, respSignIn.GetToken())
+
+			// Sessions revoked on disabling do not come back.
+			_, err = st.AuthClient.GetUser(userCtx, &ssov1.GetUserRequest{})
+			require.Error(t, err)
+			assert.Equal(t, codes.Unauthenticated, status.Code(err))
+		})
+	}
+}
+
+func Test_SetUserStatus_FailCases(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	adminCtx := withBearer(ctx, adminToken(ctx, t, st))
+	admin, err := st.AuthClient.GetUser(adminCtx, &ssov1.GetUserRequest{})
+	require.NoError(t, err)
+	approver, err := st.AuthClient.GetUser(withBearer(ctx, approverToken(ctx, t, st)), &ssov1.GetUserRequest{})
+	require.NoError(t, err)
+	userToken, userID := signUpAndSignIn(ctx, t, st)
+
+	tests := []struct {
+		ctx          context.Context
+		req          *ssov1.SetUserStatusRequest
+		expectedCode codes.Code
+	}{
+		{
+			//Empty user id
+			ctx:          adminCtx,
+			req:          &ssov1.SetUserStatusRequest{Status: "disabled", Reason: "abuse"},
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			//Unknown status
+			ctx:          adminCtx,
+			req:          &ssov1.SetUserStatusRequest{UserId: userID, Status: "banned", Reason: "abuse"},
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			//Empty reason
+			ctx:          adminCtx,
+			req:          &ssov1.SetUserStatusRequest{UserId: userID, Status: "disabled"},
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			//Suspension without an end
+			ctx:          adminCtx,
+			req:          &ssov1.SetUserStatusRequest{UserId: userID, Status: "suspended", Reason: "abuse"},
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			//Unknown user
+			ctx:          adminCtx,
+			req:          &ssov1.SetUserStatusRequest{UserId: 1 << 40, Status: "disabled", Reason: "abuse"},
+			expectedCode: codes.NotFound,
+		},
+		{
+			//Admin who has not been demoted
+			ctx:          adminCtx,
+			req:          &ssov1.SetUserStatusRequest{UserId: approver.GetUser().GetId(), Status: "disabled", Reason: "abuse"},
+			expectedCode: codes.FailedPrecondition,
+		},
+		{
+			//Admin changing their own status
+			ctx:          adminCtx,
+			req:          &ssov1.SetUserStatusRequest{UserId: admin.GetUser().GetId(), Status: "deleted", Reason: "leaving"},
+			expectedCode: codes.PermissionDenied,
+		},
+		{
+			//Caller who is not an admin
+			ctx:          withBearer(ctx, userToken),
+			req:          &ssov1.SetUserStatusRequest{UserId: userID, Status: "disabled", Reason: "abuse"},
+			expectedCode: codes.PermissionDenied,
+		},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("Test_SetUserStatus_FailCases №%d", i), func(t *testing.T) {
+			_, err := st.AuthClient.SetUserStatus(test.ctx, test.req)
+			require.Error(t, err)
+			assert.Equal(t, test.expectedCode, status.Code(err))
+		})
+	}
+
+	respUser, err := st.AuthClient.GetUser(adminCtx, &ssov1.GetUserRequest{UserId: userID})
+	require.NoError(t, err)
+	assert.Equal(t, "active", respUser.GetUser().GetStatus())
+}
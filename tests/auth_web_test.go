@@ -0,0 +1,90 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/DavidG9999/my_grpc_app/tests/suite"
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Connect_SignUp_SignIn_HappyPath(t *testing.T) {
+	_, st := suite.NewSuite(t)
+
+	email := gofakeit.Email()
+	password := randomFakePassword()
+
+	var signUp struct {
+		UserID string `json:"userId"`
+	}
+	code := connectCall(t, st, "SignUp", map[string]any{
+		"name":     gofakeit.Username(),
+		"email":    email,
+		"password": password,
+	}, &signUp)
+	require.Equal(t, http.StatusOK, code)
+	assert.NotEmpty(t, signUp.UserID)
+
+	var signIn struct {
+		Token string `json:"token"`
+	}
+	code = connectCall(t, st, "SignIn", map[string]any{
+		"email":    email,
+		"password": password,
+		"appId":    appID,
+	}, &signIn)
+	require.Equal(t, http.StatusOK, code)
+	assert.NotEmpty(t, signIn.Token)
+}
+
+func Test_Connect_SignUp_FailCases(t *testing.T) {
+	_, st := suite.NewSuite(t)
+
+	var connectErr struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}
+	code := connectCall(t, st, "SignUp", map[string]any{
+		"name":     gofakeit.Username(),
+		"email":    gofakeit.Email(),
+		"password": "",
+	}, &connectErr)
+	require.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "invalid_argument", connectErr.Code)
+	assert.Equal(t, "password is required", connectErr.Message)
+}
+
+func Test_Connect_RequiresToken(t *testing.T) {
+	_, st := suite.NewSuite(t)
+
+	var connectErr struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}
+	code := connectCall(t, st, "ListUsers", map[string]any{}, &connectErr)
+	require.Equal(t, http.StatusUnauthorized, code)
+	assert.Equal(t, "unauthenticated", connectErr.Code)
+}
+
+func connectCall(t *testing.T, st *suite.Suite, method string, req any, resp any) int {
+	t.Helper()
+
+	body, err := json.Marshal(req)
+	require.NoError(t, err)
+
+	httpReq, err := http.NewRequest(http.MethodPost, st.HTTPURL+"/auth.Auth/"+method, bytes.NewReader(body))
+	require.NoError(t, err)
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Connect-Protocol-Version", "1")
+
+	httpResp, err := st.HTTPClient.Do(httpReq)
+	require.NoError(t, err)
+	defer httpResp.Body.Close()
+
+	require.NoError(t, json.NewDecoder(httpResp.Body).Decode(resp))
+	return httpResp.StatusCode
+}
@@ -0,0 +1 @@
+UPDATE apps SET groups_claim = TRUE WHERE id = 1002;
@@ -0,0 +1 @@
+INSERT INTO apps (id, name, secret, public) VALUES (1003, 'test-public', 'test-public-secret', TRUE) ON CONFLICT DO NOTHING; INSERT INTO app_redirect_uris (app_id, uri) VALUES (1003, 'http://localhost:3000/callback') ON CONFLICT DO NOTHING;
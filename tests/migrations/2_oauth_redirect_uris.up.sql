@@ -0,0 +1 @@
+INSERT INTO app_redirect_uris (app_id, uri) VALUES (1, 'http://localhost:3000/callback') ON CONFLICT DO NOTHING;
@@ -0,0 +1 @@
+INSERT INTO app_scopes (app_id, scope) VALUES (1, 'users:read'), (1, 'users:write') ON CONFLICT DO NOTHING;
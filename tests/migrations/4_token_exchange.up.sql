@@ -0,0 +1,3 @@
+INSERT INTO apps (id, name, secret) VALUES (1000, 'test-target', 'test-target-secret') ON CONFLICT DO NOTHING;
+
+INSERT INTO token_exchange_policies (source_app_id, target_app_id, scope) VALUES (1, 1000, 'orders:read') ON CONFLICT DO NOTHING;
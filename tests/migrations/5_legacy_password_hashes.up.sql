@@ -0,0 +1 @@
+INSERT INTO users (name, email, password_hash, is_admin) VALUES ('legacy-bcrypt', 'legacy-bcrypt@example.com', '$2a$10$b6TyOoqi/9pg/ilXDR4x2ORl9j/2mxeNOUX2Ie6vs9rrsaVL0VcEi', FALSE) ON CONFLICT DO NOTHING;
@@ -0,0 +1 @@
+INSERT INTO users (name, email, password_hash, is_admin) VALUES ('test-admin', 'test-admin@example.com', '$2a$10$TV0Wm.2XYkqt/UmALhsgqOvbrYQDyB5yxDdM70KiH4aMpsaPYiZGm', TRUE) ON CONFLICT DO NOTHING;
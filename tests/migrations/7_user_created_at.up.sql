@@ -0,0 +1 @@
+UPDATE users SET created_at = strftime('%Y-%m-%d %H:%M:%S+00:00', 'now') WHERE created_at IS NULL;
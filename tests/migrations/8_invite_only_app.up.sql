@@ -0,0 +1 @@
+INSERT INTO apps (id, name, secret, invite_only) VALUES (1001, 'test-invite-only', 'test-invite-only-secret', TRUE) ON CONFLICT DO NOTHING;
@@ -0,0 +1,3 @@
+INSERT INTO organizations (id, name, created_at) VALUES (2, 'test-tenant', strftime('%Y-%m-%d %H:%M:%S+00:00', 'now')) ON CONFLICT DO NOTHING;
+
+INSERT INTO apps (id, org_id, name, secret) VALUES (1002, 2, 'test-tenant-app', 'test-tenant-secret') ON CONFLICT DO NOTHING;
@@ -0,0 +1,304 @@
+package tests
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	ssov1 "github.com/DavidG9999/api/gen/go/sso"
+	"github.com/DavidG9999/my_grpc_app/tests/suite"
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	redirectURI = "http://localhost:3000/callback"
+
+	publicAppID = 1003
+)
+
+type oauthTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+	IDToken      string `json:"id_token"`
+	Error        string `json:"error"`
+}
+
+func Test_OAuth_AuthorizationCode_HappyPath(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	email := gofakeit.Email()
+	password := randomFakePassword()
+
+	_, err := st.AuthClient.SignUp(ctx, &ssov1.SignUpRequest{
+		Name:     gofakeit.Username(),
+		Email:    email,
+		Password: password,
+	})
+	require.NoError(t, err)
+
+	verifier := gofakeit.LetterN(64)
+	code := authorize(t, st, email, password, verifier, nil)
+
+	token := exchangeCode(t, st, code, verifier)
+	require.Empty(t, token.Error)
+	assert.NotEmpty(t, token.AccessToken)
+	assert.NotEmpty(t, token.RefreshToken)
+	assert.Equal(t, "Bearer", token.TokenType)
+
+	reused := exchangeCode(t, st, code, verifier)
+	assert.Equal(t, "invalid_grant", reused.Error)
+
+	refreshed := oauthToken(t, st, url.Values{
+		"grant_type":    {"refresh_token"},
+		"client_id":     {strconv.Itoa(appID)},
+		"client_secret": {appSecret},
+		"refresh_token": {token.RefreshToken},
+	})
+	assert.Equal(t, "invalid_grant", refreshed.Error, "tokens issued from a reused code must be revoked")
+}
+
+func Test_OAuth_AuthorizationCode_FailCases(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	email := gofakeit.Email()
+	password := randomFakePassword()
+
+	_, err := st.AuthClient.SignUp(ctx, &ssov1.SignUpRequest{
+		Name:     gofakeit.Username(),
+		Email:    email,
+		Password: password,
+	})
+	require.NoError(t, err)
+
+	verifier := gofakeit.LetterN(64)
+	code := authorize(t, st, email, password, verifier, nil)
+
+	token := exchangeCode(t, st, code, gofakeit.LetterN(64))
+	assert.Equal(t, "invalid_grant", token.Error)
+
+	resp, err := st.HTTPClient.Get(st.HTTPURL + "/authorize?" + url.Values{
+		"response_type": {"code"},
+		"client_id":     {strconv.Itoa(appID)},
+		"redirect_uri":  {"http://evil.example.com/callback"},
+	}.Encode())
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func Test_OAuth_ClientAuthentication(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	email := gofakeit.Email()
+	password := randomFakePassword()
+
+	_, err := st.AuthClient.SignUp(ctx, &ssov1.SignUpRequest{
+		Name:     gofakeit.Username(),
+		Email:    email,
+		Password: password,
+	})
+	require.NoError(t, err)
+
+	verifier := gofakeit.LetterN(64)
+	code := authorize(t, st, email, password, verifier, nil)
+
+	// Confidential clients have to send their secret.
+	token := oauthToken(t, st, url.Values{
+		"grant_type":    {"authorization_code"},
+		"client_id":     {strconv.Itoa(appID)},
+		"code":          {code},
+		"redirect_uri":  {redirectURI},
+		"code_verifier": {verifier},
+	})
+	assert.Equal(t, "invalid_client", token.Error)
+
+	code = authorize(t, st, email, password, verifier, nil)
+	token = exchangeCode(t, st, code, verifier)
+	require.Empty(t, token.Error)
+
+	for _, secret := range []string{"", "wrong-secret"} {
+		refreshed := oauthToken(t, st, url.Values{
+			"grant_type":    {"refresh_token"},
+			"client_id":     {strconv.Itoa(appID)},
+			"client_secret": {secret},
+			"refresh_token": {token.RefreshToken},
+		})
+		assert.Equal(t, "invalid_client", refreshed.Error)
+	}
+
+	// Public clients rely on PKCE alone.
+	code = authorize(t, st, email, password, verifier, url.Values{"client_id": {strconv.Itoa(publicAppID)}})
+	token = oauthToken(t, st, url.Values{
+		"grant_type":    {"authorization_code"},
+		"client_id":     {strconv.Itoa(publicAppID)},
+		"code":          {code},
+		"redirect_uri":  {redirectURI},
+		"code_verifier": {verifier},
+	})
+	require.Empty(t, token.Error)
+
+	refreshed := oauthToken(t, st, url.Values{
+		"grant_type":    {"refresh_token"},
+		"client_id":     {strconv.Itoa(publicAppID)},
+		"refresh_token": {token.RefreshToken},
+	})
+	require.Empty(t, refreshed.Error)
+	assert.NotEmpty(t, refreshed.AccessToken)
+}
+
+func Test_OAuth_RefreshTokenRotation(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	email := gofakeit.Email()
+	password := randomFakePassword()
+
+	_, err := st.AuthClient.SignUp(ctx, &ssov1.SignUpRequest{
+		Name:     gofakeit.Username(),
+		Email:    email,
+		Password: password,
+	})
+	require.NoError(t, err)
+
+	refresh := func(refreshToken string) url.Values {
+		return url.Values{
+			"grant_type":    {"refresh_token"},
+			"client_id":     {strconv.Itoa(appID)},
+			"client_secret": {appSecret},
+			"refresh_token": {refreshToken},
+		}
+	}
+
+	verifier := gofakeit.LetterN(64)
+	token := exchangeCode(t, st, authorize(t, st, email, password, verifier, nil), verifier)
+	require.Empty(t, token.Error)
+
+	rotated := oauthToken(t, st, refresh(token.RefreshToken))
+	require.Empty(t, rotated.Error)
+
+	// Reusing a rotated token revokes the session, the tokens rotated from it
+	// included.
+	reused := oauthToken(t, st, refresh(token.RefreshToken))
+	assert.Equal(t, "invalid_grant", reused.Error)
+	_, err = st.AuthClient.ValidateToken(ctx, &ssov1.ValidateTokenRequest{Token: rotated.AccessToken})
+	require.Error(t, err)
+	reused = oauthToken(t, st, refresh(rotated.RefreshToken))
+	assert.Equal(t, "invalid_grant", reused.Error)
+
+	// Concurrent refreshes rotate the token once.
+	token = exchangeCode(t, st, authorize(t, st, email, password, verifier, nil), verifier)
+	require.Empty(t, token.Error)
+
+	const refreshes = 5
+	results := make(chan oauthTokenResponse, refreshes)
+	errs := make(chan error, refreshes)
+	var wg sync.WaitGroup
+	for range refreshes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			result, err := postOAuthToken(st, refresh(token.RefreshToken))
+			if err != nil {
+				errs <- err
+				return
+			}
+			results <- result
+		}()
+	}
+	wg.Wait()
+	close(results)
+	close(errs)
+
+	for err := range errs {
+		require.NoError(t, err)
+	}
+	var rotations int
+	for result := range results {
+		if result.Error == "" {
+			rotations++
+			continue
+		}
+		assert.Equal(t, "invalid_grant", result.Error)
+	}
+	assert.Equal(t, 1, rotations)
+}
+
+func authorize(t *testing.T, st *suite.Suite, email string, password string, verifier string, extra url.Values) string {
+	t.Helper()
+
+	sum := sha256.Sum256([]byte(verifier))
+	form := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {strconv.Itoa(appID)},
+		"redirect_uri":          {redirectURI},
+		"state":                 {gofakeit.LetterN(16)},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(sum[:])},
+		"code_challenge_method": {"S256"},
+		"email":                 {email},
+		"password":              {password},
+	}
+	for key, values := range extra {
+		form[key] = values
+	}
+
+	client := *st.HTTPClient
+	client.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	resp, err := client.Post(st.HTTPURL+"/authorize", "application/x-www-form-urlencoded", strings.NewReader(form.Encode()))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	assert.Equal(t, form.Get("state"), location.Query().Get("state"))
+
+	code := location.Query().Get("code")
+	require.NotEmpty(t, code)
+	return code
+}
+
+func exchangeCode(t *testing.T, st *suite.Suite, code string, verifier string) oauthTokenResponse {
+	t.Helper()
+
+	return oauthToken(t, st, url.Values{
+		"grant_type":    {"authorization_code"},
+		"client_id":     {strconv.Itoa(appID)},
+		"client_secret": {appSecret},
+		"code":          {code},
+		"redirect_uri":  {redirectURI},
+		"code_verifier": {verifier},
+	})
+}
+
+func oauthToken(t *testing.T, st *suite.Suite, form url.Values) oauthTokenResponse {
+	t.Helper()
+
+	token, err := postOAuthToken(st, form)
+	require.NoError(t, err)
+	return token
+}
+
+func postOAuthToken(st *suite.Suite, form url.Values) (oauthTokenResponse, error) {
+	resp, err := st.HTTPClient.Post(st.HTTPURL+"/token", "application/x-www-form-urlencoded", strings.NewReader(form.Encode()))
+	if err != nil {
+		return oauthTokenResponse{}, err
+	}
+	defer resp.Body.Close()
+
+	var token oauthTokenResponse
+	err = json.NewDecoder(resp.Body).Decode(&token)
+	return token, err
+}
@@ -0,0 +1,158 @@
+package tests
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	ssov1 "github.com/DavidG9999/api/gen/go/sso"
+	"github.com/DavidG9999/my_grpc_app/tests/suite"
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_OIDC_IDToken_UserInfo_HappyPath(t *testing.T) {
+	ctx, st := suite.NewSuite(t)
+
+	name := gofakeit.Username()
+	email := gofakeit.Email()
+	password := randomFakePassword()
+
+	respSignUp, err := st.AuthClient.SignUp(ctx, &ssov1.SignUpRequest{
+		Name:     name,
+		Email:    email,
+		Password: password,
+	})
+	require.NoError(t, err)
+
+	nonce := gofakeit.LetterN(16)
+	verifier := gofakeit.LetterN(64)
+	loginTime := time.Now()
+
+	code := authorize(t, st, email, password, verifier, url.Values{
+		"scope": {"openid profile email"},
+		"nonce": {nonce},
+	})
+	token := exchangeCode(t, st, code, verifier)
+	require.Empty(t, token.Error)
+	require.NotEmpty(t, token.IDToken)
+
+	keys := signingKeys(t, st)
+	idToken, err := jwt.Parse(token.IDToken, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+		}
+		kid, _ := token.Header["kid"].(string)
+		key, ok := keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown key %q", kid)
+		}
+		return key, nil
+	})
+	require.NoError(t, err)
+
+	// The app secret does not verify ID tokens.
+	_, err = jwt.Parse(token.IDToken, func(token *jwt.Token) (interface{}, error) {
+		return []byte(appSecret), nil
+	})
+	require.Error(t, err)
+
+	claims, ok := idToken.Claims.(jwt.MapClaims)
+	require.True(t, ok)
+
+	const deltaSeconds = 1
+	assert.Equal(t, strconv.FormatInt(respSignUp.GetUserId(), 10), claims["sub"])
+	assert.Equal(t, strconv.Itoa(appID), claims["aud"])
+	assert.Equal(t, st.Cfg.OAuth.Issuer, claims["iss"])
+	assert.Equal(t, nonce, claims["nonce"])
+	assert.InDelta(t, loginTime.Unix(), claims["auth_time"].(float64), deltaSeconds)
+
+	req, err := http.NewRequest(http.MethodGet, st.HTTPURL+"/userinfo", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+
+	resp, err := st.HTTPClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var userInfo struct {
+		Sub   string `json:"sub"`
+		Name  string `json:"name"`
+		Email string `json:"email"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&userInfo))
+	assert.Equal(t, claims["sub"], userInfo.Sub)
+	assert.Equal(t, name, userInfo.Name)
+	assert.Equal(t, email, userInfo.Email)
+}
+
+func Test_OIDC_Discovery(t *testing.T) {
+	_, st := suite.NewSuite(t)
+
+	resp, err := st.HTTPClient.Get(st.HTTPURL + "/.well-known/openid-configuration")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var doc map[string]any
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&doc))
+	assert.Equal(t, st.Cfg.OAuth.Issuer, doc["issuer"])
+	assert.Equal(t, st.Cfg.OAuth.Issuer+"/token", doc["token_endpoint"])
+	assert.Equal(t, st.Cfg.OAuth.Issuer+"/userinfo", doc["userinfo_endpoint"])
+	assert.Equal(t, []any{"RS256"}, doc["id_token_signing_alg_values_supported"])
+	assert.NotEmpty(t, signingKeys(t, st))
+}
+
+func Test_OIDC_UserInfo_InvalidToken(t *testing.T) {
+	_, st := suite.NewSuite(t)
+
+	req, err := http.NewRequest(http.MethodGet, st.HTTPURL+"/userinfo", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+gofakeit.LetterN(32))
+
+	resp, err := st.HTTPClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
+// signingKeys fetches the JWKS and returns its keys by kid.
+func signingKeys(t *testing.T, st *suite.Suite) map[string]*rsa.PublicKey {
+	t.Helper()
+
+	resp, err := st.HTTPClient.Get(st.HTTPURL + "/.well-known/jwks.json")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var jwks struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&jwks))
+
+	keys := make(map[string]*rsa.PublicKey, len(jwks.Keys))
+	for _, key := range jwks.Keys {
+		require.Equal(t, "RSA", key.Kty)
+		n, err := base64.RawURLEncoding.DecodeString(key.N)
+		require.NoError(t, err)
+		e, err := base64.RawURLEncoding.DecodeString(key.E)
+		require.NoError(t, err)
+		keys[key.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+	return keys
+}
@@ -2,12 +2,16 @@ package suite
 
 import (
 	"context"
+	"database/sql"
 	"net"
+	"net/http"
+	"path/filepath"
 	"strconv"
 	"testing"
 
 	ssov1 "github.com/DavidG9999/api/gen/go/sso"
 	"github.com/DavidG9999/my_grpc_app/internal/config"
+	"github.com/DavidG9999/my_grpc_app/internal/storage/sqlite"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 )
@@ -20,6 +24,8 @@ type Suite struct {
 	*testing.T
 	Cfg        *config.Config
 	AuthClient ssov1.AuthClient
+	HTTPClient *http.Client
+	HTTPURL    string
 }
 
 func NewSuite(t *testing.T) (context.Context, *Suite) {
@@ -43,9 +49,30 @@ func NewSuite(t *testing.T) (context.Context, *Suite) {
 		T:          t,
 		Cfg:        cfg,
 		AuthClient: ssov1.NewAuthClient(cc),
+		HTTPClient: &http.Client{Timeout: cfg.HTTP.Timeout},
+		HTTPURL:    "http://" + httpAddress(cfg),
 	}
 }
 
+// Storage opens the database of the server under test, for what the API does
+// not show.
+func (s *Suite) Storage() *sql.DB {
+	s.Helper()
+
+	db, err := sqlite.NewSQLiteDB(filepath.Join("..", s.Cfg.StoragePath))
+	if err != nil {
+		s.Fatalf("storage connection failed: %v", err)
+	}
+	s.Cleanup(func() {
+		db.Close()
+	})
+	return db
+}
+
 func grpcAddress(cfg *config.Config) string {
 	return net.JoinHostPort(grpcHost, strconv.Itoa(cfg.GRPC.Port))
 }
+
+func httpAddress(cfg *config.Config) string {
+	return net.JoinHostPort(grpcHost, strconv.Itoa(cfg.HTTP.Port))
+}